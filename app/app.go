@@ -2,20 +2,23 @@ package app
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/lemmego/api/session"
 	"github.com/romsar/gonertia"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
 	"time"
 
+	"github.com/lemmego/api/cmder"
 	"github.com/lemmego/api/config"
 	"github.com/lemmego/api/req"
 	"github.com/lemmego/api/shared"
@@ -56,7 +59,7 @@ type M map[string]any
 
 // Error returns a string representation of the JSON-encoded map.
 func (m M) Error() string {
-	jsonEncoded, err := json.Marshal(m)
+	jsonEncoded, err := JSONMarshal(m)
 	if err != nil {
 		return err.Error()
 	}
@@ -105,12 +108,15 @@ type Application struct {
 	bootStrapperCallbacks     []func(a App) error
 	commands                  []Command
 	runningInConsole          bool
+	server                    *http.Server
+	listenAddr                string
 }
 
 type Options struct {
 	Config   config.M
 	Commands []Command
 	Routes   RouteCallback
+	BasePath string
 }
 
 type OptFunc func(opts *Options)
@@ -161,6 +167,19 @@ func WithRoutes(routes RouteCallback) OptFunc {
 	}
 }
 
+// WithBasePath mounts the whole router under prefix, e.g. "/api/v1"
+func WithBasePath(prefix string) OptFunc {
+	return func(opts *Options) {
+		opts.BasePath = prefix
+	}
+}
+
+// SetBasePath mounts the whole router under prefix, e.g. "/api/v1". It must
+// be called before the app runs.
+func SetBasePath(prefix string) {
+	Get().(*Application).router.SetBasePath(prefix)
+}
+
 func Configure(optFuncs ...OptFunc) AppEngine {
 	opts := &Options{}
 
@@ -182,6 +201,10 @@ func Configure(optFuncs ...OptFunc) AppEngine {
 		i.routeCallbacks = append(i.routeCallbacks, opts.Routes)
 	}
 
+	if opts.BasePath != "" {
+		i.router.SetBasePath(opts.BasePath)
+	}
+
 	return i
 }
 
@@ -224,14 +247,24 @@ func (a *Application) registerCommands() {
 
 	rootCmd.AddCommand(publishCmd)
 
+	rootCmd.AddCommand(openAPICmd)
+
 	rootCmd.AddCommand(cmd.MigrateCmd)
 
+	// A Ctrl+C at a prompt ends the command like it ends any other CLI
+	cmder.ExitOnInterrupt = true
+
 	if err := rootCmd.Execute(); err != nil {
 		panic(err)
 	}
 }
 
 func (a *Application) registerServiceProviders() {
+	if err := a.loadTimezone(); err != nil {
+		panic(err)
+	}
+	a.loadJSONOptions()
+
 	for _, callback := range a.serviceRegistrarCallbacks {
 		if err := callback(a); err != nil {
 			panic(err)
@@ -265,48 +298,80 @@ func (a *Application) registerRoutes() {
 	// Register error endpoint if not overridden already
 	if !a.router.HasRoute("GET", "/error") {
 		a.router.Get("/error", func(c *Context) error {
-			err := c.PopSession("error").(string)
+			err := c.PopSessionString("error")
 			return c.Status(500).HTML([]byte("<html><body><code>" + err + "</code></body></html>"))
 		})
 	}
 
-	a.router.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
-	a.router.mux.Handle("GET /public/", http.StripPrefix("/public/", http.FileServer(http.Dir("public"))))
+	a.serveStaticDirs()
+}
+
+// serveStaticDirs mounts the static and public directories, configured by
+// app.static_dir and app.static_url (default "static" on /static/) and
+// app.public_dir and app.public_url (default "public" on /public/). An
+// empty dir disables the mount, and a prefix already mounted with
+// Router.Static, e.g. from an embed.FS, is left alone.
+func (a *Application) serveStaticDirs() {
+	mounts := []struct{ dirKey, dir, urlKey, url string }{
+		{"app.static_dir", "static", "app.static_url", "/static/"},
+		{"app.public_dir", "public", "app.public_url", "/public/"},
+	}
+
+	for _, mount := range mounts {
+		dir, _ := a.config.Get(mount.dirKey, mount.dir).(string)
+		url, _ := a.config.Get(mount.urlKey, mount.url).(string)
+		if dir == "" || url == "" {
+			continue
+		}
+		if slices.Contains(a.router.staticPrefixes, "/"+strings.Trim(url, "/")+"/") {
+			continue
+		}
+		a.router.Static(url, os.DirFS(dir))
+	}
 }
 
 func makeHandlerFunc(app *Application, route *Route) http.HandlerFunc {
 	fn := func(w http.ResponseWriter, r *http.Request) {
-		slog.Debug("Handling request for route: %s %s", route.Method, route.Path)
+		slog.Debug(fmt.Sprintf("Handling request for route: %s %s", route.Method, route.Path))
 		if route.router == nil {
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 			return
 		}
 
-		var sess *session.Session
-		if err := app.Service(&sess); err != nil {
-			slog.Error(err.Error())
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
+		if pattern, ok := r.Context().Value(RoutePatternKey).(*string); ok {
+			*pattern = route.Method + " " + route.Path
 		}
-		token := sess.Token(r.Context())
-		if token != "" {
-			r = r.WithContext(context.WithValue(r.Context(), "sessionID", token))
-			slog.Debug("Current session ID: ", token)
+
+		var sess *session.Session
+		if err := app.Service(&sess); err == nil && sess != nil && sess.Loaded(r.Context()) {
+			token := sess.Token(r.Context())
+			if token != "" {
+				r = r.WithContext(context.WithValue(r.Context(), "sessionID", token))
+				slog.Debug("Current session ID", "token", token)
+			}
 		}
 
 		allHandlers := append(append([]Handler{}, route.BeforeMiddleware...), route.Handlers...)
 		allHandlers = append(allHandlers, route.AfterMiddleware...)
 
 		ctx := &Context{
-			Mutex:    sync.Mutex{},
-			app:      app,
-			request:  r,
-			writer:   w,
-			handlers: allHandlers,
-			index:    -1,
+			Mutex:     sync.Mutex{},
+			app:       app,
+			request:   r,
+			writer:    w,
+			handlers:  allHandlers,
+			index:     -1,
+			errorMode: route.errorMode,
 		}
 
-		if err := ctx.Next(); err != nil {
+		if err := route.chain()(ctx); err != nil {
+			// A streamed response has already sent its status and part of
+			// its body, so an error document would only corrupt it
+			if ctx.HeadersSent() {
+				slog.Error("Handler failed after the response was sent", "route", route.Method+" "+route.Path, "error", err)
+				return
+			}
+
 			if errors.As(err, &shared.ValidationErrors{}) {
 				ctx.ValidationError(err)
 				return
@@ -383,37 +448,160 @@ func (a *Application) Run() {
 		os.Exit(0)
 	}
 
-	var sess *session.Session
-	if err := a.Service(&sess); &sess == nil || err != nil {
-		panic(err)
+	addr := a.configuredAddr()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Fatalf("listen: %s\n", err)
 	}
 
+	// Record the bound address, which differs from the configured one for ":0"
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", a.config.Get("app.port", 3000)),
-		Handler: sess.LoadAndSave(a.router),
+		Addr:    ln.Addr().String(),
+		Handler: a.handler(),
 	}
+	a.setServer(srv, addr)
+	config.OnReload(a.reload)
 
 	// Start the server in a goroutine
 	go func() {
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Fatalf("listen: %s\n", err)
 		}
 	}()
-	slog.Info(fmt.Sprintf("%s is running on port %d, Press Ctrl+C to close the server...", a.config.Get("app.name", "Lemmego"), a.config.Get("app.port", 3000)))
+	slog.Info(fmt.Sprintf("%s is running on %s, Press Ctrl+C to close the server...", a.config.Get("app.name", "Lemmego"), srv.Addr))
 	a.HandleSignals(srv)
 }
 
+// Addr returns the address the server listens on: the bound address once
+// it runs, otherwise app.addr if set (e.g. "127.0.0.1:8080" or ":0"), or
+// app.host and app.port, which default to all interfaces and 3000
+func (a *Application) Addr() string {
+	if srv := a.Server(); srv != nil {
+		return srv.Addr
+	}
+	return a.configuredAddr()
+}
+
+// configuredAddr resolves the listen address from config alone
+func (a *Application) configuredAddr() string {
+	if addr, _ := a.config.Get("app.addr", "").(string); addr != "" {
+		return addr
+	}
+
+	host, _ := a.config.Get("app.host", "").(string)
+	return net.JoinHostPort(host, fmt.Sprint(a.config.Get("app.port", 3000)))
+}
+
+// HasSession reports whether a session provider has registered a session
+func (a *Application) HasSession() bool {
+	var sess *session.Session
+	return a.Service(&sess) == nil && sess != nil
+}
+
+// handler returns the router wrapped in the session middleware, if a
+// session provider is registered
+func (a *Application) handler() http.Handler {
+	var sess *session.Session
+	if err := a.Service(&sess); err == nil && sess != nil {
+		return sess.LoadAndSave(a.router)
+	}
+
+	if a.config.Get("session") != nil {
+		slog.Error("Session is configured but no session was registered, check the session driver and that the session provider is imported; sessions are disabled")
+	} else {
+		slog.Warn("No session provider registered, sessions are disabled")
+	}
+	return a.router
+}
+
+// setServer records the running server and the configured address it was
+// bound from
+func (a *Application) setServer(srv *http.Server, listenAddr string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.server = srv
+	a.listenAddr = listenAddr
+}
+
+// Server returns the running HTTP server, nil before Run starts it
+func (a *Application) Server() *http.Server {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.server
+}
+
+// Rebind moves the running server to addr without downtime, e.g. when a
+// config reload changes the port. The new address is bound first, so a
+// failure leaves the current server untouched; once the new server is
+// accepting connections the old one is shut down gracefully, letting
+// in-flight requests finish within the shutdown timeout. Rebinding to the
+// address the server was bound from, or to its bound address, does nothing.
+func (a *Application) Rebind(addr string) error {
+	old := a.Server()
+	if old == nil {
+		return errors.New("app: no server is running")
+	}
+	a.mu.Lock()
+	current := a.listenAddr
+	a.mu.Unlock()
+	if addr == current || addr == old.Addr {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("app: cannot listen on %s: %w", addr, err)
+	}
+
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: old.Handler}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server stopped", "addr", srv.Addr, "error", err)
+		}
+	}()
+	a.setServer(srv, addr)
+	slog.Info(fmt.Sprintf("Listening on %s, draining %s", srv.Addr, old.Addr))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return old.Shutdown(ctx)
+}
+
+// reload applies a config reload to the running server. It is registered
+// with config.OnReload by Run, after the application's own config, so hooks
+// that refresh app.addr, app.host or app.port from the environment have
+// already run: the JSON options are read again and the server is rebound
+// if its configured address changed.
+func (a *Application) reload() {
+	a.loadJSONOptions()
+	if err := a.Rebind(a.configuredAddr()); err != nil {
+		slog.Error("Cannot rebind after config reload", "error", err)
+	}
+}
+
+// HandleSignals blocks until SIGINT or SIGTERM and shuts down gracefully.
+// SIGHUP re-reads .env through config.ReloadEnv, which runs the reload
+// hooks, and keeps serving.
 func (a *Application) HandleSignals(srv *http.Server) {
 	signalChannel := make(chan os.Signal, 1)
 	signal.Notify(signalChannel,
 		syscall.SIGINT,
 		syscall.SIGTERM,
+		syscall.SIGHUP,
 	)
 
-	sig := <-signalChannel
-	switch sig {
-	case syscall.SIGINT, syscall.SIGTERM:
-		// Gracefully shutdown the server
+	for sig := range signalChannel {
+		if sig == syscall.SIGHUP {
+			if err := config.ReloadEnv(".env"); err != nil {
+				slog.Error("Cannot reload .env", "error", err)
+			}
+			continue
+		}
+
+		// Gracefully shutdown the server, which may have been rebound
+		if current := a.Server(); current != nil {
+			srv = current
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 		if err := srv.Shutdown(ctx); err != nil {
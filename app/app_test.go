@@ -0,0 +1,229 @@
+package app
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lemmego/api/config"
+)
+
+// noKeepAlive opens a new connection per request, so requests reach the
+// server currently bound to the address rather than a pooled one
+var noKeepAlive = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+
+// startServer serves handler on addr the way Run does and returns the app
+func startServer(t *testing.T, addr string, handler http.Handler) *Application {
+	t.Helper()
+	a := &Application{Services: newServiceContainer(), router: newRouter(), config: config.GetInstance()}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Addr: ln.Addr().String(), Handler: handler}
+	a.setServer(srv, addr)
+	go srv.Serve(ln)
+
+	t.Cleanup(func() {
+		if current := a.Server(); current != nil {
+			current.Close()
+		}
+		srv.Close()
+	})
+	return a
+}
+
+func get(t *testing.T, addr, path string) (int, string, error) {
+	t.Helper()
+	res, err := noKeepAlive.Get("http://" + addr + path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), nil
+}
+
+func TestRebindServesNewAddressAndDrainsOld(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("finished"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	a := startServer(t, "127.0.0.1:0", mux)
+	oldAddr := a.Server().Addr
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		_, body, err := get(t, oldAddr, "/slow")
+		inFlight <- result{body, err}
+	}()
+	<-started
+
+	rebound := make(chan error, 1)
+	go func() { rebound <- a.Rebind("localhost:0") }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for a.Server().Addr == oldAddr {
+		if time.Now().After(deadline) {
+			t.Fatal("Rebind did not start a new server")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	newAddr := a.Server().Addr
+	if _, port, _ := net.SplitHostPort(newAddr); port == "0" {
+		t.Fatalf("new server address = %s, want the bound port", newAddr)
+	}
+
+	if status, body, err := get(t, newAddr, "/"); err != nil || status != http.StatusOK || body != "ok" {
+		t.Fatalf("GET on the new address = %d %q %v, want 200 ok", status, body, err)
+	}
+
+	// The old listener closes while its in-flight request keeps running
+	for {
+		if _, _, err := get(t, oldAddr, "/"); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the old address still accepts connections")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-rebound:
+		t.Fatalf("Rebind returned %v before the in-flight request finished", err)
+	default:
+	}
+
+	close(release)
+	if res := <-inFlight; res.err != nil || res.body != "finished" {
+		t.Fatalf("in-flight request = %q, %v, want it to finish on the old server", res.body, res.err)
+	}
+	if err := <-rebound; err != nil {
+		t.Fatalf("Rebind() = %v", err)
+	}
+}
+
+func TestRebindSameAddress(t *testing.T) {
+	a := startServer(t, "127.0.0.1:0", http.NotFoundHandler())
+	srv := a.Server()
+
+	for _, addr := range []string{"127.0.0.1:0", srv.Addr} {
+		if err := a.Rebind(addr); err != nil || a.Server() != srv {
+			t.Errorf("Rebind(%q) = %v and replaced the server, want a no-op", addr, err)
+		}
+	}
+}
+
+func TestRebindFailureKeepsServer(t *testing.T) {
+	if err := (&Application{}).Rebind(":0"); err == nil {
+		t.Error("Rebind() without a running server returned no error")
+	}
+
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+
+	a := startServer(t, "127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv := a.Server()
+
+	if err := a.Rebind(busy.Addr().String()); err == nil {
+		t.Fatal("Rebind() to a busy address returned no error")
+	}
+	if a.Server() != srv {
+		t.Fatal("a failed Rebind replaced the server")
+	}
+	if _, body, err := get(t, srv.Addr, "/"); err != nil || body != "ok" {
+		t.Errorf("GET after a failed Rebind = %q, %v, want the old server serving", body, err)
+	}
+}
+
+func TestReloadRebindsOnAddressChange(t *testing.T) {
+	config.Set("app.addr", "127.0.0.1:0")
+	defer config.Set("app.addr", nil)
+
+	a := startServer(t, "127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv := a.Server()
+
+	a.reload()
+	if a.Server() != srv {
+		t.Fatal("reload rebound although the address did not change")
+	}
+
+	config.Set("app.addr", "localhost:0")
+	a.reload()
+	if a.Server() == srv {
+		t.Fatal("reload did not rebind after the address changed")
+	}
+	if _, body, err := get(t, a.Server().Addr, "/"); err != nil || body != "ok" {
+		t.Errorf("GET on the reloaded address = %q, %v, want ok", body, err)
+	}
+}
+
+func TestAddrFromConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		conf config.M
+		want string
+	}{
+		{"defaults", config.M{}, ":3000"},
+		{"port", config.M{"port": 8080}, ":8080"},
+		{"port as string", config.M{"port": "8081"}, ":8081"},
+		{"host and port", config.M{"host": "127.0.0.1", "port": 8080}, "127.0.0.1:8080"},
+		{"ipv6 host", config.M{"host": "::1", "port": 8080}, "[::1]:8080"},
+		{"explicit addr wins", config.M{"addr": "localhost:9000", "host": "127.0.0.1", "port": 8080}, "localhost:9000"},
+		{"ephemeral port", config.M{"addr": ":0"}, ":0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Application{config: config.GetInstance()}
+			for key, value := range tt.conf {
+				config.Set("app."+key, value)
+				defer config.Set("app."+key, nil)
+			}
+
+			if got := a.Addr(); got != tt.want {
+				t.Errorf("Addr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddrReportsBoundPort(t *testing.T) {
+	a := startServer(t, "127.0.0.1:0", http.NotFoundHandler())
+
+	host, port, err := net.SplitHostPort(a.Addr())
+	if err != nil || host != "127.0.0.1" || port == "0" {
+		t.Fatalf("Addr() = %q, want 127.0.0.1 with the bound port", a.Addr())
+	}
+
+	if err := a.Rebind("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Rebind("localhost:0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, port, _ := net.SplitHostPort(a.Addr()); port == "0" {
+		t.Errorf("Addr() after Rebind(:0) = %q, want the bound port", a.Addr())
+	}
+}
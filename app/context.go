@@ -1,11 +1,16 @@
 package app
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/gob"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/db"
+	"github.com/lemmego/api/encryption"
 	"github.com/lemmego/api/fs"
 	"github.com/lemmego/api/session"
 	"html/template"
@@ -14,11 +19,16 @@ import (
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lemmego/api/res"
 	"github.com/lemmego/api/shared"
@@ -27,13 +37,15 @@ import (
 	"github.com/lemmego/api/req"
 
 	"github.com/a-h/templ"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
 )
 
 func init() {
 	gob.Register(&res.AlertMessage{})
 	gob.Register(shared.ValidationErrors{})
 	gob.Register([]*res.AlertMessage{})
-	gob.Register(shared.ValidationErrors{})
+	gob.Register(shared.ValidationEnvelope{})
 	gob.Register(map[string][]string{})
 }
 
@@ -46,6 +58,21 @@ type Context struct {
 
 	handlers []Handler
 	index    int
+
+	locals map[string]any
+
+	rawBody     []byte
+	rawBodyRead bool
+	rawBodyErr  error
+
+	headerWritten bool
+
+	sessionErr error
+
+	oldInput       map[string][]string
+	oldInputPopped bool
+
+	errorMode ErrorMode
 }
 
 type R struct {
@@ -89,6 +116,60 @@ func (c *Context) Cookie(name string) *http.Cookie {
 	return cookie
 }
 
+// Cookies returns all cookies sent with the request
+func (c *Context) Cookies() []*http.Cookie {
+	return c.request.Cookies()
+}
+
+// HasCookie reports whether the request carries a cookie with the given name
+func (c *Context) HasCookie(name string) bool {
+	_, err := c.request.Cookie(name)
+	return err == nil
+}
+
+// SetSignedCookie sets the cookie with an HMAC of its name and value
+// appended, keyed with APP_KEY. The value stays readable by the client but
+// cannot be altered without SignedCookie noticing.
+func (c *Context) SetSignedCookie(cookie *http.Cookie) {
+	signer, err := encryption.NewSigner([]byte(os.Getenv("APP_KEY")))
+	if err != nil {
+		slog.Error("APP_KEY must be set to sign cookies")
+		return
+	}
+
+	signed := *cookie
+	signature := signer.Sign([]byte(cookie.Name + "=" + cookie.Value))
+	signed.Value = cookie.Value + "." + base64.RawURLEncoding.EncodeToString(signature)
+	http.SetCookie(c.writer, &signed)
+}
+
+// SignedCookie returns the value of a cookie set with SetSignedCookie. ok is
+// false if the cookie is missing or its signature doesn't match.
+func (c *Context) SignedCookie(name string) (string, bool) {
+	cookie, err := c.request.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+
+	signer, err := encryption.NewSigner([]byte(os.Getenv("APP_KEY")))
+	if err != nil {
+		return "", false
+	}
+
+	i := strings.LastIndexByte(cookie.Value, '.')
+	if i < 0 {
+		return "", false
+	}
+
+	value := cookie.Value[:i]
+	signature, err := base64.RawURLEncoding.DecodeString(cookie.Value[i+1:])
+	if err != nil || !signer.Verify([]byte(name+"="+value), signature) {
+		return "", false
+	}
+
+	return value, true
+}
+
 func (c *Context) Alert(typ string, message string) *res.AlertMessage {
 	if typ != "success" && typ != "error" && typ != "warning" && typ != "info" && typ != "debug" {
 		return &res.AlertMessage{Type: "", Body: ""}
@@ -114,20 +195,133 @@ func (c *Context) Validate(body req.Validator) error {
 	return nil
 }
 
-func (c *Context) ParseInput(inputStruct any) error {
-	err := req.ParseInput(c, inputStruct)
+// ValidateInput parses the request into out and, if out implements
+// req.Validator, validates it. Returning the error from a handler lets the
+// router render validation failures as a 422 or a redirect back.
+func (c *Context) ValidateInput(out any, opts ...req.DecodeOptions) error {
+	if err := c.ParseInput(out, opts...); err != nil {
+		return err
+	}
+
+	if v, ok := out.(req.Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// ValidateAll parses the request into each of the validators, e.g. a body
+// struct and a query struct, and validates them. Structs with httpin `in`
+// tags are decoded from their tagged sources even for JSON requests, and
+// JSON bodies are decoded with unknown fields allowed, since each struct
+// only covers part of the input. Validation errors are merged into one
+// shared.ValidationErrors so the client gets all of them at once; any other
+// error is returned as is.
+func (c *Context) ValidateAll(validators ...req.Validator) error {
+	merged := shared.ValidationErrors{}
+
+	for _, v := range validators {
+		var err error
+		if req.HasInTags(v) {
+			err = req.DecodeTagged(c.request, v)
+		} else {
+			// Rewind the body so every struct decodes the same payload,
+			// parsed forms are cached on the request already
+			if !c.HasMultiPartRequest() && !c.HasFormURLEncodedRequest() {
+				if _, err := c.RawBody(); err != nil {
+					return err
+				}
+			}
+			err = req.ParseInputWith(c, v, req.DecodeOptions{AllowUnknownFields: true})
+		}
+		if err != nil {
+			return err
+		}
+		c.initBaseInput(v)
+
+		err = v.Validate()
+		if err == nil {
+			continue
+		}
+
+		var errs shared.ValidationErrors
+		if !errors.As(err, &errs) {
+			return err
+		}
+		for field, messages := range errs {
+			for _, message := range messages {
+				if !slices.Contains(merged[field], message) {
+					merged[field] = append(merged[field], message)
+				}
+			}
+		}
+	}
+
+	if len(merged) > 0 {
+		return merged
+	}
+	return nil
+}
+
+// ParseInput decodes the request into inputStruct. JSON bodies are decoded
+// strictly unless decode options say otherwise.
+func (c *Context) ParseInput(inputStruct any, opts ...req.DecodeOptions) error {
+	var decodeOpts req.DecodeOptions
+	if len(opts) > 0 {
+		decodeOpts = opts[0]
+	}
+
+	err := req.ParseInputWith(c, inputStruct, decodeOpts)
 	if err != nil {
 		return err
 	}
 
+	c.initBaseInput(inputStruct)
+	return nil
+}
+
+// initBaseInput sets up the embedded BaseInput of inputStruct, if any
+func (c *Context) initBaseInput(inputStruct any) {
 	v := reflect.ValueOf(inputStruct).Elem()
 
 	nameField := v.FieldByName("BaseInput")
 	if nameField.IsValid() && nameField.CanSet() {
-		i := &BaseInput{App: c.app, Ctx: c, Validator: NewValidator(c.app)}
+		validator := NewValidator(c.app)
+		validator.MarkPresent(c.inputKeys()...)
+		i := &BaseInput{App: c.app, Ctx: c, Validator: validator}
 		nameField.Set(reflect.ValueOf(i))
 	}
-	return nil
+}
+
+// inputKeys returns the names of the fields submitted with the request: the
+// top-level keys of a JSON body, or the query, form and file field names
+func (c *Context) inputKeys() []string {
+	var keys []string
+
+	if strings.Contains(strings.ToLower(c.GetHeader("Content-Type")), "json") {
+		body, err := c.RawBody()
+		if err == nil {
+			var fields map[string]json.RawMessage
+			if JSONUnmarshal(body, &fields) == nil {
+				for key := range fields {
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+
+	for key := range c.request.URL.Query() {
+		keys = append(keys, key)
+	}
+	for key := range c.request.Form {
+		keys = append(keys, key)
+	}
+	if c.request.MultipartForm != nil {
+		for key := range c.request.MultipartForm.File {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
 }
 
 func (c *Context) Input(inputStruct any) any {
@@ -200,12 +394,25 @@ func (c *Context) RequestContext() context.Context {
 	return c.request.Context()
 }
 
+// DB returns the named connection (the default one if omitted) bound to the
+// request context, so queries are aborted when the request is cancelled or
+// times out
+func (c *Context) DB(connName ...string) *gorm.DB {
+	return db.Get(connName...).WithContext(c.RequestContext())
+}
+
+// SpanContext returns the trace span context of the request, which is
+// invalid unless a tracing middleware started a span
+func (c *Context) SpanContext() trace.SpanContext {
+	return trace.SpanContextFromContext(c.RequestContext())
+}
+
 func (c *Context) Templ(component templ.Component) error {
 	c.writer.Header().Set("content-type", "text/html")
 	if c.status == 0 {
 		c.status = http.StatusOK
 	}
-	c.writer.WriteHeader(c.status)
+	c.WriteStatus(c.status)
 	return component.Render(c.Request().Context(), c.writer)
 }
 
@@ -214,14 +421,111 @@ func (c *Context) Status(status int) *Context {
 	return c
 }
 
+// WriteStatus writes the response header with the given status. Only the
+// first call has an effect, so chaining responders doesn't trigger
+// net/http's "superfluous WriteHeader" warning.
+func (c *Context) WriteStatus(status int) {
+	if c.headerWritten {
+		return
+	}
+	c.headerWritten = true
+	c.writer.WriteHeader(status)
+}
+
+// HeadersSent reports whether a Context responder has written the response header
+func (c *Context) HeadersSent() bool {
+	return c.headerWritten
+}
+
+// GetHeader returns a request header, same as RequestHeader
 func (c *Context) GetHeader(key string) string {
+	return c.RequestHeader(key)
+}
+
+// RequestHeader returns the value of a header sent by the client
+func (c *Context) RequestHeader(key string) string {
 	return c.request.Header.Get(key)
 }
 
+// ResponseHeader returns the value of a header set on the response so far
+func (c *Context) ResponseHeader(key string) string {
+	return c.writer.Header().Get(key)
+}
+
+// SetHeader sets a response header, replacing any values it already has
 func (c *Context) SetHeader(key string, value string) {
+	c.writer.Header().Set(key, value)
+}
+
+// AddHeader appends a value to a response header
+func (c *Context) AddHeader(key string, value string) {
 	c.writer.Header().Add(key, value)
 }
 
+// SetRetryAfter sets the Retry-After header to d in whole seconds, e.g.
+// alongside a 429 or 503 response
+func (c *Context) SetRetryAfter(d time.Duration) *Context {
+	c.writer.Header().Set("Retry-After", req.FormatRetryAfter(d))
+	return c
+}
+
+// CacheControl sets the Cache-Control header, e.g. "public, max-age=3600"
+func (c *Context) CacheControl(directives string) *Context {
+	c.writer.Header().Set("Cache-Control", directives)
+	return c
+}
+
+// NoCache tells clients and proxies not to store or reuse the response
+func (c *Context) NoCache() *Context {
+	header := c.writer.Header()
+	header.Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	header.Set("Pragma", "no-cache")
+	header.Set("Expires", "0")
+	return c
+}
+
+// Vary adds headers to the Vary header, skipping the ones already listed.
+// A Vary of "*" is left as is.
+func (c *Context) Vary(headers ...string) *Context {
+	header := c.writer.Header()
+
+	var values []string
+	seen := map[string]bool{}
+	for _, line := range header.Values("Vary") {
+		for _, value := range strings.Split(line, ",") {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			if value == "*" {
+				return c
+			}
+			if key := http.CanonicalHeaderKey(value); !seen[key] {
+				seen[key] = true
+				values = append(values, value)
+			}
+		}
+	}
+
+	for _, value := range headers {
+		if value = strings.TrimSpace(value); value == "" {
+			continue
+		}
+		if value == "*" {
+			header.Set("Vary", "*")
+			return c
+		}
+		if key := http.CanonicalHeaderKey(value); !seen[key] {
+			seen[key] = true
+			values = append(values, key)
+		}
+	}
+
+	if len(values) > 0 {
+		header.Set("Vary", strings.Join(values, ", "))
+	}
+	return c
+}
+
 func (c *Context) WantsJSON() bool {
 	return req.WantsJSON(c.request)
 }
@@ -230,15 +534,97 @@ func (c *Context) WantsHTML() bool {
 	return req.WantsHTML(c.request)
 }
 
+// Accepts returns the offered media type the client prefers, or "" if
+// none is acceptable
+func (c *Context) Accepts(mediaTypes ...string) string {
+	return req.Accepts(c.request, mediaTypes...)
+}
+
+// WantsType reports whether the client explicitly accepts the media type
+func (c *Context) WantsType(mediaType string) bool {
+	return req.WantsType(c.request, mediaType)
+}
+
 func (c *Context) JSON(body M) error {
-	// TODO: Check if header is already sent
-	response, _ := json.Marshal(body)
+	response, err := JSONMarshal(body)
+	if err != nil {
+		return err
+	}
 	c.writer.Header().Set("content-Type", "application/json")
 	if c.status == 0 {
 		c.status = http.StatusOK
 	}
-	c.writer.WriteHeader(c.status)
-	_, err := c.writer.Write(response)
+	c.WriteStatus(c.status)
+	_, err = c.writer.Write(response)
+	return err
+}
+
+// JSONStream writes a JSON response produced incrementally by fn through
+// an encoder on the response writer, flushing once fn returns. The encoder
+// encodes with JSONMarshal, writing each value on its own line. Headers are
+// sent before fn runs, so an error from fn cannot change the status; it is
+// returned and the body is left truncated.
+func (c *Context) JSONStream(fn func(enc JSONEncoder) error) error {
+	c.writer.Header().Set("content-Type", "application/json")
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
+
+	if err := fn(hookEncoder{c.writer}); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+// JSONArray streams the values received from ch as a JSON array, flushing
+// after each element so large collections are never buffered whole. If an
+// element fails to encode the array is left unterminated, which clients
+// see as invalid JSON, and the error is returned. ch is not drained after an
+// error, so producers should also watch the request context.
+func (c *Context) JSONArray(ch <-chan any) error {
+	c.writer.Header().Set("content-Type", "application/json")
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
+
+	if _, err := io.WriteString(c.writer, "["); err != nil {
+		return err
+	}
+
+	first := true
+	for item := range ch {
+		data, err := JSONMarshal(item)
+		if err != nil {
+			return err
+		}
+		if !first {
+			if _, err := io.WriteString(c.writer, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+		if _, err := c.writer.Write(data); err != nil {
+			return err
+		}
+		if err := c.flush(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := io.WriteString(c.writer, "]"); err != nil {
+		return err
+	}
+	return c.flush()
+}
+
+// flush sends buffered response data to the client when the writer supports it
+func (c *Context) flush() error {
+	err := http.NewResponseController(c.writer).Flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
 	return err
 }
 
@@ -251,20 +637,18 @@ func (c *Context) resolveTemplateData(data *res.TemplateData) *res.TemplateData
 		data = &res.TemplateData{}
 	}
 
-	vErrs := shared.ValidationErrors{}
-
-	if val, ok := c.PopSession("errors").(shared.ValidationErrors); ok {
-		vErrs = val
+	if data.ValidationErrors == nil {
+		data.ValidationErrors = c.popFlashedErrors().Errors
 	}
 
-	if data.ValidationErrors == nil {
-		data.ValidationErrors = vErrs
+	if data.OldInput == nil {
+		data.OldInput = c.OldInput()
 	}
 
-	data.Messages = append(data.Messages, &res.AlertMessage{"success", c.PopSessionString("success")})
-	data.Messages = append(data.Messages, &res.AlertMessage{"info", c.PopSessionString("info")})
-	data.Messages = append(data.Messages, &res.AlertMessage{"warning", c.PopSessionString("warning")})
-	data.Messages = append(data.Messages, &res.AlertMessage{"error", c.PopSessionString("error")})
+	data.Messages = append(data.Messages, &res.AlertMessage{Type: "success", Body: c.PopSessionString("success")})
+	data.Messages = append(data.Messages, &res.AlertMessage{Type: "info", Body: c.PopSessionString("info")})
+	data.Messages = append(data.Messages, &res.AlertMessage{Type: "warning", Body: c.PopSessionString("warning")})
+	data.Messages = append(data.Messages, &res.AlertMessage{Type: "error", Body: c.PopSessionString("error")})
 
 	return data
 }
@@ -274,7 +658,7 @@ func (c *Context) Text(body []byte) error {
 	if c.status == 0 {
 		c.status = http.StatusOK
 	}
-	c.writer.WriteHeader(c.status)
+	c.WriteStatus(c.status)
 	_, err := c.writer.Write(body)
 	return err
 }
@@ -284,22 +668,78 @@ func (c *Context) HTML(body []byte) error {
 	if c.status == 0 {
 		c.status = http.StatusOK
 	}
-	c.writer.WriteHeader(c.status)
+	c.WriteStatus(c.status)
+	_, err := c.writer.Write(body)
+	return err
+}
+
+// RenderString sends s with the given content type, or one sniffed from
+// its first bytes, e.g. "text/html; charset=utf-8" for markup
+func (c *Context) RenderString(s string, contentType ...string) error {
+	return c.renderBytes([]byte(s), contentType)
+}
+
+// RenderStringer sends the String() of s, like RenderString
+func (c *Context) RenderStringer(s fmt.Stringer, contentType ...string) error {
+	return c.RenderString(s.String(), contentType...)
+}
+
+// RenderWriterTo sends the output of wt, like RenderString. Without a
+// content type the output is buffered to sniff one, otherwise it is
+// written straight to the response.
+func (c *Context) RenderWriterTo(wt io.WriterTo, contentType ...string) error {
+	if len(contentType) == 0 || contentType[0] == "" {
+		var buf bytes.Buffer
+		if _, err := wt.WriteTo(&buf); err != nil {
+			return err
+		}
+		return c.renderBytes(buf.Bytes(), nil)
+	}
+
+	c.writer.Header().Set("content-type", contentType[0])
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
+	_, err := wt.WriteTo(c.writer)
+	return err
+}
+
+func (c *Context) renderBytes(body []byte, contentType []string) error {
+	ct := http.DetectContentType(body)
+	if len(contentType) > 0 && contentType[0] != "" {
+		ct = contentType[0]
+	}
+
+	c.writer.Header().Set("content-type", ct)
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
 	_, err := c.writer.Write(body)
 	return err
 }
 
+// csrfToken returns the token the CSRF middleware handed out for this
+// request, which in cookie mode is the signed cookie value, falling back to
+// the session token
+func (c *Context) csrfToken() string {
+	if token, ok := c.Get("_token").(string); ok && token != "" {
+		return token
+	}
+	return c.GetSessionString("_token")
+}
+
 func (c *Context) Render(tmplPath string, data *res.TemplateData) error {
 	data = c.resolveTemplateData(data)
 	c.writer.Header().Set("content-type", "text/html")
 	if c.status == 0 {
 		c.status = http.StatusOK
 	}
-	c.writer.WriteHeader(c.status)
+	c.WriteStatus(c.status)
 	data.FuncMap = template.FuncMap{
 		"csrf": func() template.HTML {
-			token := c.GetSessionString("_token")
-			return template.HTML(`<input type="hidden" name="_token" value="` + token + `" />`)
+			return template.HTML(`<input type="hidden" name="_token" value="` + template.HTMLEscapeString(c.csrfToken()) + `" />`)
 		},
 	}
 	return res.RenderTemplate(c.writer, tmplPath, data)
@@ -311,26 +751,21 @@ func (c *Context) Inertia(filePath string, props map[string]any) error {
 		return errors.New("inertia not enabled")
 	}
 
-	if errs := c.PopSession("errors"); errs != nil {
-		if props == nil {
-			props = map[string]any{}
-		}
-
-		props["errors"] = errs
-	}
+	props = c.withValidationProps(props)
 
 	if c.status == 0 {
 		c.status = http.StatusOK
 	}
-	c.writer.WriteHeader(c.status)
+	c.WriteStatus(c.status)
 	return i.Render(c.ResponseWriter(), c.Request(), filePath, props)
 }
 
 func (c *Context) Redirect(url string) error {
 	if c.IsInertiaRequest() {
 		var i *inertia.Inertia
-		if c.App().Service(&i) != nil {
+		if c.App().Service(&i) == nil {
 			i.Redirect(c.ResponseWriter(), c.Request(), url)
+			c.headerWritten = true
 			return nil
 		}
 	}
@@ -339,7 +774,7 @@ func (c *Context) Redirect(url string) error {
 	if c.status == 0 {
 		c.status = http.StatusFound
 	}
-	c.writer.WriteHeader(c.status)
+	c.WriteStatus(c.status)
 	return nil
 }
 
@@ -347,8 +782,48 @@ func (c *Context) With(key string, message string) *Context {
 	return c.PutSession(key, message)
 }
 
+// WithErrors flashes the errors to the session in the same
+// shared.ValidationEnvelope the JSON 422 response uses
 func (c *Context) WithErrors(errors shared.ValidationErrors) *Context {
-	return c.PutSession("errors", errors)
+	return c.PutSession("errors", errors.Envelope())
+}
+
+// popFlashedErrors returns the validation envelope flashed by WithErrors.
+// Bare error maps flashed by older versions are wrapped in an envelope.
+func (c *Context) popFlashedErrors() shared.ValidationEnvelope {
+	switch errs := c.PopSession("errors").(type) {
+	case shared.ValidationEnvelope:
+		if errs.Errors == nil {
+			errs.Errors = shared.ValidationErrors{}
+		}
+		return errs
+	case shared.ValidationErrors:
+		return errs.Envelope()
+	case map[string][]string:
+		return shared.ValidationErrors(errs).Envelope()
+	}
+	return shared.ValidationEnvelope{Errors: shared.ValidationErrors{}}
+}
+
+// withValidationProps adds the flashed validation envelope's "message" and
+// "errors" to Inertia props, so pages see the JSON 422 body's shape. Props
+// the handler set themselves win.
+func (c *Context) withValidationProps(props map[string]any) map[string]any {
+	envelope := c.popFlashedErrors()
+	if len(envelope.Errors) == 0 {
+		return props
+	}
+
+	if props == nil {
+		props = map[string]any{}
+	}
+	if _, ok := props["errors"]; !ok {
+		props["errors"] = envelope.Errors
+	}
+	if _, ok := props["message"]; !ok {
+		props["message"] = envelope.Message
+	}
+	return props
 }
 
 func (c *Context) WithSuccess(message string) *Context {
@@ -371,32 +846,205 @@ func (c *Context) WithData(data map[string]any) *Context {
 	return c.PutSession("data", data)
 }
 
+// WithInput flashes the submitted form values to the session so the next
+// request can repopulate the form with Old. Fields whose name contains
+// "password" are never flashed.
 func (c *Context) WithInput() *Context {
 	body, err := c.Form()
-	if err == nil && body != nil {
-		c.PutSession("input", body)
+	if err != nil || body == nil {
+		return c
 	}
-	return c
+
+	input := make(map[string][]string, len(body))
+	for key, values := range body {
+		if strings.Contains(strings.ToLower(key), "password") {
+			continue
+		}
+		input[key] = values
+	}
+	return c.PutSession("input", input)
 }
 
-func (c *Context) Back() error {
+// OldInput returns the form values flashed by WithInput on the previous
+// request, e.g. after a failed validation. The flash is consumed on first
+// use and cached for the rest of the request.
+func (c *Context) OldInput() map[string][]string {
+	if !c.oldInputPopped {
+		c.oldInputPopped = true
+		if input, ok := c.PopSession("input").(map[string][]string); ok {
+			c.oldInput = input
+		}
+	}
+
+	if c.oldInput == nil {
+		return map[string][]string{}
+	}
+	return c.oldInput
+}
+
+// Old returns the first flashed value of the form field key, or fallback
+// (or "") if there is none
+func (c *Context) Old(key string, fallback ...string) string {
+	if values := c.OldInput()[key]; len(values) > 0 {
+		return values[0]
+	}
+	if len(fallback) > 0 {
+		return fallback[0]
+	}
+	return ""
+}
+
+// SafeRedirect redirects to url only if it is a relative path, points to
+// the request's own host or to one of the hosts listed in
+// app.redirect_hosts. Any other target is replaced by fallback, which
+// prevents open redirects when url comes from user input.
+func (c *Context) SafeRedirect(url string, fallback string) error {
+	if !c.isSafeRedirect(url) {
+		url = fallback
+	}
+	return c.Redirect(url)
+}
+
+func (c *Context) isSafeRedirect(target string) bool {
+	if target == "" || strings.HasPrefix(target, "//") || strings.ContainsRune(target, '\\') {
+		return false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+
+	if u.Scheme == "" && u.Host == "" {
+		return u.Opaque == ""
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	if strings.EqualFold(u.Host, c.request.Host) {
+		return true
+	}
+
+	allowed, _ := config.Get("app.redirect_hosts", []string{}).([]string)
+	for _, host := range allowed {
+		if strings.EqualFold(u.Host, host) || strings.EqualFold(u.Hostname(), host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Back redirects to the referring page. A missing referer, e.g. after
+// direct navigation, or one that fails the SafeRedirect checks is replaced
+// by fallback, "/" if not given.
+func (c *Context) Back(fallback ...string) error {
 	if c.status == 0 {
 		c.status = http.StatusFound
 	}
 
+	if !c.isSafeRedirect(c.Referer()) {
+		target := "/"
+		if len(fallback) > 0 && fallback[0] != "" {
+			target = fallback[0]
+		}
+		return c.Redirect(target)
+	}
+
 	var i *inertia.Inertia
 	if c.App().Service(&i) == nil {
 		i.Back(c.ResponseWriter(), c.Request(), c.status)
+		c.headerWritten = true
 		return nil
 	}
 
 	return c.Redirect(c.Referer())
 }
 
+// TrustedProxies returns app.trusted_proxies, the IPs or CIDR ranges whose
+// forwarding headers are honored
+func TrustedProxies() []string {
+	proxies, _ := config.Get("app.trusted_proxies", []string{}).([]string)
+	return proxies
+}
+
+// Scheme returns "https" or "http", honoring X-Forwarded-Proto only from
+// the proxies listed in app.trusted_proxies
+func (c *Context) Scheme() string {
+	return req.Scheme(c.request, TrustedProxies())
+}
+
+// Host returns the host the request was addressed to, honoring
+// X-Forwarded-Host only from the proxies listed in app.trusted_proxies
+func (c *Context) Host() string {
+	if req.IsTrustedProxy(c.request, TrustedProxies()) {
+		host, _, _ := strings.Cut(c.request.Header.Get("X-Forwarded-Host"), ",")
+		if host = strings.TrimSpace(host); host != "" {
+			return host
+		}
+	}
+	return c.request.Host
+}
+
+// ClientIP returns the client's IP address, honoring X-Forwarded-For and
+// X-Real-IP only from the proxies listed in app.trusted_proxies
+func (c *Context) ClientIP() string {
+	return req.ClientIP(c.request, TrustedProxies())
+}
+
+// FullURL returns the absolute URL of the request, including the query string
+func (c *Context) FullURL() string {
+	return c.Scheme() + "://" + c.Host() + c.request.URL.RequestURI()
+}
+
 func (c *Context) Referer() string {
 	return c.request.Referer()
 }
 
+// maxBodySize reads app.max_body_size, the request body limit in bytes,
+// defaulting to 1MB
+func maxBodySize() int64 {
+	if n, ok := numericValue(config.Get("app.max_body_size")); ok && n > 0 {
+		return int64(n)
+	}
+	return 1 << 20
+}
+
+// RawBody returns the request body. It is read once and cached, and the
+// request body is reset on every call so later JSON decoding or form
+// parsing still sees the full content. Bodies over app.max_body_size fail
+// with a 413 *req.MalformedRequest.
+func (c *Context) RawBody() ([]byte, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if !c.rawBodyRead {
+		if c.request.Body != nil {
+			body, err := io.ReadAll(http.MaxBytesReader(c.writer, c.request.Body, maxBodySize()))
+			c.request.Body.Close()
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					err = req.BodyTooLarge(tooLarge.Limit)
+				}
+				c.rawBodyErr = err
+			} else {
+				c.rawBody = body
+			}
+		}
+		c.rawBodyRead = true
+	}
+
+	if c.rawBodyErr != nil {
+		return nil, c.rawBodyErr
+	}
+
+	c.request.Body = io.NopCloser(bytes.NewReader(c.rawBody))
+	return c.rawBody, nil
+}
+
 func (c *Context) HasMultiPartRequest() bool {
 	contentType := strings.ToLower(c.GetHeader("Content-Type"))
 	return contentType != "" && strings.HasPrefix(contentType, "multipart/")
@@ -431,7 +1079,7 @@ func (c *Context) Form() (map[string][]string, error) {
 	var err error
 
 	if c.HasMultiPartRequest() {
-		err = c.request.ParseMultipartForm(32 << 20)
+		err = c.request.ParseMultipartForm(multipartMaxMemory())
 	}
 
 	if c.HasFormURLEncodedRequest() {
@@ -455,20 +1103,49 @@ func (c *Context) Body() (map[string][]string, error) {
 	return c.request.Form, nil
 }
 
-func (c *Context) FormFile(key string) (multipart.File, *multipart.FileHeader, error) {
-	if file, _, err := c.request.FormFile(key); file != nil && err == nil {
-		return c.request.FormFile(key)
+// defaultMultipartMaxMemory is used when no threshold is configured
+const defaultMultipartMaxMemory = 32 << 20
+
+// multipartMemoryOverride holds the value set by SetMultipartMaxMemory
+var multipartMemoryOverride atomic.Int64
+
+// SetMultipartMaxMemory sets how many bytes of a multipart body are kept in
+// memory before file parts spill to temporary files, overriding config.
+// A non-positive value restores the configured threshold.
+func SetMultipartMaxMemory(bytes int64) {
+	multipartMemoryOverride.Store(bytes)
+}
+
+// multipartMaxMemory returns the multipart memory threshold: the value from
+// SetMultipartMaxMemory, else request.multipart_max_memory, else 32MB
+func multipartMaxMemory() int64 {
+	if n := multipartMemoryOverride.Load(); n > 0 {
+		return n
+	}
+	if n, ok := numericValue(config.Get("request.multipart_max_memory")); ok && n > 0 {
+		return int64(n)
 	}
+	return defaultMultipartMaxMemory
+}
 
-	if err := c.request.ParseMultipartForm(32 << 20); err != nil {
-		return nil, nil, err
+func (c *Context) FormFile(key string) (multipart.File, *multipart.FileHeader, error) {
+	// Parse with the configured threshold before http.Request.FormFile
+	// falls back to its own default
+	if c.request.MultipartForm == nil {
+		if err := c.request.ParseMultipartForm(multipartMaxMemory()); err != nil {
+			return nil, nil, err
+		}
 	}
 	return c.request.FormFile(key)
 }
 
 func (c *Context) HasFile(key string) bool {
-	_, _, err := c.request.FormFile(key)
-	return err == nil
+	file, _, err := c.FormFile(key)
+	if err != nil {
+		return false
+	}
+	file.Close()
+	return true
 }
 
 func (c *Context) Upload(uploadedFileName string, dir string, filename ...string) (*os.File, error) {
@@ -533,6 +1210,11 @@ func (c *Context) File(path string, headers ...map[string][]string) error {
 			}
 		}
 	}
+
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
 	_, err = io.Copy(c.writer, file)
 	return err
 }
@@ -576,6 +1258,10 @@ func (c *Context) StorageFile(path string, headers ...map[string][]string) error
 		}
 	}
 
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
 	_, err = io.Copy(c.writer, file)
 	return err
 }
@@ -610,11 +1296,45 @@ func (c *Context) Download(path string, filename string) error {
 
 	c.writer.Header().Set("content-type", "application/octet-stream")
 	c.writer.Header().Set("content-disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
 	_, err = io.Copy(c.writer, file)
 	return err
 }
 
+// DownloadReader streams r to the client as an attachment named filename.
+// An empty contentType defaults to application/octet-stream and a negative
+// size leaves the Content-Length unset.
+func (c *Context) DownloadReader(r io.Reader, filename string, contentType string, size int64) error {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.writer.Header().Set("content-type", contentType)
+	c.writer.Header().Set("content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	if size >= 0 {
+		c.writer.Header().Set("content-length", strconv.FormatInt(size, 10))
+	}
+
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.WriteStatus(c.status)
+
+	_, err := io.Copy(c.writer, r)
+	return err
+}
+
 func (c *Context) Set(key string, value interface{}) {
+	c.SetValue(key, value)
+}
+
+// SetValue stores a value in the request context under any comparable key,
+// such as a value of an unexported key type that can't collide with other
+// packages. Set is the same for string keys.
+func (c *Context) SetValue(key any, value any) {
 	c.Lock()
 	defer c.Unlock()
 	c.request = c.request.WithContext(context.WithValue(c.request.Context(), key, value))
@@ -627,17 +1347,106 @@ func (c *Context) SetRequest(r *http.Request) {
 }
 
 func (c *Context) Get(key string) any {
+	return c.Value(key)
+}
+
+// Value returns the request context value stored under key, which may be of
+// any type; see SetValue
+func (c *Context) Value(key any) any {
 	c.Lock()
 	defer c.Unlock()
 	return c.request.Context().Value(key)
 }
 
-func (c *Context) PutSession(key string, value any) *Context {
+// SetLocal stores a value for the lifetime of this request only. Unlike
+// Set, it does not touch the request's context.Context, so the value is
+// invisible to code that only sees the *http.Request; unlike PutSession,
+// it is never persisted.
+func (c *Context) SetLocal(key string, v any) {
+	c.Lock()
+	defer c.Unlock()
+	if c.locals == nil {
+		c.locals = make(map[string]any)
+	}
+	c.locals[key] = v
+}
+
+// Locals returns the value stored with SetLocal, or nil
+func (c *Context) Locals(key string) any {
+	c.Lock()
+	defer c.Unlock()
+	return c.locals[key]
+}
+
+// LocaleKey is the request context key holding the resolved locale
+const LocaleKey = "locale"
+
+// Locale returns the locale resolved by the Locale middleware, falling back
+// to app.locale and then "en"
+func (c *Context) Locale() string {
+	if locale, ok := c.Get(LocaleKey).(string); ok && locale != "" {
+		return locale
+	}
+	if locale, ok := config.Get("app.locale").(string); ok && locale != "" {
+		return locale
+	}
+	return "en"
+}
+
+// Provide returns a handler that stores value under key in the request
+// context before the rest of the chain runs, readable with c.Value(key), or
+// c.Get(key) for a string key
+func Provide(key any, value any) Handler {
+	return func(c *Context) error {
+		c.SetValue(key, value)
+		return c.Next()
+	}
+}
+
+// session returns the session manager, or ErrSessionNotSet when no session
+// provider is registered or the request didn't pass through its middleware
+func (c *Context) session() (*session.Session, error) {
 	var sess *session.Session
+	if err := c.App().Service(&sess); err != nil || sess == nil {
+		return nil, ErrSessionNotSet
+	}
+	if !sess.Loaded(c.Request().Context()) {
+		return nil, ErrSessionNotSet
+	}
+	return sess, nil
+}
 
-	if err := c.App().Service(&sess); err != nil {
-		slog.Error(err.Error())
-		return nil
+// sessionFailed records and logs an error from a session method that cannot
+// return it
+func (c *Context) sessionFailed(err error) {
+	c.sessionErr = err
+	slog.Error("Session unavailable", "path", c.request.URL.Path, "error", err)
+}
+
+// SessionErr returns the error of the last session method that failed
+// during this request, typically ErrSessionNotSet, or nil. Session getters
+// return nil or "" on failure, so check SessionErr where a missing session
+// must not be mistaken for a missing value.
+func (c *Context) SessionErr() error {
+	return c.sessionErr
+}
+
+// SessionAvailable reports whether session methods can be used for this
+// request. When it returns false they are no-ops and FlushSession returns
+// ErrSessionNotSet.
+func (c *Context) SessionAvailable() bool {
+	_, err := c.session()
+	return err == nil
+}
+
+// PutSession stores value in the session under key. Without a session it
+// records the error for SessionErr and returns c unchanged, so chained calls
+// like c.WithSuccess("saved").Redirect("/") still respond.
+func (c *Context) PutSession(key string, value any) *Context {
+	sess, err := c.session()
+	if err != nil {
+		c.sessionFailed(err)
+		return c
 	}
 
 	sess.Put(c.Request().Context(), key, value)
@@ -645,10 +1454,9 @@ func (c *Context) PutSession(key string, value any) *Context {
 }
 
 func (c *Context) PopSession(key string) any {
-	var sess *session.Session
-
-	if err := c.App().Service(&sess); err != nil {
-		slog.Error(err.Error())
+	sess, err := c.session()
+	if err != nil {
+		c.sessionFailed(err)
 		return nil
 	}
 
@@ -656,10 +1464,9 @@ func (c *Context) PopSession(key string) any {
 }
 
 func (c *Context) PopSessionString(key string) string {
-	var sess *session.Session
-
-	if err := c.App().Service(&sess); err != nil {
-		slog.Error(err.Error())
+	sess, err := c.session()
+	if err != nil {
+		c.sessionFailed(err)
 		return ""
 	}
 
@@ -667,10 +1474,9 @@ func (c *Context) PopSessionString(key string) string {
 }
 
 func (c *Context) GetSession(key string) any {
-	var sess *session.Session
-
-	if err := c.App().Service(&sess); err != nil {
-		slog.Error(err.Error())
+	sess, err := c.session()
+	if err != nil {
+		c.sessionFailed(err)
 		return nil
 	}
 
@@ -678,21 +1484,76 @@ func (c *Context) GetSession(key string) any {
 }
 
 func (c *Context) GetSessionString(key string) string {
-	var sess *session.Session
-
-	if err := c.App().Service(&sess); err != nil {
-		slog.Error(err.Error())
+	sess, err := c.session()
+	if err != nil {
+		c.sessionFailed(err)
 		return ""
 	}
 
 	return sess.GetString(c.Request().Context(), key)
 }
 
+// SessionKeys returns the keys currently stored in the session, sorted
+func (c *Context) SessionKeys() []string {
+	sess, err := c.session()
+	if err != nil {
+		c.sessionFailed(err)
+		return nil
+	}
+
+	return sess.Keys(c.Request().Context())
+}
+
+// ForgetSession removes the given keys from the session
+func (c *Context) ForgetSession(keys ...string) *Context {
+	sess, err := c.session()
+	if err != nil {
+		c.sessionFailed(err)
+		return c
+	}
+
+	for _, key := range keys {
+		sess.Remove(c.Request().Context(), key)
+	}
+	return c
+}
+
+// FlushSession removes all session data except the CSRF token
+func (c *Context) FlushSession() error {
+	sess, err := c.session()
+	if err != nil {
+		return err
+	}
+
+	token := sess.GetString(c.Request().Context(), "_token")
+	if err := sess.Clear(c.Request().Context()); err != nil {
+		return err
+	}
+
+	if token != "" {
+		sess.Put(c.Request().Context(), "_token", token)
+	}
+	return nil
+}
+
+// wantsJSONErrors reports whether errors are sent as JSON, as set by the
+// route's ErrorMode or else by the Accept header
+func (c *Context) wantsJSONErrors() bool {
+	switch c.errorMode {
+	case JSONErrors:
+		return true
+	case HTMLErrors:
+		return false
+	default:
+		return c.WantsJSON()
+	}
+}
+
 func (c *Context) Error(status int, err error) error {
-	if c.WantsJSON() {
-		return c.JSON(M{"message": err.Error()})
+	if c.wantsJSONErrors() {
+		return c.Status(status).JSON(M{"message": err.Error()})
 	}
-	c.writer.WriteHeader(status)
+	c.WriteStatus(status)
 	if _, e := c.writer.Write([]byte(err.Error())); e != nil {
 		return err
 	}
@@ -706,11 +1567,12 @@ func (c *Context) ValidationError(err error) error {
 		return c.Error(http.StatusInternalServerError, err)
 	}
 
-	if c.WantsJSON() || c.Referer() == "" {
-		return c.Status(http.StatusUnprocessableEntity).JSON(M{"errors": err})
+	if c.wantsJSONErrors() || (c.errorMode == AutoErrors && c.Referer() == "") {
+		envelope := e.Envelope()
+		return c.Status(http.StatusUnprocessableEntity).JSON(M{"message": envelope.Message, "errors": envelope.Errors})
 	}
 
-	return c.WithErrors(err.(shared.ValidationErrors)).WithInput().Back()
+	return c.WithErrors(e).WithInput().Back()
 }
 
 func (c *Context) InternalServerError(err error) error {
@@ -733,15 +1595,88 @@ func (c *Context) Forbidden(err error) error {
 	return c.Error(http.StatusForbidden, err)
 }
 
+// StatusPageExpired is the non-standard status sent when a CSRF token is
+// missing or stale
+const StatusPageExpired = 419
+
+// PageExpiredHandler, when set, renders the response of PageExpired, e.g.
+// to show the app's own session-expired page
+var PageExpiredHandler Handler
+
+const pageExpiredHTML = `<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>Page Expired</title></head>
+<body>
+<h1>419 | Page Expired</h1>
+<p>This page has expired. Please go back, refresh the page and try again.</p>
+</body>
+</html>`
+
+// PageExpired responds with a 419, typically after a CSRF check failed:
+// {"message": "page expired"} for clients that want JSON, an HTML page for
+// browsers and plain text otherwise. Set PageExpiredHandler to override it.
 func (c *Context) PageExpired() error {
-	return c.Error(419, errors.New("page expired"))
+	c.Status(StatusPageExpired)
+	if PageExpiredHandler != nil {
+		return PageExpiredHandler(c)
+	}
+
+	switch {
+	case c.wantsJSONErrors():
+		return c.JSON(M{"message": "page expired"})
+	case c.errorMode == HTMLErrors || c.WantsHTML():
+		return c.HTML([]byte(pageExpiredHTML))
+	default:
+		return c.Text([]byte("page expired"))
+	}
 }
 
 func (c *Context) NoContent() error {
-	c.Status(204).writer.Write(nil)
+	c.status = http.StatusNoContent
+	c.WriteStatus(c.status)
+	return nil
+}
+
+// NotModified sends a bare 304 response
+func (c *Context) NotModified() error {
+	c.status = http.StatusNotModified
+	c.WriteStatus(c.status)
 	return nil
 }
 
+// Fresh reports whether the client's cached copy, described by the
+// If-None-Match and If-Modified-Since headers, is still valid for the given
+// validators, in which case NotModified can be sent. If-None-Match takes
+// precedence when present. Pass an empty etag or a zero time to skip a validator.
+func (c *Context) Fresh(etag string, lastModified time.Time) bool {
+	if c.request.Method != http.MethodGet && c.request.Method != http.MethodHead {
+		return false
+	}
+
+	if noneMatch := c.request.Header.Get("If-None-Match"); noneMatch != "" {
+		if etag == "" {
+			return false
+		}
+		for _, candidate := range strings.Split(noneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if modifiedSince := c.request.Header.Get("If-Modified-Since"); modifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(modifiedSince)
+		if err != nil {
+			return false
+		}
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+
+	return false
+}
+
 func (c *Context) DecodeJSON(v interface{}) error {
 	return req.DecodeJSONBody(c.writer, c.request, v)
 }
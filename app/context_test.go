@@ -0,0 +1,616 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/req"
+)
+
+func TestDownloadReader(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	body := strings.Repeat("x", 64*1024)
+	if err := c.DownloadReader(strings.NewReader(body), `report "q1".csv`, "text/csv", int64(len(body))); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/csv" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Content-Length"); got != "65536" {
+		t.Errorf("Content-Length = %q", got)
+	}
+
+	disposition, params, err := mime.ParseMediaType(w.Header().Get("Content-Disposition"))
+	if err != nil || disposition != "attachment" || params["filename"] != `report "q1".csv` {
+		t.Errorf("Content-Disposition = %q", w.Header().Get("Content-Disposition"))
+	}
+	if w.Body.String() != body {
+		t.Errorf("streamed %d bytes, want %d", w.Body.Len(), len(body))
+	}
+}
+
+func TestDownloadReaderDefaults(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if err := c.DownloadReader(strings.NewReader("data"), "file.bin", "", -1); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/octet-stream" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Content-Length"); got != "" {
+		t.Errorf("Content-Length = %q, want unset", got)
+	}
+	if w.Body.String() != "data" {
+		t.Errorf("body = %q", w.Body.String())
+	}
+}
+
+func TestFresh(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		etag    string
+		want    bool
+	}{
+		{"matching etag", http.MethodGet, map[string]string{"If-None-Match": `"v1"`}, `"v1"`, true},
+		{"matching one of several etags", http.MethodGet, map[string]string{"If-None-Match": `"v0", "v1"`}, `"v1"`, true},
+		{"weak etag", http.MethodGet, map[string]string{"If-None-Match": `W/"v1"`}, `"v1"`, true},
+		{"wildcard", http.MethodGet, map[string]string{"If-None-Match": "*"}, `"v1"`, true},
+		{"non-matching etag", http.MethodGet, map[string]string{"If-None-Match": `"v0"`}, `"v1"`, false},
+		{"etag wins over date", http.MethodGet, map[string]string{"If-None-Match": `"v0"`, "If-Modified-Since": modified.Format(http.TimeFormat)}, `"v1"`, false},
+		{"not modified since", http.MethodGet, map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)}, "", true},
+		{"modified since", http.MethodGet, map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}, "", false},
+		{"invalid date", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, "", false},
+		{"no validators", http.MethodGet, nil, `"v1"`, false},
+		{"head", http.MethodHead, map[string]string{"If-None-Match": `"v1"`}, `"v1"`, true},
+		{"post", http.MethodPost, map[string]string{"If-None-Match": `"v1"`}, `"v1"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/", nil)
+			for key, value := range tt.headers {
+				r.Header.Set(key, value)
+			}
+			c, _ := newTestContext(r)
+
+			// Sub-second precision is dropped, as in the header
+			if got := c.Fresh(tt.etag, modified.Add(500*time.Millisecond)); got != tt.want {
+				t.Errorf("Fresh() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if err := c.NotModified(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNotModified {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotModified)
+	}
+	if w.Body.Len() != 0 {
+		t.Errorf("body = %q, want empty", w.Body.String())
+	}
+}
+
+type providedKey struct{}
+
+func TestProvide(t *testing.T) {
+	srv, shutDown := TestServer(WithRoutes(func(r Router) {
+		r.Get("/",
+			Provide("tenant", "acme"),
+			Provide(providedKey{}, 42),
+			func(c *Context) error {
+				return c.Text([]byte(fmt.Sprint(c.Get("tenant"), " ", c.Value(providedKey{}), " ", c.Get("missing"))))
+			},
+		)
+	}))
+	defer shutDown()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "acme 42 <nil>" {
+		t.Fatalf("body = %q, want %q", body, "acme 42 <nil>")
+	}
+}
+
+func TestSetAndValueKeysDoNotCollide(t *testing.T) {
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	c.Set("key", "string")
+	c.SetValue(providedKey{}, "typed")
+
+	if c.Get("key") != "string" || c.Value("key") != "string" {
+		t.Errorf("string key = %v / %v", c.Get("key"), c.Value("key"))
+	}
+	if c.Value(providedKey{}) != "typed" {
+		t.Errorf("typed key = %v", c.Value(providedKey{}))
+	}
+	if c.Request().Context().Value(providedKey{}) != "typed" {
+		t.Error("typed value missing from the request context")
+	}
+}
+
+func TestRawBodyReadTwice(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":"john"}`))
+
+	first, err := c.RawBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := c.RawBody()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(first) != `{"name":"john"}` || string(second) != string(first) {
+		t.Fatalf("RawBody() = %q then %q", first, second)
+	}
+
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := c.DecodeJSON(&in); err != nil || in.Name != "john" {
+		t.Fatalf("DecodeJSON after RawBody = %+v, %v", in, err)
+	}
+}
+
+func TestRawBodyTooLarge(t *testing.T) {
+	config.Set("app.max_body_size", 16)
+	defer config.Set("app.max_body_size", nil)
+
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":"a name longer than sixteen bytes"}`))
+
+	for i := 0; i < 2; i++ {
+		_, err := c.RawBody()
+		var mr *req.MalformedRequest
+		if !errors.As(err, &mr) || mr.Status != http.StatusRequestEntityTooLarge {
+			t.Fatalf("call %d: RawBody() error = %v, want a 413 MalformedRequest", i+1, err)
+		}
+		if mr.Message != "Request body must not be larger than 16 bytes" {
+			t.Errorf("message = %q", mr.Message)
+		}
+	}
+
+	if _, err := c.All(); err == nil {
+		t.Error("All() accepted an oversized body")
+	}
+}
+
+func TestRawBodyLimitOverHTTP(t *testing.T) {
+	config.Set("app.max_body_size", 1024)
+	defer config.Set("app.max_body_size", nil)
+
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Post("/echo", func(c *Context) error {
+			input, err := c.All()
+			if err != nil {
+				return err
+			}
+			return c.JSON(input)
+		})
+	}))
+	defer shutDown()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, jsonRequest(http.MethodPost, "/echo", `{"name":"john"}`))
+	if w.Code != http.StatusOK {
+		t.Fatalf("small body: status = %d, want 200", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, jsonRequest(http.MethodPost, "/echo", `{"name":"`+strings.Repeat("x", 2048)+`"}`))
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "1KB") {
+		t.Fatalf("large body: got %d %s, want 413 naming the 1KB limit", w.Code, w.Body.String())
+	}
+}
+
+// headerCounter records how often WriteHeader is called
+type headerCounter struct {
+	*httptest.ResponseRecorder
+	calls int
+}
+
+func (w *headerCounter) WriteHeader(status int) {
+	w.calls++
+	w.ResponseRecorder.WriteHeader(status)
+}
+
+func TestNoContent(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodDelete, "/", nil))
+
+	if err := c.NoContent(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("got %d with %q, want an empty 204", w.Code, w.Body.String())
+	}
+	if !c.HeadersSent() {
+		t.Error("HeadersSent() = false after NoContent")
+	}
+}
+
+func TestSecondResponderKeepsFirstStatus(t *testing.T) {
+	responders := map[string]func(c *Context) error{
+		"NoContent":   (*Context).NoContent,
+		"NotModified": (*Context).NotModified,
+		"Redirect":    func(c *Context) error { return c.Redirect("/next") },
+		"Back":        func(c *Context) error { return c.Back() },
+		"JSON":        func(c *Context) error { return c.Status(http.StatusCreated).JSON(M{"id": 1}) },
+		"Text":        func(c *Context) error { return c.Status(http.StatusAccepted).Text([]byte("ok")) },
+		"Error":       func(c *Context) error { return c.Error(http.StatusConflict, errors.New("conflict")) },
+	}
+	want := map[string]int{
+		"NoContent":   http.StatusNoContent,
+		"NotModified": http.StatusNotModified,
+		"Redirect":    http.StatusFound,
+		"Back":        http.StatusFound,
+		"JSON":        http.StatusCreated,
+		"Text":        http.StatusAccepted,
+		"Error":       http.StatusConflict,
+	}
+
+	for name, first := range responders {
+		t.Run(name, func(t *testing.T) {
+			w := &headerCounter{ResponseRecorder: httptest.NewRecorder()}
+			c := &Context{app: Get(), request: httptest.NewRequest(http.MethodPost, "/", nil), writer: w, index: -1}
+
+			if err := first(c); err != nil {
+				t.Fatal(err)
+			}
+			if !c.HeadersSent() {
+				t.Fatalf("HeadersSent() = false after %s", name)
+			}
+
+			c.Status(http.StatusInternalServerError).Text([]byte("second"))
+
+			if w.Code != want[name] {
+				t.Errorf("status = %d, want %d from the first responder", w.Code, want[name])
+			}
+			if w.calls != 1 {
+				t.Errorf("WriteHeader called %d times, want 1", w.calls)
+			}
+		})
+	}
+}
+
+func TestFileMarksHeadersSent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := c.File(path); err != nil {
+		t.Fatal(err)
+	}
+	if !c.HeadersSent() || w.Code != http.StatusOK || w.Body.String() != "hello" {
+		t.Errorf("got %d %q, HeadersSent() = %v", w.Code, w.Body.String(), c.HeadersSent())
+	}
+}
+
+func TestPageExpired(t *testing.T) {
+	tests := []struct {
+		name        string
+		accept      string
+		mode        ErrorMode
+		contentType string
+		body        string
+	}{
+		{"json accept", "application/json", 0, "application/json", `"page expired"`},
+		{"html accept", "text/html,application/xhtml+xml,*/*;q=0.8", 0, "text/html", "<html"},
+		{"no accept", "", 0, "text/plain", "page expired"},
+		{"json mode wins over html accept", "text/html", JSONErrors, "application/json", `"page expired"`},
+		{"html mode wins over json accept", "application/json", HTMLErrors, "text/html", "<html"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/form", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			c, w := newTestContext(r)
+			c.errorMode = tt.mode
+
+			if err := c.PageExpired(); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != StatusPageExpired {
+				t.Errorf("status = %d, want 419", w.Code)
+			}
+			if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.contentType) {
+				t.Errorf("Content-Type = %q, want %s", got, tt.contentType)
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("body = %s, want it to contain %s", w.Body.String(), tt.body)
+			}
+		})
+	}
+}
+
+func TestPageExpiredHandler(t *testing.T) {
+	PageExpiredHandler = func(c *Context) error {
+		return c.Text([]byte("session timed out, reload the page"))
+	}
+	defer func() { PageExpiredHandler = nil }()
+
+	c, w := newTestContext(httptest.NewRequest(http.MethodPost, "/form", nil))
+	if err := c.PageExpired(); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != StatusPageExpired || w.Body.String() != "session timed out, reload the page" {
+		t.Errorf("got %d %q, want 419 from the custom handler", w.Code, w.Body.String())
+	}
+}
+
+func TestVary(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		add      []string
+		want     string
+	}{
+		{"adds headers", nil, []string{"Accept", "accept-encoding"}, "Accept, Accept-Encoding"},
+		{"skips duplicates", []string{"Accept"}, []string{"accept", "Cookie"}, "Accept, Cookie"},
+		{"merges lines", []string{"Accept", "Origin, Cookie"}, []string{"Origin"}, "Accept, Origin, Cookie"},
+		{"skips empty values", nil, []string{"", " ", "Cookie"}, "Cookie"},
+		{"star wins", []string{"Accept"}, []string{"Cookie", "*"}, "*"},
+		{"star is kept", []string{"*"}, []string{"Accept"}, "*"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+			for _, v := range tt.existing {
+				w.Header().Add("Vary", v)
+			}
+
+			if got := c.Vary(tt.add...); got != c {
+				t.Fatal("Vary() did not return the context")
+			}
+			if got := strings.Join(w.Header().Values("Vary"), ", "); got != tt.want {
+				t.Errorf("Vary = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheControl(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	c.CacheControl("public, max-age=3600").CacheControl("private, max-age=60")
+
+	if got := w.Header().Values("Cache-Control"); len(got) != 1 || got[0] != "private, max-age=60" {
+		t.Errorf("Cache-Control = %q, want the last value only", got)
+	}
+}
+
+func TestNoCache(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	c.CacheControl("public, max-age=3600").NoCache()
+
+	want := map[string]string{
+		"Cache-Control": "no-cache, no-store, must-revalidate",
+		"Pragma":        "no-cache",
+		"Expires":       "0",
+	}
+	for key, value := range want {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestRequestAndResponseHeaders(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Add("X-Trace", "first")
+	r.Header.Add("X-Trace", "second")
+	c, w := newTestContext(r)
+
+	if got := c.RequestHeader("x-trace"); got != "first" {
+		t.Errorf("RequestHeader(x-trace) = %q, want the first value", got)
+	}
+	if got := c.GetHeader("X-Trace"); got != "first" {
+		t.Errorf("GetHeader(X-Trace) = %q, want the first value", got)
+	}
+	if got := c.ResponseHeader("X-Trace"); got != "" {
+		t.Errorf("ResponseHeader(X-Trace) = %q, want request headers kept apart", got)
+	}
+
+	c.AddHeader("Link", "</a.css>; rel=preload")
+	c.AddHeader("link", "</b.js>; rel=preload")
+	if got := w.Header().Values("Link"); len(got) != 2 {
+		t.Errorf("Link after AddHeader twice = %q, want both values", got)
+	}
+	if got := c.ResponseHeader("link"); got != "</a.css>; rel=preload" {
+		t.Errorf("ResponseHeader(link) = %q, want the first value", got)
+	}
+
+	c.SetHeader("link", "</c.css>; rel=preload")
+	if got := w.Header().Values("Link"); len(got) != 1 || got[0] != "</c.css>; rel=preload" {
+		t.Errorf("Link after SetHeader = %q, want a single replaced value", got)
+	}
+	if got := c.RequestHeader("Link"); got != "" {
+		t.Errorf("RequestHeader(Link) = %q, want response headers kept apart", got)
+	}
+}
+
+type greeting string
+
+func (g greeting) String() string { return "<p>hello " + string(g) + "</p>" }
+
+// writerTo writes body and then fails with err, recording whether it
+// wrote to the response directly
+type writerTo struct {
+	body     string
+	err      error
+	streamed bool
+}
+
+func (wt *writerTo) WriteTo(w io.Writer) (int64, error) {
+	_, wt.streamed = w.(http.ResponseWriter)
+	n, _ := io.WriteString(w, wt.body)
+	return int64(n), wt.err
+}
+
+func TestRenderString(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType []string
+		want        string
+	}{
+		{"sniffs html", "<!DOCTYPE html><p>hi</p>", nil, "text/html; charset=utf-8"},
+		{"sniffs text", "hello", nil, "text/plain; charset=utf-8"},
+		{"sniffs json as text", `{"id":1}`, nil, "text/plain; charset=utf-8"},
+		{"explicit type", `{"id":1}`, []string{"application/json"}, "application/json"},
+		{"empty type sniffs", "<p>hi</p>", []string{""}, "text/html; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+			if err := c.RenderString(tt.body, tt.contentType...); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != http.StatusOK || w.Body.String() != tt.body {
+				t.Errorf("response = %d %q, want 200 %q", w.Code, w.Body.String(), tt.body)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.want {
+				t.Errorf("Content-Type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderStringKeepsStatus(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodPost, "/", nil))
+	if err := c.Status(http.StatusCreated).RenderString("created"); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusCreated || !c.HeadersSent() {
+		t.Errorf("status = %d, headers sent = %v, want 201 sent", w.Code, c.HeadersSent())
+	}
+}
+
+func TestRenderStringer(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := c.RenderStringer(greeting("john")); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "<p>hello john</p>" || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("response = %q as %q, want the sniffed String()", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	c, w = newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := c.RenderStringer(greeting("john"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %q, want text/plain", got)
+	}
+}
+
+func TestRenderWriterTo(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	wt := &writerTo{body: "<html><body>hi</body></html>"}
+	if err := c.RenderWriterTo(wt); err != nil {
+		t.Fatal(err)
+	}
+	if wt.streamed {
+		t.Error("output was streamed although the content type had to be sniffed")
+	}
+	if w.Body.String() != wt.body || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("response = %q as %q, want the sniffed output", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	c, w = newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	wt = &writerTo{body: "a,b\n1,2\n"}
+	if err := c.Status(http.StatusAccepted).RenderWriterTo(wt, "text/csv"); err != nil {
+		t.Fatal(err)
+	}
+	if !wt.streamed {
+		t.Error("output was buffered although a content type was given")
+	}
+	if w.Code != http.StatusAccepted || w.Body.String() != wt.body || w.Header().Get("Content-Type") != "text/csv" {
+		t.Errorf("response = %d %q as %q, want 202 text/csv", w.Code, w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
+
+func TestRenderWriterToError(t *testing.T) {
+	failed := errors.New("render failed")
+
+	// A buffered render fails before anything is sent, so the error can
+	// still be rendered
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := c.RenderWriterTo(&writerTo{body: "partial", err: failed}); !errors.Is(err, failed) {
+		t.Fatalf("RenderWriterTo() = %v, want %v", err, failed)
+	}
+	if c.HeadersSent() || w.Body.Len() != 0 {
+		t.Errorf("sent %q, want nothing after a buffered failure", w.Body.String())
+	}
+
+	c, w = newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := c.RenderWriterTo(&writerTo{body: "partial", err: failed}, "text/plain"); !errors.Is(err, failed) {
+		t.Fatalf("RenderWriterTo() = %v, want %v", err, failed)
+	}
+	if !c.HeadersSent() || w.Body.String() != "partial" {
+		t.Errorf("sent %q, want the streamed output before the failure", w.Body.String())
+	}
+}
+
+func TestLocale(t *testing.T) {
+	tests := []struct {
+		name     string
+		resolved any
+		conf     any
+		want     string
+	}{
+		{"resolved by the middleware", "fr", "de", "fr"},
+		{"configured", nil, "de", "de"},
+		{"default", nil, nil, "en"},
+		{"empty config", nil, "", "en"},
+		{"non-string config", nil, []string{"de", "fr"}, "en"},
+		{"numeric config", nil, 1, "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Set("app.locale", tt.conf)
+			defer config.Set("app.locale", nil)
+
+			c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+			if tt.resolved != nil {
+				c.Set(LocaleKey, tt.resolved)
+			}
+			if got := c.Locale(); got != tt.want {
+				t.Errorf("Locale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
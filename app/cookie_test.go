@@ -0,0 +1,142 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCookiesNone(t *testing.T) {
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if cookies := c.Cookies(); len(cookies) != 0 {
+		t.Errorf("Cookies() = %v, want none", cookies)
+	}
+	if c.HasCookie("session") {
+		t.Error("HasCookie(session) = true without cookies")
+	}
+	if c.Cookie("session") != nil {
+		t.Error("Cookie(session) != nil without cookies")
+	}
+}
+
+func TestCookiesSeveral(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.AddCookie(&http.Cookie{Name: "session", Value: "abc"})
+	r.AddCookie(&http.Cookie{Name: "theme", Value: "dark"})
+	r.AddCookie(&http.Cookie{Name: "empty", Value: ""})
+	c, _ := newTestContext(r)
+
+	cookies := c.Cookies()
+	if len(cookies) != 3 {
+		t.Fatalf("Cookies() returned %d cookies, want 3", len(cookies))
+	}
+	want := map[string]string{"session": "abc", "theme": "dark", "empty": ""}
+	for _, cookie := range cookies {
+		if value, ok := want[cookie.Name]; !ok || value != cookie.Value {
+			t.Errorf("unexpected cookie %s=%q", cookie.Name, cookie.Value)
+		}
+	}
+
+	for _, name := range []string{"session", "theme", "empty"} {
+		if !c.HasCookie(name) {
+			t.Errorf("HasCookie(%q) = false", name)
+		}
+	}
+	if c.HasCookie("missing") {
+		t.Error("HasCookie(missing) = true")
+	}
+	if got := c.Cookie("theme"); got == nil || got.Value != "dark" {
+		t.Errorf("Cookie(theme) = %v", got)
+	}
+}
+
+// signedCookie sets name=value with SetSignedCookie and returns the cookie
+// the client would send back.
+func signedCookie(t *testing.T, name, value string) *http.Cookie {
+	t.Helper()
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	c.SetSignedCookie(&http.Cookie{Name: name, Value: value, Path: "/"})
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("SetSignedCookie wrote %d cookies, want 1", len(cookies))
+	}
+	return cookies[0]
+}
+
+func readSignedCookie(cookie *http.Cookie, name string) (string, bool) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	c, _ := newTestContext(r)
+	return c.SignedCookie(name)
+}
+
+func TestSignedCookieRoundTrip(t *testing.T) {
+	t.Setenv("APP_KEY", "test-app-key")
+
+	for _, value := range []string{"dark", "", "v1.2.3", "a=b"} {
+		cookie := signedCookie(t, "theme", value)
+		if cookie.Value == value {
+			t.Errorf("SetSignedCookie(%q) wrote the value unsigned", value)
+		}
+		got, ok := readSignedCookie(cookie, "theme")
+		if !ok || got != value {
+			t.Errorf("SignedCookie() = %q, %v, want %q, true", got, ok, value)
+		}
+	}
+}
+
+func TestSignedCookieTampered(t *testing.T) {
+	t.Setenv("APP_KEY", "test-app-key")
+	cookie := signedCookie(t, "theme", "dark")
+	signature := cookie.Value[len("dark"):]
+
+	tests := []struct {
+		name   string
+		cookie *http.Cookie
+		read   string
+	}{
+		{"value changed", &http.Cookie{Name: "theme", Value: "light" + signature}, "theme"},
+		{"signature changed", &http.Cookie{Name: "theme", Value: cookie.Value[:len(cookie.Value)-2] + "AA"}, "theme"},
+		{"signature stripped", &http.Cookie{Name: "theme", Value: "dark"}, "theme"},
+		{"signature not base64", &http.Cookie{Name: "theme", Value: "dark.!!!"}, "theme"},
+		{"moved to another name", &http.Cookie{Name: "role", Value: cookie.Value}, "role"},
+		{"missing", nil, "theme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := readSignedCookie(tt.cookie, tt.read); ok || got != "" {
+				t.Errorf("SignedCookie() = %q, %v, want rejection", got, ok)
+			}
+		})
+	}
+}
+
+func TestSignedCookieKeyRotation(t *testing.T) {
+	t.Setenv("APP_KEY", "old-key")
+	cookie := signedCookie(t, "theme", "dark")
+
+	t.Setenv("APP_KEY", "new-key")
+	if _, ok := readSignedCookie(cookie, "theme"); ok {
+		t.Error("SignedCookie() accepted a cookie signed with a different APP_KEY")
+	}
+
+	t.Setenv("APP_KEY", "")
+	if _, ok := readSignedCookie(cookie, "theme"); ok {
+		t.Error("SignedCookie() accepted a cookie without an APP_KEY")
+	}
+}
+
+func TestSetSignedCookieWithoutKey(t *testing.T) {
+	t.Setenv("APP_KEY", "")
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	c.SetSignedCookie(&http.Cookie{Name: "theme", Value: "dark"})
+
+	if cookies := w.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("SetSignedCookie wrote %v without an APP_KEY", cookies)
+	}
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type ctxKey struct{}
+
+func TestDBUsesRequestContext(t *testing.T) {
+	openUniqueTestDB(t)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, "request"))
+	c, _ := newTestContext(r)
+
+	tx := c.DB()
+	if got := tx.Statement.Context.Value(ctxKey{}); got != "request" {
+		t.Fatalf("DB() context value = %v, want the request's", got)
+	}
+	if got := c.DB(t.Name()).Statement.Context.Value(ctxKey{}); got != "request" {
+		t.Fatalf("DB(name) context value = %v, want the request's", got)
+	}
+
+	var count int64
+	if err := tx.Table("users").Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("count = %d, %v", count, err)
+	}
+}
+
+func TestDBQueriesAbortWhenRequestIsCancelled(t *testing.T) {
+	openUniqueTestDB(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	cancel()
+
+	var count int64
+	err := c.DB().Table("users").Count(&count).Error
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("query on a cancelled request = %v, want context.Canceled", err)
+	}
+}
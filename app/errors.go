@@ -4,4 +4,5 @@ import "errors"
 
 var (
 	ErrServiceNotFound = errors.New("service not found")
+	ErrSessionNotSet   = errors.New("session not set")
 )
@@ -0,0 +1,48 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/session"
+)
+
+// newTestContext returns a Context serving r, recording its response
+func newTestContext(r *http.Request) (*Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	return &Context{app: Get(), request: r, writer: w, index: -1}, w
+}
+
+// jsonRequest builds a request with a JSON body
+func jsonRequest(method, target, body string) *http.Request {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, target, reader)
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Accept", "application/json")
+	return r
+}
+
+// newSessionContext returns a Context serving r with a loaded in-memory
+// session, on an application of its own so the global app stays sessionless
+func newSessionContext(t *testing.T, r *http.Request) (*Context, *session.Session, *httptest.ResponseRecorder) {
+	t.Helper()
+	sess := &session.Session{SessionManager: scs.New()}
+	a := &Application{Services: newServiceContainer(), router: newRouter(), config: config.GetInstance()}
+	a.AddService(sess)
+
+	ctx, err := sess.Load(r.Context(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	return &Context{app: a, request: r.WithContext(ctx), writer: w, index: -1}, sess, w
+}
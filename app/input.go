@@ -1,6 +1,13 @@
 package app
 
-import "github.com/ggicci/httpin"
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ggicci/httpin"
+	"github.com/lemmego/api/req"
+)
 
 type BaseInput struct {
 	App       App
@@ -11,3 +18,154 @@ type BaseInput struct {
 type FileInput struct {
 	*httpin.File
 }
+
+// InputOf parses the request into a new T via Context.ParseInput and
+// returns it typed. (app.Input is already the route-level input middleware.)
+func InputOf[T any](c *Context, opts ...req.DecodeOptions) (*T, error) {
+	input := new(T)
+	if err := c.ParseInput(input, opts...); err != nil {
+		return nil, err
+	}
+	return input, nil
+}
+
+// MustInput is like InputOf but panics if the request cannot be parsed
+func MustInput[T any](c *Context, opts ...req.DecodeOptions) *T {
+	input, err := InputOf[T](c, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return input
+}
+
+// All returns the request input as one map, whatever its encoding: the
+// query string merged with a JSON object body or with url-encoded or
+// multipart form values, the body winning on conflicts.
+//
+// Form keys in bracket notation are nested the way a JSON body would be,
+// so "user[name]=john" yields {"user": {"name": "john"}}. A form field sent
+// once is a string and one sent several times, or named with a trailing
+// "[]", a []string. Uploaded files are *multipart.FileHeader values.
+//
+// JSON scalars are converted to the strings a form would send, so
+// {"age": 30, "tags": ["a", "b"]} and "age=30&tags[]=a&tags[]=b" yield the
+// same map. JSON null stays nil.
+func (c *Context) All() (map[string]any, error) {
+	all := map[string]any{}
+	for key, values := range c.request.URL.Query() {
+		setInputValue(all, key, values)
+	}
+
+	if strings.Contains(strings.ToLower(c.GetHeader("Content-Type")), "json") {
+		body, err := c.RawBody()
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(body))) == 0 {
+			return all, nil
+		}
+
+		var fields map[string]any
+		if err := JSONUnmarshal(body, &fields); err != nil {
+			return nil, &req.MalformedRequest{Status: http.StatusBadRequest, Message: "Request body must be a JSON object"}
+		}
+		for key, value := range fields {
+			all[key] = formValue(value)
+		}
+		return all, nil
+	}
+
+	if _, err := c.Form(); err != nil {
+		return nil, err
+	}
+	for key, values := range c.request.PostForm {
+		setInputValue(all, key, values)
+	}
+	if c.request.MultipartForm != nil {
+		for key, files := range c.request.MultipartForm.File {
+			if len(files) == 1 && !strings.HasSuffix(key, "[]") {
+				setInputPath(all, inputPath(key), files[0])
+				continue
+			}
+			setInputPath(all, inputPath(key), files)
+		}
+	}
+
+	return all, nil
+}
+
+// formValue converts a decoded JSON value to its form equivalent: scalars
+// become strings, arrays of scalars a []string, and objects and other
+// arrays are converted element by element
+func formValue(value any) any {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case map[string]any:
+		for key, item := range v {
+			v[key] = formValue(item)
+		}
+		return v
+	case []any:
+		values := make([]string, len(v))
+		scalars := true
+		for i, item := range v {
+			v[i] = formValue(item)
+			s, ok := v[i].(string)
+			values[i] = s
+			scalars = scalars && ok
+		}
+		if scalars {
+			return values
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// setInputValue stores form values under key, as a string when sent once
+// and as a []string when repeated or named "key[]"
+func setInputValue(all map[string]any, key string, values []string) {
+	if len(values) == 1 && !strings.HasSuffix(key, "[]") {
+		setInputPath(all, inputPath(key), values[0])
+		return
+	}
+	setInputPath(all, inputPath(key), values)
+}
+
+// inputPath splits a bracketed form key like "user[address][city]" into
+// its segments, dropping a trailing "[]"
+func inputPath(key string) []string {
+	key = strings.TrimSuffix(key, "[]")
+	head, rest, ok := strings.Cut(key, "[")
+	if !ok || head == "" || !strings.HasSuffix(rest, "]") {
+		return []string{key}
+	}
+
+	path := []string{head}
+	for _, segment := range strings.Split(strings.TrimSuffix(rest, "]"), "][") {
+		if segment == "" {
+			return []string{key}
+		}
+		path = append(path, segment)
+	}
+	return path
+}
+
+func setInputPath(all map[string]any, path []string, value any) {
+	node := all
+	for _, segment := range path[:len(path)-1] {
+		child, ok := node[segment].(map[string]any)
+		if !ok {
+			child = map[string]any{}
+			node[segment] = child
+		}
+		node = child
+	}
+	node[path[len(path)-1]] = value
+}
@@ -0,0 +1,184 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/api/req"
+)
+
+type signupInput struct {
+	Name  string `json:"name" in:"form=name"`
+	Email string `json:"email" in:"form=email"`
+	Age   int    `json:"age" in:"form=age"`
+}
+
+// formRequest builds a url-encoded POST of values
+func formRequest(target string, values url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, target, strings.NewReader(values.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return r
+}
+
+// multipartFormRequest builds a multipart POST of values
+func multipartFormRequest(t *testing.T, target string, values url.Values) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	for key, vals := range values {
+		for _, v := range vals {
+			if err := w.WriteField(key, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	w.Close()
+
+	r := httptest.NewRequest(http.MethodPost, target, &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+func TestInputOfJSON(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":"john","email":"john@example.com","age":30}`))
+
+	input, err := InputOf[signupInput](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := signupInput{Name: "john", Email: "john@example.com", Age: 30}
+	if *input != want {
+		t.Errorf("InputOf() = %+v, want %+v", *input, want)
+	}
+}
+
+func TestInputOfForm(t *testing.T) {
+	c, _ := newTestContext(formRequest("/", url.Values{
+		"name":  {"jane"},
+		"email": {"jane@example.com"},
+		"age":   {"41"},
+	}))
+
+	input, err := InputOf[signupInput](c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := signupInput{Name: "jane", Email: "jane@example.com", Age: 41}
+	if *input != want {
+		t.Errorf("InputOf() = %+v, want %+v", *input, want)
+	}
+}
+
+func TestInputOfDecodeOptions(t *testing.T) {
+	const body = `{"name":"john","admin":true}`
+
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", body))
+	if _, err := InputOf[signupInput](c); err == nil {
+		t.Error("InputOf() accepted an unknown field by default")
+	}
+
+	c, _ = newTestContext(jsonRequest(http.MethodPost, "/", body))
+	input, err := InputOf[signupInput](c, req.DecodeOptions{AllowUnknownFields: true})
+	if err != nil || input.Name != "john" {
+		t.Errorf("InputOf() with AllowUnknownFields = %+v, %v", input, err)
+	}
+}
+
+func TestInputOfMalformedJSON(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":`))
+
+	input, err := InputOf[signupInput](c)
+	var mr *req.MalformedRequest
+	if input != nil || !errors.As(err, &mr) || mr.Status != http.StatusBadRequest {
+		t.Fatalf("InputOf() = %v, %v, want nil and a 400 MalformedRequest", input, err)
+	}
+}
+
+func TestMustInput(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":"john"}`))
+	if input := MustInput[signupInput](c); input.Name != "john" {
+		t.Errorf("MustInput() = %+v", input)
+	}
+
+	defer func() {
+		err, ok := recover().(error)
+		var mr *req.MalformedRequest
+		if !ok || !errors.As(err, &mr) {
+			t.Errorf("MustInput() panicked with %v, want the MalformedRequest", err)
+		}
+	}()
+	c, _ = newTestContext(jsonRequest(http.MethodPost, "/", `[1, 2]`))
+	MustInput[signupInput](c)
+	t.Error("MustInput() did not panic on a bad body")
+}
+
+func TestAllIsTheSameForJSONAndForm(t *testing.T) {
+	want := map[string]any{
+		"page":   "2",
+		"name":   "john",
+		"age":    "30",
+		"price":  "9.5",
+		"active": "true",
+		"tags":   []string{"a", "b"},
+		"user":   map[string]any{"city": "Dhaka", "zip": "1207"},
+	}
+
+	jsonBody := `{"name":"john","age":30,"price":9.5,"active":true,"tags":["a","b"],"user":{"city":"Dhaka","zip":1207}}`
+	form := url.Values{
+		"name": {"john"}, "age": {"30"}, "price": {"9.5"}, "active": {"true"},
+		"tags[]": {"a", "b"}, "user[city]": {"Dhaka"}, "user[zip]": {"1207"},
+	}
+
+	requests := map[string]*http.Request{
+		"json":       jsonRequest(http.MethodPost, "/?page=2", jsonBody),
+		"urlencoded": formRequest("/?page=2", form),
+		"multipart":  multipartFormRequest(t, "/?page=2", form),
+	}
+	for name, r := range requests {
+		t.Run(name, func(t *testing.T) {
+			c, _ := newTestContext(r)
+			got, err := c.All()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("All() = %#v, want %#v", got, want)
+			}
+		})
+	}
+}
+
+func TestAllJSONValues(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/?name=query", `{"name":"body","big":12345678,"none":null,"items":[{"qty":2},{"qty":3}],"empty":[]}`))
+
+	got, err := c.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"name":  "body",
+		"big":   "12345678",
+		"none":  nil,
+		"items": []any{map[string]any{"qty": "2"}, map[string]any{"qty": "3"}},
+		"empty": []string{},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %#v, want %#v", got, want)
+	}
+}
+
+func TestAllRejectsNonObjectJSON(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", `["a"]`))
+
+	var mr *req.MalformedRequest
+	if _, err := c.All(); !errors.As(err, &mr) || mr.Status != http.StatusBadRequest {
+		t.Errorf("All() error = %v, want a 400 MalformedRequest", err)
+	}
+}
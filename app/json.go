@@ -0,0 +1,138 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/req"
+)
+
+func init() {
+	req.JSONUnmarshalHook = func() func(data []byte, v any) error {
+		if JSONUnmarshal == nil || isFunc(JSONUnmarshal, json.Unmarshal) {
+			// req keeps its strict decoder for the default codec
+			return nil
+		}
+		return JSONUnmarshal
+	}
+	req.MaxBodySize = maxBodySize
+}
+
+// JSONMarshal encodes every JSON payload the framework writes, such as the
+// responses of Context.JSON. The default honors the JSON options from
+// config; replace it to plug in a faster encoder such as goccy/go-json.
+var JSONMarshal func(v any) ([]byte, error) = marshalJSON
+
+// JSONUnmarshal decodes every JSON document the framework reads: request
+// bodies decoded by req.DecodeJSONBody, the input collected by Context.All
+// and the JSON validation rule. It defaults to json.Unmarshal; replace it to
+// plug in a faster decoder. With the default, req.DecodeJSONBody rejects
+// unknown fields unless DecodeOptions.AllowUnknownFields is set; a
+// replacement is responsible for its own unknown-field handling.
+var JSONUnmarshal func(data []byte, v any) error = json.Unmarshal
+
+// isFunc reports whether f and g are the same function
+func isFunc(f, g func(data []byte, v any) error) bool {
+	return reflect.ValueOf(f).Pointer() == reflect.ValueOf(g).Pointer()
+}
+
+// JSONEncoder writes one JSON value per Encode call, see Context.JSONStream
+type JSONEncoder interface {
+	Encode(v any) error
+}
+
+// hookEncoder encodes values with JSONMarshal, newline-terminated like
+// json.Encoder
+type hookEncoder struct {
+	w io.Writer
+}
+
+func (e hookEncoder) Encode(v any) error {
+	data, err := JSONMarshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+// JSONOptions controls the default JSON encoder
+type JSONOptions struct {
+	// EscapeHTML escapes <, > and & inside strings
+	EscapeHTML bool
+
+	// Indent pretty-prints the output with this indentation when non-empty
+	Indent string
+}
+
+// jsonOpts holds the options loaded at boot by loadJSONOptions
+var jsonOpts atomic.Pointer[JSONOptions]
+
+// SetJSONOptions changes the options of the default JSON encoder
+func SetJSONOptions(opts JSONOptions) {
+	jsonOpts.Store(&opts)
+}
+
+// loadJSONOptions caches the JSON options from config so marshalJSON
+// doesn't read config on every call
+func (a *Application) loadJSONOptions() {
+	SetJSONOptions(jsonOptionsFrom(a.config))
+}
+
+// jsonOptions returns the options loaded at boot, or reads them from config
+// if the app hasn't booted
+func jsonOptions() JSONOptions {
+	if opts := jsonOpts.Load(); opts != nil {
+		return *opts
+	}
+	return jsonOptionsFrom(config.GetInstance())
+}
+
+// jsonOptionsFrom reads json.escape_html (default true) and json.pretty
+// from conf, indenting pretty output with json.indent (default two spaces).
+// Without json.pretty the output is compact unless app.env is "local",
+// "dev" or "development".
+func jsonOptionsFrom(conf config.Configuration) JSONOptions {
+	opts := JSONOptions{EscapeHTML: true}
+
+	if escape, ok := conf.Get("json.escape_html").(bool); ok {
+		opts.EscapeHTML = escape
+	}
+
+	pretty, ok := conf.Get("json.pretty").(bool)
+	if !ok {
+		env, _ := conf.Get("app.env").(string)
+		pretty = env == "local" || env == "dev" || env == "development"
+	}
+
+	if pretty {
+		opts.Indent = "  "
+		if indent, ok := conf.Get("json.indent").(string); ok && indent != "" {
+			opts.Indent = indent
+		}
+	}
+
+	return opts
+}
+
+func marshalJSON(v any) ([]byte, error) {
+	opts := jsonOptions()
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(opts.EscapeHTML)
+	if opts.Indent != "" {
+		enc.SetIndent("", opts.Indent)
+	}
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	// Encode terminates the value with a newline, which json.Marshal doesn't
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
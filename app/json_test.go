@@ -0,0 +1,186 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/req"
+)
+
+func TestJSONUnmarshalHookIsUsedEverywhere(t *testing.T) {
+	calls := 0
+	JSONUnmarshal = func(data []byte, v any) error {
+		calls++
+		return json.Unmarshal(data, v)
+	}
+	defer func() { JSONUnmarshal = json.Unmarshal }()
+
+	c, w := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":"john","unknown":1}`))
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	if err := req.DecodeJSONBody(w, c.Request(), &body); err != nil {
+		t.Fatalf("DecodeJSONBody: %v", err)
+	}
+	if body.Name != "john" {
+		t.Fatalf("decoded name = %q, want john", body.Name)
+	}
+
+	if _, err := c.All(); err != nil {
+		t.Fatalf("All: %v", err)
+	}
+
+	vee := NewValidator(nil)
+	vee.Field("payload", `{"a":1}`).JSON()
+	if err := vee.Validate(); err != nil {
+		t.Fatalf("JSON rule: %v", err)
+	}
+
+	if calls != 3 {
+		t.Fatalf("hook called %d times, want 3", calls)
+	}
+}
+
+func TestJSONUnmarshalDefaultsToStdlib(t *testing.T) {
+	var v struct {
+		A int `json:"a"`
+	}
+	if err := JSONUnmarshal([]byte(`{"a":1}`), &v); err != nil || v.A != 1 {
+		t.Fatalf("JSONUnmarshal() = %v with %+v, want it usable directly", err, v)
+	}
+	if req.JSONUnmarshalHook() != nil {
+		t.Error("req uses the default codec as a hook, want its strict decoder")
+	}
+
+	hook := func(data []byte, v any) error { return nil }
+	JSONUnmarshal = hook
+	defer func() { JSONUnmarshal = json.Unmarshal }()
+	if req.JSONUnmarshalHook() == nil {
+		t.Error("req ignores a replaced JSONUnmarshal")
+	}
+}
+
+func TestDecodeJSONBodyIsStrictWithoutHook(t *testing.T) {
+	c, w := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":"john","unknown":1}`))
+
+	var body struct {
+		Name string `json:"name"`
+	}
+	err := req.DecodeJSONBody(w, c.Request(), &body)
+	var mr *req.MalformedRequest
+	if !errors.As(err, &mr) || mr.Status != http.StatusBadRequest {
+		t.Fatalf("got %v, want a 400 for the unknown field", err)
+	}
+}
+
+func TestJSONMarshalHook(t *testing.T) {
+	JSONMarshal = func(v any) ([]byte, error) {
+		return []byte(`{"hooked":true}`), nil
+	}
+	defer func() { JSONMarshal = marshalJSON }()
+
+	c, w := newTestContext(jsonRequest(http.MethodGet, "/", ""))
+	if err := c.JSON(M{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != `{"hooked":true}` {
+		t.Fatalf("JSON body = %q", got)
+	}
+
+	c, w = newTestContext(jsonRequest(http.MethodGet, "/", ""))
+	err := c.JSONStream(func(enc JSONEncoder) error {
+		return enc.Encode(M{"a": 1})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Body.String(); got != "{\"hooked\":true}\n" {
+		t.Fatalf("JSONStream body = %q", got)
+	}
+}
+
+// jsonBody boots an app with conf and returns the body of a JSON response
+func jsonBody(t *testing.T, conf config.M) string {
+	t.Helper()
+	defer jsonOpts.Store(nil)
+
+	handler, shutDown := TestHandler(WithConfig(conf), WithRoutes(func(r Router) {
+		r.Get("/json", func(c *Context) error {
+			return c.JSON(M{"html": "<b>&</b>"})
+		})
+	}))
+	defer shutDown()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/json", nil))
+	return w.Body.String()
+}
+
+func TestJSONPrettyInDevelopment(t *testing.T) {
+	got := jsonBody(t, config.M{"app": config.M{"env": "local"}})
+	want := "{\n  \"html\": \"\\u003cb\\u003e\\u0026\\u003c/b\\u003e\"\n}"
+	if got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+}
+
+func TestJSONCompactByDefault(t *testing.T) {
+	want := `{"html":"\u003cb\u003e\u0026\u003c/b\u003e"}`
+	for _, env := range []any{"production", "staging", "testing", nil} {
+		if got := jsonBody(t, config.M{"app": config.M{"env": env}}); got != want {
+			t.Errorf("app.env %v: body = %q, want %q", env, got, want)
+		}
+	}
+}
+
+func TestJSONPrettyWhenConfigured(t *testing.T) {
+	for _, env := range []string{"dev", "development"} {
+		if got := jsonBody(t, config.M{"app": config.M{"env": env}}); got[:2] != "{\n" {
+			t.Errorf("app.env %s: body = %q, want it pretty-printed", env, got)
+		}
+	}
+
+	got := jsonBody(t, config.M{"app": config.M{"env": "production"}, "json": config.M{"pretty": true}})
+	if got[:2] != "{\n" {
+		t.Errorf("json.pretty: body = %q, want it pretty-printed", got)
+	}
+}
+
+func TestJSONEscapingAndIndentFromConfig(t *testing.T) {
+	got := jsonBody(t, config.M{
+		"app":  config.M{"env": "production"},
+		"json": config.M{"escape_html": false, "pretty": true, "indent": "\t"},
+	})
+	want := "{\n\t\"html\": \"<b>&</b>\"\n}"
+	if got != want {
+		t.Fatalf("body = %q, want %q", got, want)
+	}
+}
+
+func TestJSONOptionsAreCachedAtBoot(t *testing.T) {
+	defer jsonOpts.Store(nil)
+	defer config.Set("json.pretty", nil)
+
+	config.Set("json.pretty", false)
+	(&Application{config: config.GetInstance()}).loadJSONOptions()
+	config.Set("json.pretty", true)
+
+	data, err := marshalJSON(M{"a": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"a":1}` {
+		t.Fatalf("marshalJSON() = %q, want the compact output loaded at boot", data)
+	}
+
+	SetJSONOptions(JSONOptions{Indent: " "})
+	data, _ = marshalJSON(M{"a": "<"})
+	if string(data) != "{\n \"a\": \"<\"\n}" {
+		t.Fatalf("marshalJSON() after SetJSONOptions = %q", data)
+	}
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocalsDoNotLeakAcrossRequests(t *testing.T) {
+	var seen []any
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/locals", func(c *Context) error {
+			seen = append(seen, c.Locals("user"))
+			c.SetLocal("user", c.Query("name"))
+			return c.Text([]byte(c.Locals("user").(string)))
+		})
+	}))
+	defer shutDown()
+
+	for _, name := range []string{"alice", "bob"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/locals?name="+name, nil))
+		if w.Body.String() != name {
+			t.Fatalf("response = %q, want %q", w.Body.String(), name)
+		}
+	}
+
+	for i, v := range seen {
+		if v != nil {
+			t.Errorf("request %d started with Locals(user) = %v, want nil", i, v)
+		}
+	}
+}
+
+func TestLocalsAreNotInRequestContext(t *testing.T) {
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	before := c.Request().Context()
+
+	c.SetLocal("user", "alice")
+
+	if c.Request().Context() != before {
+		t.Error("SetLocal replaced the request context")
+	}
+	if v := c.Request().Context().Value("user"); v != nil {
+		t.Errorf("request context has user = %v", v)
+	}
+	if v := c.Get("user"); v != nil {
+		t.Errorf("Get(user) = %v, want nil", v)
+	}
+	if v := c.Locals("user"); v != "alice" {
+		t.Errorf("Locals(user) = %v, want alice", v)
+	}
+}
+
+func TestSetDoesNotWriteLocals(t *testing.T) {
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	c.Set("user", "alice")
+
+	if v := c.Locals("user"); v != nil {
+		t.Errorf("Locals(user) = %v after Set, want nil", v)
+	}
+	if v := c.Locals("missing"); v != nil {
+		t.Errorf("Locals(missing) = %v, want nil", v)
+	}
+}
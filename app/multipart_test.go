@@ -0,0 +1,127 @@
+package app
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+// multipartRequest builds a multipart request with a "title" field and a
+// "doc" file of the given size
+func multipartRequest(t *testing.T, fileSize int) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("title", "report"); err != nil {
+		t.Fatal(err)
+	}
+	part, err := w.CreateFormFile("doc", "report.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bytes.Repeat([]byte("x"), fileSize))
+	w.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/upload", &body)
+	r.Header.Set("Content-Type", w.FormDataContentType())
+	return r
+}
+
+// spilled reports whether the uploaded file was written to a temporary file
+// instead of being kept in memory. Temporary files are removed after the test.
+func spilled(t *testing.T, c *Context) bool {
+	t.Helper()
+	file, _, err := c.FormFile("doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	t.Cleanup(func() { c.Request().MultipartForm.RemoveAll() })
+	_, onDisk := file.(*os.File)
+	return onDisk
+}
+
+func TestMultipartMaxMemoryFromConfig(t *testing.T) {
+	config.Set("request.multipart_max_memory", 1024)
+	defer config.Set("request.multipart_max_memory", nil)
+
+	tests := []struct {
+		name     string
+		fileSize int
+		spill    bool
+	}{
+		{"under the threshold", 512, false},
+		{"over the threshold", 4096, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestContext(multipartRequest(t, tt.fileSize))
+
+			if got := spilled(t, c); got != tt.spill {
+				t.Errorf("%d byte file spilled = %v, want %v", tt.fileSize, got, tt.spill)
+			}
+		})
+	}
+}
+
+func TestMultipartMaxMemoryDefault(t *testing.T) {
+	if got := multipartMaxMemory(); got != defaultMultipartMaxMemory {
+		t.Fatalf("multipartMaxMemory() = %d, want %d", got, defaultMultipartMaxMemory)
+	}
+
+	c, _ := newTestContext(multipartRequest(t, 64<<10))
+	if spilled(t, c) {
+		t.Error("64KB file spilled to disk under the 32MB default")
+	}
+}
+
+func TestFormUsesMultipartMaxMemory(t *testing.T) {
+	config.Set("request.multipart_max_memory", 1024)
+	defer config.Set("request.multipart_max_memory", nil)
+
+	c, _ := newTestContext(multipartRequest(t, 4096))
+	form, err := c.Form()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if form["title"][0] != "report" {
+		t.Errorf("title = %v", form["title"])
+	}
+	if !spilled(t, c) {
+		t.Error("Form() parsed the upload without the configured threshold")
+	}
+	if !c.HasFile("doc") {
+		t.Error("HasFile(doc) = false")
+	}
+}
+
+func TestSetMultipartMaxMemory(t *testing.T) {
+	config.Set("request.multipart_max_memory", 1<<20)
+	defer config.Set("request.multipart_max_memory", nil)
+	defer SetMultipartMaxMemory(0)
+
+	SetMultipartMaxMemory(1024)
+	if got := multipartMaxMemory(); got != 1024 {
+		t.Fatalf("multipartMaxMemory() = %d, want the 1024 set over config", got)
+	}
+	c, _ := newTestContext(multipartRequest(t, 4096))
+	if !spilled(t, c) {
+		t.Error("4KB file kept in memory with a 1KB threshold")
+	}
+
+	SetMultipartMaxMemory(0)
+	if got := multipartMaxMemory(); got != 1<<20 {
+		t.Fatalf("multipartMaxMemory() after reset = %d, want the configured 1MB", got)
+	}
+	c, _ = newTestContext(multipartRequest(t, 4096))
+	if spilled(t, c) {
+		t.Error("4KB file spilled with the configured 1MB threshold")
+	}
+}
@@ -0,0 +1,318 @@
+package app
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Summary sets the route's summary in the generated OpenAPI document
+func (r *Route) Summary(summary string) *Route {
+	r.summary = summary
+	return r
+}
+
+// WithInput documents the route's input struct in the generated OpenAPI
+// document. Fields tagged `in:"query=..."`, `in:"path=..."` or
+// `in:"header=..."` become parameters, `in:"form=..."` fields a form body
+// and json-tagged fields a JSON body. A `doc` tag adds a description and a
+// required directive or validate rule marks the field required.
+func (r *Route) WithInput(input any) *Route {
+	r.input = input
+	return r
+}
+
+// WithOutput documents the body of the route's 200 response, using the
+// json tags of output's type
+func (r *Route) WithOutput(output any) *Route {
+	r.output = output
+	return r
+}
+
+var pathParamRegex = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+// OpenAPISpec builds an OpenAPI 3 document from the routes registered with
+// WithRoutes. The routes are collected on a scratch router, so this can be
+// called before or after Run. Title and version come from app.name and
+// app.version.
+func OpenAPISpec() M {
+	a := Get().(*Application)
+
+	router := newRouter()
+	router.basePrefix = a.router.basePrefix
+	for _, cb := range a.routeCallbacks {
+		cb(router)
+	}
+
+	gen := &schemaGen{schemas: M{}, names: map[reflect.Type]string{}}
+	paths := M{}
+	for _, route := range router.routes {
+		if route.Method == http.MethodConnect || route.Method == http.MethodTrace {
+			continue
+		}
+
+		p := pathParamRegex.ReplaceAllString(route.Path, "{$1}")
+		item, ok := paths[p].(M)
+		if !ok {
+			item = M{}
+			paths[p] = item
+		}
+		item[strings.ToLower(route.Method)] = gen.operation(route)
+	}
+
+	spec := M{
+		"openapi": "3.0.3",
+		"info": M{
+			"title":   a.config.Get("app.name", "Lemmego"),
+			"version": a.config.Get("app.version", "1.0.0"),
+		},
+		"paths": paths,
+	}
+	if len(gen.schemas) > 0 {
+		spec["components"] = M{"schemas": gen.schemas}
+	}
+	return spec
+}
+
+// schemaGen converts Go types to OpenAPI schemas. Named struct types are
+// emitted once into components/schemas and referenced with $ref, which
+// also keeps self-referential types from recursing forever.
+type schemaGen struct {
+	schemas M
+	names   map[reflect.Type]string
+}
+
+func (g *schemaGen) operation(route *Route) M {
+	ok := M{"description": "OK"}
+	if t := reflect.TypeOf(route.output); t != nil {
+		ok["content"] = M{"application/json": M{"schema": g.schema(t)}}
+	}
+
+	op := M{
+		"responses": M{"200": ok},
+	}
+	if route.summary != "" {
+		op["summary"] = route.summary
+	}
+
+	params := []M{}
+	documented := map[string]bool{}
+	bodyProps, bodyRequired := M{}, []string{}
+	formProps, formRequired := M{}, []string{}
+
+	if t := structType(route.input); t != nil {
+		for _, field := range documentedFields(t) {
+			schema := describe(g.schema(field.Type), field.Tag.Get("doc"))
+			required := strings.Contains(field.Tag.Get("in"), "required") ||
+				slices.Contains(strings.Split(field.Tag.Get("validate"), ","), "required")
+
+			if location, name := httpinSource(field.Tag.Get("in")); location != "" {
+				if location == "form" {
+					formProps[name] = schema
+					if required {
+						formRequired = append(formRequired, name)
+					}
+					continue
+				}
+
+				param := M{"name": name, "in": location, "schema": schema}
+				if required || location == "path" {
+					param["required"] = true
+				}
+				params = append(params, param)
+				documented[location+":"+name] = true
+				continue
+			}
+
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			bodyProps[name] = schema
+			if required {
+				bodyRequired = append(bodyRequired, name)
+			}
+		}
+	}
+
+	for _, match := range pathParamRegex.FindAllStringSubmatch(route.Path, -1) {
+		if !documented["path:"+match[1]] {
+			params = append(params, M{"name": match[1], "in": "path", "required": true, "schema": M{"type": "string"}})
+		}
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	content := M{}
+	if len(bodyProps) > 0 {
+		content["application/json"] = M{"schema": objectSchema(bodyProps, bodyRequired)}
+	}
+	if len(formProps) > 0 {
+		content["multipart/form-data"] = M{"schema": objectSchema(formProps, formRequired)}
+	}
+	if len(content) > 0 {
+		op["requestBody"] = M{"content": content}
+	}
+
+	return op
+}
+
+// httpinSource returns the first supported location and key of an httpin
+// `in` tag, e.g. "query", "page" for `in:"query=page,p;required"`
+func httpinSource(tag string) (string, string) {
+	for _, directive := range strings.Split(tag, ";") {
+		name, args, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		key, _, _ := strings.Cut(args, ",")
+		switch name {
+		case "query", "path", "header", "form":
+			if key != "" {
+				return name, key
+			}
+		}
+	}
+	return "", ""
+}
+
+func structType(v any) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return t
+}
+
+func objectSchema(props M, required []string) M {
+	schema := M{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	baseInputType = reflect.TypeOf(BaseInput{})
+)
+
+// documentedFields returns the exported fields of struct type t, with the
+// fields of embedded structs promoted into it as encoding/json and httpin
+// do. BaseInput, which carries no input, is left out.
+func documentedFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			ft := field.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft == baseInputType {
+				continue
+			}
+			if ft.Kind() == reflect.Struct && field.Tag.Get("json") == "" && field.Tag.Get("in") == "" {
+				fields = append(fields, documentedFields(ft)...)
+				continue
+			}
+		}
+		if field.IsExported() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// schema maps a Go type to an OpenAPI schema
+func (g *schemaGen) schema(t reflect.Type) M {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return M{"type": "string", "format": "date-time"}
+	}
+	if t.Name() == "FileInput" || strings.HasSuffix(t.String(), "httpin.File") {
+		return M{"type": "string", "format": "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return M{"type": "string"}
+	case reflect.Bool:
+		return M{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return M{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return M{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return M{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return M{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.objectSchema(t)
+		}
+		name, seen := g.names[t]
+		if !seen {
+			name = g.schemaName(t)
+			g.names[t] = name
+			g.schemas[name] = g.objectSchema(t)
+		}
+		return M{"$ref": "#/components/schemas/" + name}
+	}
+
+	return M{}
+}
+
+// schemaName returns the component name for t: its type name, qualified
+// with its package if another type of the same name was seen first
+func (g *schemaGen) schemaName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := g.schemas[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	pkg = pkg[strings.LastIndex(pkg, "/")+1:]
+	return pkg + "." + name
+}
+
+func (g *schemaGen) objectSchema(t reflect.Type) M {
+	props := M{}
+	for _, field := range documentedFields(t) {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		props[name] = describe(g.schema(field.Type), field.Tag.Get("doc"))
+	}
+	return objectSchema(props, nil)
+}
+
+// describe adds a doc tag's description to schema. A $ref can't carry
+// siblings in OpenAPI 3.0, so it is wrapped in allOf first.
+func describe(schema M, doc string) M {
+	if doc == "" {
+		return schema
+	}
+	if _, isRef := schema["$ref"]; isRef {
+		schema = M{"allOf": []M{schema}}
+	}
+	schema["description"] = doc
+	return schema
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	openAPIOutput string
+	openAPIFormat string
+)
+
+var openAPICmd = &cobra.Command{
+	Use:   "openapi",
+	Short: "Write the OpenAPI document of the registered routes",
+	Long: `Write the OpenAPI document built by app.OpenAPISpec to stdout, or to
+the file given with --output. The format is json unless --format says
+otherwise or the output file ends in .yaml or .yml.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := openAPIFormat
+		if format == "" {
+			format = "json"
+			if ext := strings.ToLower(filepath.Ext(openAPIOutput)); ext == ".yaml" || ext == ".yml" {
+				format = "yaml"
+			}
+		}
+
+		data, err := encodeOpenAPISpec(OpenAPISpec(), format)
+		if err != nil {
+			return err
+		}
+
+		if openAPIOutput == "" {
+			_, err = cmd.OutOrStdout().Write(data)
+			return err
+		}
+		return os.WriteFile(openAPIOutput, data, 0644)
+	},
+}
+
+func init() {
+	openAPICmd.Flags().StringVarP(&openAPIOutput, "output", "o", "", "File to write the document to instead of stdout")
+	openAPICmd.Flags().StringVarP(&openAPIFormat, "format", "f", "", "Output format, json or yaml")
+}
+
+// encodeOpenAPISpec encodes spec as indented JSON or as YAML. The YAML is
+// converted from the JSON, so both use the json tags and key names.
+func encodeOpenAPISpec(spec M, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(format) {
+	case "json":
+		return append(data, '\n'), nil
+	case "yaml", "yml":
+		var doc any
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	default:
+		return nil, fmt.Errorf("openapi: unsupported format %q, want json or yaml", format)
+	}
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runOpenAPI runs the openapi command with args against routes and returns
+// what it wrote to stdout
+func runOpenAPI(t *testing.T, routes RouteCallback, args ...string) (string, error) {
+	t.Helper()
+	a := Get().(*Application)
+	saved := a.routeCallbacks
+	a.routeCallbacks = []RouteCallback{routes}
+	defer func() { a.routeCallbacks = saved }()
+	defer func() { openAPIOutput, openAPIFormat = "", "" }()
+
+	var out, stderr bytes.Buffer
+	openAPICmd.SetOut(&out)
+	openAPICmd.SetErr(&stderr)
+	openAPICmd.SetArgs(args)
+	defer func() {
+		openAPICmd.SetOut(nil)
+		openAPICmd.SetErr(nil)
+	}()
+
+	err := openAPICmd.Execute()
+	return out.String(), err
+}
+
+func openAPIRoutes(r Router) {
+	r.Get("/users/{id}", noop).Summary("Show a user")
+	r.Post("/users", noop).WithInput(createUserInput{})
+}
+
+// checkSpec decodes a JSON or YAML document and checks it describes
+// openAPIRoutes
+func checkSpec(t *testing.T, data []byte, unmarshal func([]byte, any) error) {
+	t.Helper()
+	spec := map[string]any{}
+	if err := unmarshal(data, &spec); err != nil {
+		t.Fatalf("decoding %q: %v", data, err)
+	}
+	if got := lookup(t, spec, "paths", "/users/{id}", "get", "summary"); got != "Show a user" {
+		t.Errorf("summary = %v, want Show a user", got)
+	}
+	required := lookup(t, spec, "paths", "/users", "post", "requestBody", "content", "application/json", "schema", "required")
+	if !reflect.DeepEqual(required, []any{"name", "email"}) {
+		t.Errorf("required = %v, want [name email]", required)
+	}
+}
+
+func TestOpenAPICommandWritesJSON(t *testing.T) {
+	out, err := runOpenAPI(t, openAPIRoutes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSpec(t, []byte(out), json.Unmarshal)
+}
+
+func TestOpenAPICommandWritesYAML(t *testing.T) {
+	out, err := runOpenAPI(t, openAPIRoutes, "--format", "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "info:\n  title: ") || strings.HasPrefix(out, "{") {
+		t.Errorf("output = %q, want YAML", out)
+	}
+	checkSpec(t, []byte(out), yaml.Unmarshal)
+}
+
+func TestOpenAPICommandWritesFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		file      string
+		args      []string
+		unmarshal func([]byte, any) error
+	}{
+		{"openapi.json", nil, json.Unmarshal},
+		{"openapi.yaml", nil, yaml.Unmarshal},
+		{"openapi.yml", nil, yaml.Unmarshal},
+		{"spec.txt", []string{"-f", "yaml"}, yaml.Unmarshal},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		out, err := runOpenAPI(t, openAPIRoutes, append([]string{"-o", path}, tt.args...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != "" {
+			t.Errorf("%s: wrote %q to stdout, want only the file", tt.file, out)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSpec(t, data, tt.unmarshal)
+	}
+}
+
+func TestOpenAPICommandRejectsUnknownFormat(t *testing.T) {
+	if _, err := runOpenAPI(t, openAPIRoutes, "--format", "xml"); err == nil {
+		t.Error("openapi --format xml returned no error")
+	}
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// specFor builds the OpenAPI document for routes alone, as JSON-decoded maps
+func specFor(t *testing.T, routes RouteCallback) map[string]any {
+	t.Helper()
+	a := Get().(*Application)
+	saved := a.routeCallbacks
+	a.routeCallbacks = []RouteCallback{routes}
+	defer func() { a.routeCallbacks = saved }()
+
+	data, err := json.Marshal(OpenAPISpec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec := map[string]any{}
+	if err := json.Unmarshal(data, &spec); err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+// lookup walks spec along keys
+func lookup(t *testing.T, spec map[string]any, keys ...string) any {
+	t.Helper()
+	var v any = spec
+	for _, key := range keys {
+		m, ok := v.(map[string]any)
+		if !ok {
+			t.Fatalf("%v: %q is not an object", keys, key)
+		}
+		if v, ok = m[key]; !ok {
+			t.Fatalf("%v: missing %q", keys, key)
+		}
+	}
+	return v
+}
+
+func noop(c *Context) error { return nil }
+
+type pageQuery struct {
+	Page    int    `in:"query=page" doc:"Page number"`
+	PerPage int    `in:"query=per_page;required"`
+	Token   string `in:"header=X-Token"`
+}
+
+type listUsersInput struct {
+	pageQuery
+	*BaseInput
+	Team string `in:"path=team"`
+}
+
+type userOut struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name" doc:"Display name"`
+	Manager   *userOut  `json:"manager,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	secret    string
+}
+
+type createUserInput struct {
+	Name  string   `json:"name" validate:"required"`
+	Email string   `json:"email" validate:"required,email"`
+	Tags  []string `json:"tags"`
+	Skip  string   `json:"-"`
+}
+
+type uploadInput struct {
+	Title string     `in:"form=title;required"`
+	File  *FileInput `in:"form=file"`
+}
+
+type treeNode struct {
+	Name     string     `json:"name"`
+	Children []treeNode `json:"children"`
+}
+
+func TestOpenAPISpecPathsAndParameters(t *testing.T) {
+	spec := specFor(t, func(r Router) {
+		r.Get("/teams/{team}/users", noop).Summary("List users").WithInput(&listUsersInput{}).WithOutput([]userOut{})
+		r.Get("/files/{path...}", noop)
+	})
+
+	if got := lookup(t, spec, "openapi"); got != "3.0.3" {
+		t.Errorf("openapi = %v", got)
+	}
+	if got := lookup(t, spec, "paths", "/teams/{team}/users", "get", "summary"); got != "List users" {
+		t.Errorf("summary = %v", got)
+	}
+
+	params := lookup(t, spec, "paths", "/teams/{team}/users", "get", "parameters").([]any)
+	byName := map[string]map[string]any{}
+	for _, p := range params {
+		p := p.(map[string]any)
+		byName[p["name"].(string)] = p
+	}
+	if len(byName) != 4 {
+		t.Fatalf("parameters = %v, want page, per_page, X-Token and team", params)
+	}
+	if p := byName["page"]; p["in"] != "query" || p["required"] != nil || lookup(t, p, "schema", "description") != "Page number" {
+		t.Errorf("page = %v", p)
+	}
+	if p := byName["per_page"]; p["required"] != true || lookup(t, p, "schema", "type") != "integer" {
+		t.Errorf("per_page = %v", p)
+	}
+	if p := byName["X-Token"]; p["in"] != "header" {
+		t.Errorf("X-Token = %v", p)
+	}
+	if p := byName["team"]; p["in"] != "path" || p["required"] != true {
+		t.Errorf("team = %v", p)
+	}
+
+	wildcard := lookup(t, spec, "paths", "/files/{path}", "get", "parameters").([]any)
+	if len(wildcard) != 1 || wildcard[0].(map[string]any)["name"] != "path" {
+		t.Errorf("wildcard parameters = %v", wildcard)
+	}
+}
+
+func TestOpenAPISpecBodies(t *testing.T) {
+	spec := specFor(t, func(r Router) {
+		r.Post("/users", noop).WithInput(&createUserInput{})
+		r.Post("/uploads", noop).WithInput(uploadInput{})
+	})
+
+	body := lookup(t, spec, "paths", "/users", "post", "requestBody", "content", "application/json", "schema").(map[string]any)
+	props := body["properties"].(map[string]any)
+	if len(props) != 3 || lookup(t, body, "properties", "tags", "items", "type") != "string" {
+		t.Errorf("JSON body properties = %v", props)
+	}
+	if !reflect.DeepEqual(body["required"], []any{"name", "email"}) {
+		t.Errorf("required = %v", body["required"])
+	}
+
+	form := lookup(t, spec, "paths", "/uploads", "post", "requestBody", "content", "multipart/form-data", "schema").(map[string]any)
+	if lookup(t, form, "properties", "file", "format") != "binary" {
+		t.Errorf("file schema = %v", lookup(t, form, "properties", "file"))
+	}
+	if !reflect.DeepEqual(form["required"], []any{"title"}) {
+		t.Errorf("form required = %v", form["required"])
+	}
+}
+
+func TestOpenAPISpecOutputAndComponents(t *testing.T) {
+	spec := specFor(t, func(r Router) {
+		r.Get("/users/{id}", noop).WithOutput(&userOut{})
+	})
+
+	ref := lookup(t, spec, "paths", "/users/{id}", "get", "responses", "200", "content", "application/json", "schema", "$ref")
+	if ref != "#/components/schemas/userOut" {
+		t.Fatalf("response schema $ref = %v", ref)
+	}
+
+	user := lookup(t, spec, "components", "schemas", "userOut").(map[string]any)
+	props := user["properties"].(map[string]any)
+	if len(props) != 4 {
+		t.Errorf("userOut properties = %v, want id, name, manager and created_at", props)
+	}
+	if lookup(t, user, "properties", "manager", "$ref") != "#/components/schemas/userOut" {
+		t.Errorf("manager = %v, want a $ref to userOut", props["manager"])
+	}
+	if lookup(t, user, "properties", "created_at", "format") != "date-time" {
+		t.Errorf("created_at = %v", props["created_at"])
+	}
+	if lookup(t, user, "properties", "name", "description") != "Display name" {
+		t.Errorf("name = %v", props["name"])
+	}
+}
+
+func TestOpenAPISpecSelfReferentialInput(t *testing.T) {
+	type treeInput struct {
+		Root treeNode `json:"root" doc:"Tree root"`
+	}
+
+	spec := specFor(t, func(r Router) {
+		r.Post("/trees", noop).WithInput(&treeInput{}).WithOutput(treeNode{})
+	})
+
+	root := lookup(t, spec, "paths", "/trees", "post", "requestBody", "content", "application/json", "schema", "properties", "root").(map[string]any)
+	if root["description"] != "Tree root" || lookup(t, root, "allOf").([]any)[0].(map[string]any)["$ref"] != "#/components/schemas/treeNode" {
+		t.Errorf("root = %v, want a described $ref to treeNode", root)
+	}
+	if lookup(t, spec, "components", "schemas", "treeNode", "properties", "children", "items", "$ref") != "#/components/schemas/treeNode" {
+		t.Errorf("treeNode = %v", lookup(t, spec, "components", "schemas", "treeNode"))
+	}
+}
+
+func TestOpenAPISpecPlainRoute(t *testing.T) {
+	spec := specFor(t, func(r Router) {
+		r.Get("/health", noop)
+	})
+
+	if got := lookup(t, spec, "paths", "/health", "get", "responses", "200", "description"); got != "OK" {
+		t.Errorf("200 description = %v", got)
+	}
+	if _, ok := spec["components"]; ok {
+		t.Errorf("components = %v for a spec without structs", spec["components"])
+	}
+}
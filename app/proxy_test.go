@@ -0,0 +1,90 @@
+package app
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+// proxiedRequest builds a request for http://example.com/orders?page=2
+// arriving from peer with the given forwarding headers
+func proxiedRequest(peer string, headers map[string]string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://example.com/orders?page=2", nil)
+	r.RemoteAddr = peer + ":41000"
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	return r
+}
+
+func TestSchemeHostAndFullURLBehindProxy(t *testing.T) {
+	config.Set("app.trusted_proxies", []string{"10.0.0.1", "192.168.0.0/16"})
+	defer config.Set("app.trusted_proxies", nil)
+
+	forwarded := map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "shop.example.org",
+	}
+
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		scheme  string
+		host    string
+	}{
+		{"trusted ip", "10.0.0.1", forwarded, "https", "shop.example.org"},
+		{"trusted cidr", "192.168.4.20", forwarded, "https", "shop.example.org"},
+		{"untrusted peer", "203.0.113.9", forwarded, "http", "example.com"},
+		{"trusted without headers", "10.0.0.1", nil, "http", "example.com"},
+		{"first of several hops", "10.0.0.1", map[string]string{
+			"X-Forwarded-Proto": "https, http",
+			"X-Forwarded-Host":  "shop.example.org, internal.lan",
+		}, "https", "shop.example.org"},
+		{"unknown proto ignored", "10.0.0.1", map[string]string{"X-Forwarded-Proto": "gopher"}, "http", "example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestContext(proxiedRequest(tt.peer, tt.headers))
+			if got := c.Scheme(); got != tt.scheme {
+				t.Errorf("Scheme() = %q, want %q", got, tt.scheme)
+			}
+			if got := c.Host(); got != tt.host {
+				t.Errorf("Host() = %q, want %q", got, tt.host)
+			}
+			want := tt.scheme + "://" + tt.host + "/orders?page=2"
+			if got := c.FullURL(); got != want {
+				t.Errorf("FullURL() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestSchemeWithoutTrustedProxies(t *testing.T) {
+	r := proxiedRequest("10.0.0.1", map[string]string{
+		"X-Forwarded-Proto": "https",
+		"X-Forwarded-Host":  "evil.example",
+	})
+	c, _ := newTestContext(r)
+
+	if got := c.FullURL(); got != "http://example.com/orders?page=2" {
+		t.Errorf("FullURL() = %q, forwarding headers honored with no trusted proxies", got)
+	}
+}
+
+func TestSchemeOverTLS(t *testing.T) {
+	r := proxiedRequest("203.0.113.9", map[string]string{"X-Forwarded-Proto": "http"})
+	r.TLS = &tls.ConnectionState{}
+	c, _ := newTestContext(r)
+
+	if got := c.Scheme(); got != "https" {
+		t.Errorf("Scheme() = %q over TLS, want https", got)
+	}
+	if got := c.FullURL(); got != "https://example.com/orders?page=2" {
+		t.Errorf("FullURL() = %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+func TestSafeRedirect(t *testing.T) {
+	config.Set("app.redirect_hosts", []string{"accounts.example.org"})
+	defer config.Set("app.redirect_hosts", nil)
+
+	tests := map[string]string{
+		"/dashboard":                              "/dashboard",
+		"/search?q=a":                             "/search?q=a",
+		"https://example.com/profile":             "https://example.com/profile",
+		"http://EXAMPLE.com/":                     "http://EXAMPLE.com/",
+		"https://accounts.example.org/login":      "https://accounts.example.org/login",
+		"https://accounts.example.org:8443/login": "https://accounts.example.org:8443/login",
+		"https://evil.com/":                       "/home",
+		"//evil.com/":                             "/home",
+		"/\\evil.com":                             "/home",
+		"https://example.com.evil.com/":           "/home",
+		"javascript:alert(1)":                     "/home",
+		"ftp://example.com/":                      "/home",
+		"":                                        "/home",
+	}
+
+	for target, want := range tests {
+		c, w := newTestContext(httptest.NewRequest(http.MethodPost, "http://example.com/form", nil))
+		if err := c.SafeRedirect(target, "/home"); err != nil {
+			t.Fatal(err)
+		}
+		if got := w.Header().Get("Location"); got != want || w.Code != http.StatusFound {
+			t.Errorf("SafeRedirect(%q) = %d %q, want 302 %q", target, w.Code, got, want)
+		}
+	}
+}
+
+func TestBackSameOriginReferer(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/form", nil)
+	r.Header.Set("Referer", "http://example.com/form?step=2")
+	c, w := newTestContext(r)
+
+	if err := c.Back(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.Header().Get("Location"); got != "http://example.com/form?step=2" {
+		t.Fatalf("Location = %q, want the referer", got)
+	}
+}
+
+func TestBackExternalRefererFallsBack(t *testing.T) {
+	for _, fallback := range [][]string{nil, {"/account"}} {
+		r := httptest.NewRequest(http.MethodPost, "http://example.com/form", nil)
+		r.Header.Set("Referer", "https://evil.com/phish")
+		c, w := newTestContext(r)
+
+		if err := c.Back(fallback...); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "/"
+		if fallback != nil {
+			want = fallback[0]
+		}
+		if got := w.Header().Get("Location"); got != want {
+			t.Errorf("Back(%v) Location = %q, want %q", fallback, got, want)
+		}
+	}
+}
+
+func TestBackFallback(t *testing.T) {
+	tests := []struct {
+		name     string
+		referer  string
+		fallback []string
+		want     string
+	}{
+		{"no referer", "", nil, "/"},
+		{"no referer with fallback", "", []string{"/dashboard"}, "/dashboard"},
+		{"no referer with empty fallback", "", []string{""}, "/"},
+		{"relative referer", "/form?step=1", []string{"/dashboard"}, "/form?step=1"},
+		{"same host referer ignores fallback", "http://example.com/posts/1", []string{"/dashboard"}, "http://example.com/posts/1"},
+		{"protocol-relative referer", "//evil.com/", []string{"/dashboard"}, "/dashboard"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "http://example.com/form", nil)
+			if tt.referer != "" {
+				r.Header.Set("Referer", tt.referer)
+			}
+			c, w := newTestContext(r)
+
+			if err := c.Back(tt.fallback...); err != nil {
+				t.Fatal(err)
+			}
+			if got := w.Header().Get("Location"); got != tt.want || w.Code != http.StatusFound {
+				t.Errorf("Back(%v) = %d %q, want 302 %q", tt.fallback, w.Code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackKeepsStatus(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodPost, "http://example.com/form", nil))
+
+	if err := c.Status(http.StatusSeeOther).Back("/done"); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/done" {
+		t.Errorf("Back() = %d %q, want 303 /done", w.Code, w.Header().Get("Location"))
+	}
+}
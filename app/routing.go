@@ -2,10 +2,12 @@ package app
 
 import (
 	"fmt"
+	"io/fs"
 	"log/slog"
 	"net/http"
 	"path"
 	"slices"
+	"strings"
 
 	"github.com/ggicci/httpin"
 	"github.com/ggicci/httpin/core"
@@ -13,6 +15,11 @@ import (
 
 const HTTPInKey = "input"
 
+// RoutePatternKey is the request context key under which HTTP middleware can
+// store a *string to learn the pattern of the route that handled the
+// request, e.g. "GET /users/{id}"
+const RoutePatternKey = "routePattern"
+
 type Handler func(c *Context) error
 
 type Middleware func(next Handler) Handler
@@ -21,6 +28,19 @@ type HTTPMiddleware func(http.Handler) http.Handler
 
 type RouteCallback func(r Router)
 
+// ErrorMode decides the format of the error responses sent by a route's
+// Context, see Group.ErrorMode
+type ErrorMode int
+
+const (
+	// AutoErrors picks JSON or HTML from the request's Accept header
+	AutoErrors ErrorMode = iota
+	// JSONErrors always sends JSON errors, e.g. for an API group
+	JSONErrors
+	// HTMLErrors always sends browser errors, e.g. for a web group
+	HTMLErrors
+)
+
 type Route struct {
 	Method           string
 	Path             string
@@ -28,6 +48,12 @@ type Route struct {
 	BeforeMiddleware []Handler
 	AfterMiddleware  []Handler
 	router           *HTTPRouter
+	middlewares      []Middleware
+	errorMode        ErrorMode
+
+	summary string
+	input   any
+	output  any
 }
 
 type HTTPRouter struct {
@@ -37,6 +63,7 @@ type HTTPRouter struct {
 	mux              *http.ServeMux
 	beforeMiddleware []Handler
 	afterMiddleware  []Handler
+	staticPrefixes   []string
 }
 
 type Group struct {
@@ -44,6 +71,7 @@ type Group struct {
 	prefix           string
 	beforeMiddleware []Handler
 	afterMiddleware  []Handler
+	errorMode        ErrorMode
 }
 
 func (g *Group) Group(prefix string) *Group {
@@ -52,9 +80,20 @@ func (g *Group) Group(prefix string) *Group {
 		prefix:           path.Join(g.prefix, prefix),
 		beforeMiddleware: append([]Handler{}, g.beforeMiddleware...),
 		afterMiddleware:  append([]Handler{}, g.afterMiddleware...),
+		errorMode:        g.errorMode,
 	}
 }
 
+// ErrorMode sets the format of the errors sent by the routes added to the
+// group afterwards and by its subgroups, regardless of the Accept header
+//
+//	api := r.Group("/api")
+//	api.ErrorMode(app.JSONErrors)
+func (g *Group) ErrorMode(mode ErrorMode) *Group {
+	g.errorMode = mode
+	return g
+}
+
 func (g *Group) UseBefore(handlers ...Handler) {
 	g.beforeMiddleware = append(g.beforeMiddleware, handlers...)
 }
@@ -72,6 +111,7 @@ func (g *Group) addRoute(method, pattern string, handlers ...Handler) *Route {
 		BeforeMiddleware: append(append([]Handler{}, g.router.beforeMiddleware...), g.beforeMiddleware...),
 		AfterMiddleware:  append(append([]Handler{}, g.afterMiddleware...), g.router.afterMiddleware...),
 		router:           g.router,
+		errorMode:        g.errorMode,
 	}
 	g.router.routes = append(g.router.routes, route)
 	return route
@@ -116,10 +156,56 @@ func (r *HTTPRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	handler.ServeHTTP(w, req)
 }
 
+// SetBasePath mounts every route, group and static file server under prefix,
+// e.g. "/api/v1". It must be called before the routes are registered.
+func (r *HTTPRouter) SetBasePath(prefix string) {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		r.basePrefix = ""
+		return
+	}
+	r.basePrefix = "/" + prefix
+}
+
+// BasePath returns the prefix set by SetBasePath
+func (r *HTTPRouter) BasePath() string {
+	return r.basePrefix
+}
+
+// URL returns p prefixed with the router's base path
+func (r *HTTPRouter) URL(p string) string {
+	if r.basePrefix == "" || !strings.HasPrefix(p, "/") {
+		return p
+	}
+	if p == "/" {
+		return r.basePrefix + "/"
+	}
+	return r.basePrefix + p
+}
+
+// routePath joins pattern onto the base path. The root pattern keeps its
+// trailing slash so "/" under a base path is served at the same URL that
+// URL("/") generates.
+func (r *HTTPRouter) routePath(pattern string) string {
+	if pattern == "/" {
+		return r.URL(pattern)
+	}
+	return path.Join(r.basePrefix, pattern)
+}
+
+// prefixPattern applies the base path to a ServeMux pattern, which may
+// start with a method, e.g. "GET /static/"
+func (r *HTTPRouter) prefixPattern(pattern string) string {
+	if method, p, ok := strings.Cut(pattern, " "); ok {
+		return method + " " + r.URL(strings.TrimLeft(p, " "))
+	}
+	return r.URL(pattern)
+}
+
 func (r *HTTPRouter) Group(prefix string) *Group {
 	return &Group{
 		router:           r,
-		prefix:           prefix,
+		prefix:           path.Join(r.basePrefix, prefix),
 		beforeMiddleware: []Handler{},
 		afterMiddleware:  []Handler{},
 	}
@@ -133,18 +219,30 @@ func (r *HTTPRouter) UseAfter(handlers ...Handler) {
 	r.afterMiddleware = append(handlers, r.afterMiddleware...)
 }
 
+// HasRoute reports whether a route is registered for method and pattern,
+// where pattern is relative to the base path
 func (r *HTTPRouter) HasRoute(method string, pattern string) bool {
+	fullPath := r.routePath(pattern)
 	return slices.ContainsFunc(r.routes, func(route *Route) bool {
-		return route.Method == method && route.Path == pattern
+		return route.Method == method && route.Path == fullPath
 	})
 }
 
 func (r *HTTPRouter) Handle(pattern string, handler http.Handler) {
-	r.mux.Handle(pattern, handler)
+	r.mux.Handle(r.prefixPattern(pattern), handler)
 }
 
 func (r *HTTPRouter) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
-	r.mux.HandleFunc(pattern, handler)
+	r.mux.HandleFunc(r.prefixPattern(pattern), handler)
+}
+
+// Static serves the files of root under the URL prefix, e.g.
+// r.Static("/assets/", assets) where assets is an embed.FS (narrowed with
+// fs.Sub if needed) for single-binary deploys
+func (r *HTTPRouter) Static(prefix string, root fs.FS) {
+	prefix = "/" + strings.Trim(prefix, "/") + "/"
+	r.Handle("GET "+prefix, http.StripPrefix(r.URL(prefix), http.FileServerFS(root)))
+	r.staticPrefixes = append(r.staticPrefixes, prefix)
 }
 
 func (r *HTTPRouter) Get(pattern string, handlers ...Handler) *Route {
@@ -189,7 +287,7 @@ func (r *HTTPRouter) Use(middlewares ...HTTPMiddleware) {
 }
 
 func (r *HTTPRouter) addRoute(method, pattern string, handlers ...Handler) *Route {
-	fullPath := path.Join(r.basePrefix, pattern)
+	fullPath := r.routePath(pattern)
 	route := &Route{
 		Method:           method,
 		Path:             fullPath,
@@ -213,6 +311,32 @@ func (r *Route) UseAfter(handlers ...Handler) *Route {
 	return r
 }
 
+// Use wraps the route's whole handler chain, including its before and after
+// middleware, with m. The first middleware given is the outermost, e.g.
+// r.Get("/users", h).Use(Input(&UserInput{}), audit) runs Input, then audit.
+func (r *Route) Use(m ...Middleware) *Route {
+	r.middlewares = append(r.middlewares, m...)
+	return r
+}
+
+// ErrorMode overrides the format of the route's errors set by its group
+func (r *Route) ErrorMode(mode ErrorMode) *Route {
+	r.errorMode = mode
+	return r
+}
+
+// chain returns the route's entry handler: its middlewares wrapped around
+// the Handler chain run by Context.Next
+func (r *Route) chain() Handler {
+	var h Handler = func(c *Context) error {
+		return c.Next()
+	}
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		h = r.middlewares[i](h)
+	}
+	return h
+}
+
 func Input(inputStruct any, opts ...core.Option) Middleware {
 	co, err := httpin.New(inputStruct, opts...)
 
@@ -241,6 +365,7 @@ type Router interface {
 	HasRoute(method string, pattern string) bool
 	Handle(pattern string, handler http.Handler)
 	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+	Static(prefix string, root fs.FS)
 	Get(pattern string, handlers ...Handler) *Route
 	Post(pattern string, handlers ...Handler) *Route
 	Put(pattern string, handlers ...Handler) *Route
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/api/shared"
+)
+
+// serve sends a method request for target to h and returns the status and body
+func serve(h http.Handler, method, target string) (int, string) {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+	return w.Code, w.Body.String()
+}
+
+func TestBasePathPrefixesRoutes(t *testing.T) {
+	handler, shutDown := TestHandler(WithBasePath("/api/v1/"), WithRoutes(func(r Router) {
+		r.Get("/users", func(c *Context) error { return c.Text([]byte("users")) })
+		r.Group("/admin").Get("/stats", func(c *Context) error { return c.Text([]byte("stats")) })
+		r.Post("/", func(c *Context) error { return c.Text([]byte("root")) })
+	}))
+	defer shutDown()
+
+	tests := []struct {
+		method, target string
+		status         int
+		body           string
+	}{
+		{http.MethodGet, "/api/v1/users", http.StatusOK, "users"},
+		{http.MethodGet, "/api/v1/admin/stats", http.StatusOK, "stats"},
+		{http.MethodPost, "/api/v1/", http.StatusOK, "root"},
+		{http.MethodGet, "/users", http.StatusNotFound, ""},
+		{http.MethodGet, "/admin/stats", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		status, body := serve(handler, tt.method, tt.target)
+		if status != tt.status || (tt.body != "" && body != tt.body) {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.target, status, body, tt.status, tt.body)
+		}
+	}
+}
+
+func TestSetBasePath(t *testing.T) {
+	SetBasePath("v2")
+	defer SetBasePath("")
+
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/ping", func(c *Context) error { return c.Text([]byte("pong")) })
+	}))
+	defer shutDown()
+
+	if status, body := serve(handler, http.MethodGet, "/v2/ping"); status != http.StatusOK || body != "pong" {
+		t.Errorf("GET /v2/ping = %d %q, want 200 pong", status, body)
+	}
+	if status, _ := serve(handler, http.MethodGet, "/ping"); status != http.StatusNotFound {
+		t.Errorf("GET /ping = %d, want 404 outside the base path", status)
+	}
+}
+
+func TestRouterURL(t *testing.T) {
+	tests := []struct {
+		base, path, want string
+	}{
+		{"", "/users", "/users"},
+		{"/api", "/users", "/api/users"},
+		{"api/", "/users", "/api/users"},
+		{"/api", "/", "/api/"},
+		{"/api", "relative", "relative"},
+		{"/", "/users", "/users"},
+	}
+	for _, tt := range tests {
+		r := newRouter()
+		r.SetBasePath(tt.base)
+		if got := r.URL(tt.path); got != tt.want {
+			t.Errorf("base %q: URL(%q) = %q, want %q", tt.base, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHasRouteUnderBasePath(t *testing.T) {
+	r := newRouter()
+	r.SetBasePath("/api")
+	r.Get("/users", func(c *Context) error { return nil })
+	r.Post("/", func(c *Context) error { return nil })
+
+	if !r.HasRoute(http.MethodGet, "/users") {
+		t.Error("HasRoute(GET /users) = false, want true")
+	}
+	if !r.HasRoute(http.MethodPost, "/") {
+		t.Error("HasRoute(POST /) = false, want true")
+	}
+	if got := r.routes[1].Path; got != "/api/" {
+		t.Errorf("root route path = %q, want /api/", got)
+	}
+}
+
+// tracer returns a Middleware that records name on the way in and out
+func tracer(trace *[]string, name string) Middleware {
+	return func(next Handler) Handler {
+		return func(c *Context) error {
+			*trace = append(*trace, name+" in")
+			err := next(c)
+			*trace = append(*trace, name+" out")
+			return err
+		}
+	}
+}
+
+func TestRouteUseOrdering(t *testing.T) {
+	var trace []string
+	step := func(name string) Handler {
+		return func(c *Context) error {
+			trace = append(trace, name)
+			return c.Next()
+		}
+	}
+
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/ordered", step("handler")).
+			UseBefore(step("before")).
+			UseAfter(step("after")).
+			Use(tracer(&trace, "first"), tracer(&trace, "second")).
+			Use(tracer(&trace, "third"))
+	}))
+	defer shutDown()
+
+	serve(handler, http.MethodGet, "/ordered")
+
+	want := []string{
+		"first in", "second in", "third in",
+		"before", "handler", "after",
+		"third out", "second out", "first out",
+	}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestRouteUseShortCircuits(t *testing.T) {
+	called := false
+	deny := func(next Handler) Handler {
+		return func(c *Context) error {
+			return c.Status(http.StatusForbidden).Text([]byte("denied"))
+		}
+	}
+	failing := func(next Handler) Handler {
+		return func(c *Context) error {
+			return errors.New("middleware failed")
+		}
+	}
+
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/denied", func(c *Context) error {
+			called = true
+			return nil
+		}).Use(deny)
+		r.Get("/failing", func(c *Context) error {
+			called = true
+			return nil
+		}).Use(failing)
+	}))
+	defer shutDown()
+
+	if status, body := serve(handler, http.MethodGet, "/denied"); status != http.StatusForbidden || body != "denied" {
+		t.Errorf("GET /denied = %d %q, want 403 denied", status, body)
+	}
+	if status, _ := serve(handler, http.MethodGet, "/failing"); status != http.StatusInternalServerError {
+		t.Errorf("GET /failing = %d, want 500 from the middleware error", status)
+	}
+	if called {
+		t.Error("the handler ran behind a middleware that did not call next")
+	}
+}
+
+func TestErrorModeOverridesAccept(t *testing.T) {
+	boom := func(c *Context) error { return errors.New("boom") }
+	invalid := func(c *Context) error {
+		return shared.ValidationErrors{"name": {"This field is required"}}
+	}
+
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		api := r.Group("/api")
+		api.ErrorMode(JSONErrors)
+		api.Get("/boom", boom)
+		api.Post("/users", invalid)
+		api.Group("/v2").Get("/boom", boom)
+		api.Get("/page", boom).ErrorMode(HTMLErrors)
+
+		web := r.Group("/web")
+		web.ErrorMode(HTMLErrors)
+		web.Get("/boom", boom)
+	}))
+	defer shutDown()
+
+	tests := []struct {
+		name, method, target, accept string
+		status                       int
+		json                         bool
+	}{
+		{"api under html accept", http.MethodGet, "/api/boom", "text/html", http.StatusInternalServerError, true},
+		{"api subgroup inherits", http.MethodGet, "/api/v2/boom", "text/html,application/xhtml+xml", http.StatusInternalServerError, true},
+		{"api validation is not redirected", http.MethodPost, "/api/users", "text/html", http.StatusUnprocessableEntity, true},
+		{"route overrides group", http.MethodGet, "/api/page", "application/json", http.StatusInternalServerError, false},
+		{"web under json accept", http.MethodGet, "/web/boom", "application/json", http.StatusInternalServerError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.Header.Set("Accept", tt.accept)
+			r.Header.Set("Referer", "http://example.com/form")
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			isJSON := strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+			if w.Code != tt.status || isJSON != tt.json {
+				t.Errorf("%s %s = %d %q, want %d with JSON %v", tt.method, tt.target, w.Code, w.Header().Get("Content-Type"), tt.status, tt.json)
+			}
+		})
+	}
+}
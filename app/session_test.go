@@ -0,0 +1,203 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/lemmego/api/session"
+	"github.com/lemmego/api/shared"
+)
+
+func TestSessionKeys(t *testing.T) {
+	c, _, _ := newSessionContext(t, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if keys := c.SessionKeys(); len(keys) != 0 {
+		t.Fatalf("new session keys = %v, want none", keys)
+	}
+
+	c.PutSession("user_id", 1).PutSession("cart", []string{"a"}).PutSession("_token", "csrf")
+
+	want := []string{"_token", "cart", "user_id"}
+	if keys := c.SessionKeys(); !reflect.DeepEqual(keys, want) {
+		t.Fatalf("SessionKeys() = %v, want %v", keys, want)
+	}
+}
+
+func TestForgetSession(t *testing.T) {
+	c, _, _ := newSessionContext(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.PutSession("a", 1).PutSession("b", 2).PutSession("c", 3)
+
+	if got := c.ForgetSession("a", "c", "missing"); got != c {
+		t.Fatalf("ForgetSession returned %v, want the context", got)
+	}
+
+	if keys := c.SessionKeys(); !reflect.DeepEqual(keys, []string{"b"}) {
+		t.Fatalf("SessionKeys() after forgetting = %v, want [b]", keys)
+	}
+	if c.GetSession("b") != 2 {
+		t.Errorf("kept key b = %v, want 2", c.GetSession("b"))
+	}
+}
+
+func TestFlushSessionKeepsCSRFToken(t *testing.T) {
+	c, _, _ := newSessionContext(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.PutSession("_token", "csrf").PutSession("user_id", 1).PutSession("cart", "x")
+
+	if err := c.FlushSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := c.SessionKeys(); !reflect.DeepEqual(keys, []string{"_token"}) {
+		t.Fatalf("SessionKeys() after flush = %v, want [_token]", keys)
+	}
+	if got := c.GetSessionString("_token"); got != "csrf" {
+		t.Errorf("_token = %q, want csrf", got)
+	}
+}
+
+func TestFlushSessionWithoutSession(t *testing.T) {
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if err := c.FlushSession(); !errors.Is(err, ErrSessionNotSet) {
+		t.Fatalf("FlushSession() = %v, want ErrSessionNotSet", err)
+	}
+	if keys := c.SessionKeys(); keys != nil {
+		t.Errorf("SessionKeys() = %v, want nil", keys)
+	}
+}
+
+func TestSessionMethodsWithoutSession(t *testing.T) {
+	c, _ := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if c.SessionAvailable() {
+		t.Fatal("SessionAvailable() = true without a session provider")
+	}
+	if got := c.PutSession("a", 1); got != c {
+		t.Errorf("PutSession returned %v, want the context", got)
+	}
+	if !errors.Is(c.SessionErr(), ErrSessionNotSet) {
+		t.Errorf("SessionErr() = %v, want ErrSessionNotSet", c.SessionErr())
+	}
+	if got := c.ForgetSession("a"); got != c {
+		t.Errorf("ForgetSession returned %v, want the context", got)
+	}
+	if got := c.WithErrors(shared.ValidationErrors{"a": {"bad"}}).WithSuccess("ok").WithData(map[string]any{"a": 1}); got != c {
+		t.Errorf("chained flash helpers returned %v, want the context", got)
+	}
+	if c.GetSession("a") != nil || c.GetSessionString("a") != "" || c.PopSession("a") != nil {
+		t.Error("session getters returned values without a session")
+	}
+}
+
+// TestRequestsWithoutSessionProvider serves requests through a handler
+// with no session provider registered
+func TestRequestsWithoutSessionProvider(t *testing.T) {
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/token", func(c *Context) error {
+			return c.Text([]byte(c.GetSessionString("_token")))
+		})
+		r.Post("/form", func(c *Context) error {
+			return shared.ValidationErrors{"email": {"This field is required"}}
+		})
+		r.Post("/save", func(c *Context) error {
+			return c.WithSuccess("saved").Redirect("/done")
+		})
+	}))
+	defer shutDown()
+
+	t.Run("get", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/token", nil))
+		if w.Code != http.StatusOK || w.Body.String() != "" {
+			t.Errorf("got %d %q, want 200 and an empty body", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("failed form validation", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/form", strings.NewReader(url.Values{"email": {""}}.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		r.Header.Set("Accept", "text/html")
+		r.Header.Set("Referer", "http://example.com/signup")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "http://example.com/signup" {
+			t.Errorf("got %d to %q, want 302 back to the referer", w.Code, w.Header().Get("Location"))
+		}
+	})
+
+	t.Run("flash and redirect", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save", nil))
+		if w.Code != http.StatusFound || w.Header().Get("Location") != "/done" {
+			t.Errorf("got %d to %q, want 302 to /done", w.Code, w.Header().Get("Location"))
+		}
+	})
+}
+
+// nextRequest commits c's session and returns a context for r that loads
+// it again, as the browser's next request would
+func nextRequest(t *testing.T, c *Context, sess *session.Session, r *http.Request) *Context {
+	t.Helper()
+	token, _, err := sess.Commit(c.Request().Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, err := sess.Load(r.Context(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Context{app: c.app, request: r.WithContext(ctx), writer: httptest.NewRecorder(), index: -1}
+}
+
+func TestOldInputFlashedByWithInput(t *testing.T) {
+	form := url.Values{"name": {"john"}, "tags": {"a", "b"}, "password": {"secret"}, "Password_Confirm": {"secret"}}
+	c, sess, _ := newSessionContext(t, formRequest("/register", form))
+	c.WithInput()
+
+	next := nextRequest(t, c, sess, httptest.NewRequest(http.MethodGet, "/register", nil))
+
+	want := map[string][]string{"name": {"john"}, "tags": {"a", "b"}}
+	if got := next.OldInput(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("OldInput() = %v, want %v without passwords", got, want)
+	}
+	if got := next.Old("name"); got != "john" {
+		t.Errorf("Old(name) = %q, want john", got)
+	}
+	if got := next.Old("tags"); got != "a" {
+		t.Errorf("Old(tags) = %q, want the first value", got)
+	}
+	if got := next.Old("password"); got != "" {
+		t.Errorf("Old(password) = %q, want it never flashed", got)
+	}
+	if got := next.Old("email", "fallback@example.com"); got != "fallback@example.com" {
+		t.Errorf("Old(email, fallback) = %q, want the fallback", got)
+	}
+
+	// Reading again in the same request uses the cached flash
+	if got := next.Old("name"); got != "john" {
+		t.Errorf("second Old(name) = %q, want john", got)
+	}
+
+	// The flash is consumed, the request after sees nothing
+	after := nextRequest(t, next, sess, httptest.NewRequest(http.MethodGet, "/register", nil))
+	if got := after.OldInput(); len(got) != 0 {
+		t.Errorf("OldInput() on the following request = %v, want empty", got)
+	}
+}
+
+func TestOldInputWithoutFlash(t *testing.T) {
+	c, _, _ := newSessionContext(t, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if got := c.OldInput(); got == nil || len(got) != 0 {
+		t.Errorf("OldInput() = %#v, want an empty map", got)
+	}
+	if got := c.Old("name", "default"); got != "default" {
+		t.Errorf("Old(name, default) = %q, want default", got)
+	}
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+//go:embed testdata/assets
+var testAssets embed.FS
+
+// staticDir writes name with contents into a new directory and returns it
+func staticDir(t *testing.T, name, contents string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestStaticConfiguredDirectory(t *testing.T) {
+	config.Set("app.static_dir", staticDir(t, "app.js", "console.log(1)"))
+	config.Set("app.static_url", "/assets")
+	config.Set("app.public_dir", staticDir(t, "robots.txt", "User-agent: *"))
+	defer func() {
+		for _, key := range []string{"app.static_dir", "app.static_url", "app.public_dir"} {
+			config.Set(key, nil)
+		}
+	}()
+
+	handler, shutDown := TestHandler()
+	defer shutDown()
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/assets/app.js", http.StatusOK, "console.log(1)"},
+		{"/public/robots.txt", http.StatusOK, "User-agent: *"},
+		{"/static/app.js", http.StatusNotFound, ""},
+		{"/assets/missing.js", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		status, body := serve(handler, http.MethodGet, tt.target)
+		if status != tt.status || (tt.body != "" && body != tt.body) {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.target, status, body, tt.status, tt.body)
+		}
+	}
+}
+
+func TestStaticDisabledByEmptyDir(t *testing.T) {
+	config.Set("app.public_dir", "")
+	defer config.Set("app.public_dir", nil)
+
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/public/{file}", func(c *Context) error { return c.Text([]byte("route")) })
+	}))
+	defer shutDown()
+
+	if status, body := serve(handler, http.MethodGet, "/public/robots.txt"); status != http.StatusOK || body != "route" {
+		t.Errorf("GET /public/robots.txt = %d %q, want the route, not a file server", status, body)
+	}
+}
+
+func TestStaticEmbedFS(t *testing.T) {
+	assets, err := fs.Sub(testAssets, "testdata/assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler, shutDown := TestHandler(WithBasePath("/app"), WithRoutes(func(r Router) {
+		r.Static("/static/", assets)
+	}))
+	defer shutDown()
+
+	if status, body := serve(handler, http.MethodGet, "/app/static/app.css"); status != http.StatusOK || body != "body { color: red; }\n" {
+		t.Errorf("GET /app/static/app.css = %d %q, want the embedded file", status, body)
+	}
+	if status, _ := serve(handler, http.MethodGet, "/static/app.css"); status != http.StatusNotFound {
+		t.Errorf("GET /static/app.css = %d, want 404 outside the base path", status)
+	}
+}
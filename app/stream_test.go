@@ -0,0 +1,110 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// flushRecorder records the body sent so far at every Flush
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed []string
+}
+
+func (w *flushRecorder) Flush() {
+	w.flushed = append(w.flushed, w.Body.String())
+	w.ResponseRecorder.Flush()
+}
+
+func values(items ...any) <-chan any {
+	ch := make(chan any, len(items))
+	for _, item := range items {
+		ch <- item
+	}
+	close(ch)
+	return ch
+}
+
+func TestJSONArrayFlushesEachElement(t *testing.T) {
+	defer jsonOpts.Store(nil)
+	SetJSONOptions(JSONOptions{EscapeHTML: true})
+
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	c := &Context{app: Get(), request: httptest.NewRequest(http.MethodGet, "/", nil), writer: w, index: -1}
+
+	if err := c.JSONArray(values(1, "two", M{"n": 3})); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{`[1`, `[1,"two"`, `[1,"two",{"n":3}`, `[1,"two",{"n":3}]`}
+	if !reflect.DeepEqual(w.flushed, want) {
+		t.Fatalf("flushed %q, want %q", w.flushed, want)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+}
+
+func TestJSONArrayEmpty(t *testing.T) {
+	c, w := newTestContext(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err := c.JSONArray(values()); err != nil {
+		t.Fatal(err)
+	}
+	if w.Body.String() != "[]" {
+		t.Fatalf("body = %q, want []", w.Body.String())
+	}
+}
+
+func TestStreamErrorsAfterHeadersAreNotRendered(t *testing.T) {
+	defer jsonOpts.Store(nil)
+
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/array", func(c *Context) error {
+			return c.JSONArray(values(1, func() {}))
+		})
+		r.Get("/stream", func(c *Context) error {
+			return c.JSONStream(func(enc JSONEncoder) error {
+				if err := enc.Encode(M{"n": 1}); err != nil {
+					return err
+				}
+				return enc.Encode(make(chan int))
+			})
+		})
+	}))
+	defer shutDown()
+	SetJSONOptions(JSONOptions{EscapeHTML: true})
+
+	tests := map[string]string{
+		"/array":  `[1`,
+		"/stream": "{\"n\":1}\n",
+	}
+	for path, want := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: status = %d, want the 200 already sent", path, w.Code)
+		}
+		if got := w.Body.String(); got != want || strings.Contains(got, "message") {
+			t.Errorf("%s: body = %q, want %q without an error document", path, got, want)
+		}
+	}
+}
+
+func TestErrorsBeforeHeadersAreRendered(t *testing.T) {
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Get("/fail", func(c *Context) error {
+			return http.ErrNoCookie
+		})
+	}))
+	defer shutDown()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, jsonRequest(http.MethodGet, "/fail", ""))
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "message") {
+		t.Fatalf("got %d %q, want a 500 error document", w.Code, w.Body.String())
+	}
+}
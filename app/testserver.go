@@ -0,0 +1,75 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/lemmego/api/config"
+)
+
+// TestServer boots a fresh application behind an httptest.Server for
+// integration tests. It runs the registered service providers, applies the
+// given config and routes, and serves requests through the same middleware
+// and session handling as Run, but skips Run's config checks, command
+// handling and signal wiring. The returned func closes the server and the
+// database connections.
+func TestServer(optFuncs ...OptFunc) (*httptest.Server, func()) {
+	handler, shutDown := TestHandler(optFuncs...)
+	srv := httptest.NewServer(handler)
+
+	return srv, func() {
+		srv.Close()
+		shutDown()
+	}
+}
+
+// TestHandler boots a fresh application like TestServer but returns its
+// http.Handler instead of binding a port, so requests can be served with an
+// httptest.ResponseRecorder. The given config replaces the process-wide
+// config while the app is up; the returned func restores the previous config,
+// time zone and JSON options and closes the database connections.
+func TestHandler(optFuncs ...OptFunc) (http.Handler, func()) {
+	opts := &Options{}
+	for _, optFunc := range optFuncs {
+		optFunc(opts)
+	}
+
+	global := Get().(*Application)
+
+	a := &Application{
+		mu:                        sync.Mutex{},
+		Services:                  newServiceContainer(),
+		router:                    newRouter(),
+		config:                    config.GetInstance(),
+		routeCallbacks:            append([]RouteCallback{}, global.routeCallbacks...),
+		serviceRegistrarCallbacks: append([]func(a App) error{}, global.serviceRegistrarCallbacks...),
+		bootStrapperCallbacks:     append([]func(a App) error{}, global.bootStrapperCallbacks...),
+	}
+
+	snapshot := a.config.GetAll()
+	savedLocation, savedJSONOpts := location.Load(), jsonOpts.Load()
+	if opts.Config != nil {
+		a.config.SetConfigMap(opts.Config)
+	}
+
+	if opts.Routes != nil {
+		a.routeCallbacks = append(a.routeCallbacks, opts.Routes)
+	}
+
+	a.router.SetBasePath(global.router.BasePath())
+	if opts.BasePath != "" {
+		a.router.SetBasePath(opts.BasePath)
+	}
+
+	a.registerServiceProviders()
+	a.registerMiddlewares()
+	a.registerRoutes()
+
+	return a.handler(), func() {
+		a.shutDown()
+		a.config.SetConfigMap(snapshot)
+		location.Store(savedLocation)
+		jsonOpts.Store(savedJSONOpts)
+	}
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+func TestTestServerServesRoutes(t *testing.T) {
+	srv, shutDown := TestServer(WithRoutes(func(r Router) {
+		r.Get("/ping", func(c *Context) error {
+			return c.Text([]byte("pong"))
+		})
+	}))
+	defer shutDown()
+
+	res, err := http.Get(srv.URL + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || string(body) != "pong" {
+		t.Fatalf("got %d %q, want 200 \"pong\"", res.StatusCode, body)
+	}
+}
+
+func TestTestHandlerRestoresConfig(t *testing.T) {
+	config.Set("testserver.name", "outer")
+	defer config.Set("testserver.name", nil)
+
+	_, shutDown := TestHandler(WithConfig(config.M{"testserver": config.M{"name": "inner"}}))
+	if got := config.Get("testserver.name"); got != "inner" {
+		t.Fatalf("config while running = %v, want inner", got)
+	}
+	shutDown()
+
+	if got := config.Get("testserver.name"); got != "outer" {
+		t.Fatalf("config after teardown = %v, want outer", got)
+	}
+}
+
+func TestTestHandlerRestoresTimezoneAndJSONOptions(t *testing.T) {
+	berlin := useLocation(t, "Europe/Berlin")
+	SetJSONOptions(JSONOptions{Indent: "\t"})
+	defer jsonOpts.Store(nil)
+
+	_, shutDown := TestHandler(WithConfig(config.M{
+		"app":  config.M{"timezone": "Asia/Tokyo"},
+		"json": config.M{"pretty": false},
+	}))
+	if Location().String() != "Asia/Tokyo" || jsonOptions().Indent != "" {
+		t.Fatalf("while running: zone %v, indent %q, want the app's settings", Location(), jsonOptions().Indent)
+	}
+	shutDown()
+
+	if Location() != berlin {
+		t.Errorf("zone after teardown = %v, want Europe/Berlin", Location())
+	}
+	if got := jsonOptions().Indent; got != "\t" {
+		t.Errorf("JSON indent after teardown = %q, want the previous tab", got)
+	}
+}
+
+func TestTestHandlerRestoresUnsetGlobals(t *testing.T) {
+	_, shutDown := TestHandler(WithConfig(config.M{"app": config.M{"timezone": "Asia/Tokyo"}}))
+	shutDown()
+
+	if location.Load() != nil || jsonOpts.Load() != nil {
+		t.Errorf("globals after teardown = %v, %v, want them unset again", location.Load(), jsonOpts.Load())
+	}
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	// Embeds the zone database so app.timezone loads on hosts without one
+	_ "time/tzdata"
+)
+
+var location atomic.Pointer[time.Location]
+
+// Location returns the application's time zone, set from app.timezone and
+// UTC by default
+func Location() *time.Location {
+	if loc := location.Load(); loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// SetLocation changes the application's time zone
+func SetLocation(loc *time.Location) {
+	location.Store(loc)
+}
+
+// Now returns the current time in the application's time zone
+func Now() time.Time {
+	return time.Now().In(Location())
+}
+
+// ParseTime parses value with layout, reading times without a zone or
+// offset as being in the application's time zone
+func ParseTime(layout, value string) (time.Time, error) {
+	return time.ParseInLocation(layout, value, Location())
+}
+
+// loadTimezone sets the application's time zone from app.timezone, e.g.
+// "Europe/Berlin"
+func (a *Application) loadTimezone() error {
+	name, _ := a.config.Get("app.timezone", "UTC").(string)
+	if name == "" {
+		return nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("app.timezone: %w", err)
+	}
+	SetLocation(loc)
+	return nil
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lemmego/api/config"
+)
+
+// useLocation sets the application's time zone for the rest of the test
+func useLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	SetLocation(loc)
+	t.Cleanup(func() { location.Store(nil) })
+	return loc
+}
+
+func TestLocationDefaultsToUTC(t *testing.T) {
+	if Location() != time.UTC {
+		t.Errorf("Location() = %v, want UTC", Location())
+	}
+	if Now().Location() != time.UTC {
+		t.Errorf("Now() is in %v, want UTC", Now().Location())
+	}
+}
+
+func TestLoadTimezone(t *testing.T) {
+	tests := []struct {
+		name    string
+		zone    any
+		want    string
+		wantErr bool
+	}{
+		{"default", nil, "UTC", false},
+		{"named zone", "Asia/Dhaka", "Asia/Dhaka", false},
+		{"empty keeps the current zone", "", "UTC", false},
+		{"unknown zone", "Mars/Olympus_Mons", "UTC", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Set("app.timezone", tt.zone)
+			defer config.Set("app.timezone", nil)
+			t.Cleanup(func() { location.Store(nil) })
+
+			a := &Application{config: config.GetInstance()}
+			if err := a.loadTimezone(); (err != nil) != tt.wantErr {
+				t.Fatalf("loadTimezone() = %v, want error %v", err, tt.wantErr)
+			}
+			if got := Location().String(); got != tt.want {
+				t.Errorf("Location() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTimeInLocation(t *testing.T) {
+	berlin := useLocation(t, "Europe/Berlin")
+
+	tests := []struct {
+		layout, value string
+		want          time.Time
+	}{
+		// Without an offset the value is read in the app's zone, DST included
+		{time.DateTime, "2024-01-15 09:00:00", time.Date(2024, 1, 15, 8, 0, 0, 0, time.UTC)},
+		{time.DateTime, "2024-07-15 09:00:00", time.Date(2024, 7, 15, 7, 0, 0, 0, time.UTC)},
+		{time.DateOnly, "2024-03-31", time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC)},
+		// An explicit offset wins over the app's zone
+		{time.RFC3339, "2024-01-15T09:00:00Z", time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC)},
+		{time.RFC3339, "2024-01-15T09:00:00+06:00", time.Date(2024, 1, 15, 3, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTime(tt.layout, tt.value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTime(%q) = %v, want %v", tt.value, got.UTC(), tt.want)
+		}
+	}
+
+	got, _ := ParseTime(time.DateTime, "2024-01-15 09:00:00")
+	if got.Location() != berlin {
+		t.Errorf("ParseTime() is in %v, want Europe/Berlin", got.Location())
+	}
+	if _, err := ParseTime(time.DateTime, "15/01/2024"); err == nil {
+		t.Error("ParseTime() with a mismatched layout returned no error")
+	}
+}
+
+func TestTimesCompareAcrossZones(t *testing.T) {
+	useLocation(t, "America/New_York")
+
+	local, err := ParseTime(time.DateTime, "2024-06-01 08:00:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if !local.Equal(utc) || local == utc {
+		t.Errorf("%v and %v: want the same instant in different zones", local, utc)
+	}
+	if !local.Before(utc.Add(time.Second)) || !local.After(utc.Add(-time.Second)) {
+		t.Error("Before/After disagree with Equal across zones")
+	}
+	if local.Hour() != 8 || local.In(time.UTC).Hour() != 12 {
+		t.Errorf("hours = %d local, %d UTC, want 8 and 12", local.Hour(), local.In(time.UTC).Hour())
+	}
+
+	now := Now()
+	if now.Location().String() != "America/New_York" {
+		t.Errorf("Now() is in %v, want America/New_York", now.Location())
+	}
+	if d := time.Since(now); d < 0 || d > time.Minute {
+		t.Errorf("Now() is %v away from time.Now()", d)
+	}
+}
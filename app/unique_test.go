@@ -0,0 +1,78 @@
+package app
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/lemmego/api/db"
+	"github.com/lemmego/api/shared"
+)
+
+// openUniqueTestDB opens a SQLite database with a users table holding one
+// row and makes it the default connection, named after the test
+func openUniqueTestDB(t *testing.T) *db.Connection {
+	t.Helper()
+	conn, err := db.NewConnection(&db.Config{
+		ConnName: t.Name(),
+		Driver:   db.DialectSQLite,
+		Database: filepath.Join(t.TempDir(), "unique.db"),
+	}).Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db.AddConnection(conn)
+	t.Setenv("DB_CONNECTION", t.Name())
+
+	if err := conn.DB().Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT, tenant INTEGER)").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.DB().Exec("INSERT INTO users (email, tenant) VALUES ('taken@example.com', 1)").Error; err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestUnique(t *testing.T) {
+	openUniqueTestDB(t)
+
+	v := NewValidator(nil)
+	v.Field("taken", "taken@example.com").Unique("users", "email")
+	v.Field("free", "free@example.com").Unique("users", "email")
+	v.Field("other_tenant", "taken@example.com").Unique("users", "email", map[string]interface{}{"tenant = ?": 2})
+
+	want := shared.ValidationErrors{"taken": {"This field must be unique"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestUniqueRejectsMaliciousIdentifiers(t *testing.T) {
+	conn := openUniqueTestDB(t)
+
+	tests := []struct{ table, column string }{
+		{"users", "email = email OR 1=1; DROP TABLE users; --"},
+		{"users", "email) OR (1=1"},
+		{"users", `"email"`},
+		{"users", ""},
+		{"users; DROP TABLE users", "email"},
+		{"users u JOIN secrets s", "email"},
+		{"a.b.c", "email"},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator(nil)
+		v.Field("email", "free@example.com").Unique(tt.table, tt.column)
+		want := shared.ValidationErrors{"email": {"This field cannot be checked for uniqueness"}}
+		if !reflect.DeepEqual(v.Errors, want) {
+			t.Errorf("Unique(%q, %q): Errors = %v, want %v", tt.table, tt.column, v.Errors, want)
+		}
+	}
+
+	var count int64
+	if err := conn.DB().Table("users").Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("users table after malicious checks: count %d, err %v", count, err)
+	}
+}
@@ -0,0 +1,295 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/lemmego/api/shared"
+)
+
+var shapeErrors = shared.ValidationErrors{
+	"email": {"This field is required"},
+	"name":  {"This field is too short", "This field must be alphabetic"},
+}
+
+// normalizeJSON round-trips v through JSON, as a client would see it
+func normalizeJSON(t *testing.T, v any) map[string]any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := map[string]any{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestValidationErrorShapeMatchesAcrossPaths(t *testing.T) {
+	// JSON response
+	c, w := newTestContext(jsonRequest(http.MethodPost, "/users", `{}`))
+	if err := c.ValidationError(shapeErrors); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("JSON status = %d, want 422", w.Code)
+	}
+	jsonShape := map[string]any{}
+	if err := json.Unmarshal(w.Body.Bytes(), &jsonShape); err != nil {
+		t.Fatal(err)
+	}
+
+	// Session flash, from an HTML form submission
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/users", nil)
+	r.Header.Set("Accept", "text/html")
+	r.Header.Set("Referer", "http://example.com/users/new")
+	c, _, w = newSessionContext(t, r)
+	if err := c.ValidationError(shapeErrors); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusFound {
+		t.Fatalf("form status = %d, want 302", w.Code)
+	}
+	flashShape := normalizeJSON(t, c.GetSession("errors"))
+
+	// Inertia props built on the next request from the flashed value
+	props := c.withValidationProps(map[string]any{"user": "jane"})
+	inertiaShape := normalizeJSON(t, map[string]any{"message": props["message"], "errors": props["errors"]})
+
+	want := normalizeJSON(t, shapeErrors.Envelope())
+	for name, shape := range map[string]map[string]any{"json": jsonShape, "flash": flashShape, "inertia": inertiaShape} {
+		if !reflect.DeepEqual(shape, want) {
+			t.Errorf("%s shape = %v, want %v", name, shape, want)
+		}
+	}
+	if props["user"] != "jane" {
+		t.Errorf("handler props were dropped: %v", props)
+	}
+}
+
+func TestValidationPropsKeepHandlerValues(t *testing.T) {
+	c, _, _ := newSessionContext(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.WithErrors(shapeErrors)
+
+	props := c.withValidationProps(map[string]any{"message": "custom"})
+	if props["message"] != "custom" {
+		t.Errorf("message prop = %v, want the handler's value", props["message"])
+	}
+	if !reflect.DeepEqual(props["errors"], shapeErrors) {
+		t.Errorf("errors prop = %v", props["errors"])
+	}
+
+	// The flash is consumed by the first read
+	if props := c.withValidationProps(nil); props != nil {
+		t.Errorf("props after the flash was read = %v, want nil", props)
+	}
+}
+
+func TestFlashedErrorsInTemplateData(t *testing.T) {
+	c, _, _ := newSessionContext(t, httptest.NewRequest(http.MethodGet, "/", nil))
+	c.WithErrors(shapeErrors)
+
+	if data := c.resolveTemplateData(nil); !reflect.DeepEqual(data.ValidationErrors, shapeErrors) {
+		t.Fatalf("template ValidationErrors = %v, want %v", data.ValidationErrors, shapeErrors)
+	}
+}
+
+func TestPopFlashedErrorsWrapsLegacyMaps(t *testing.T) {
+	c, _, _ := newSessionContext(t, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	c.PutSession("errors", map[string][]string(shapeErrors))
+	if got := c.popFlashedErrors(); !reflect.DeepEqual(got, shapeErrors.Envelope()) {
+		t.Errorf("legacy map = %+v", got)
+	}
+
+	c.PutSession("errors", shapeErrors)
+	if got := c.popFlashedErrors(); !reflect.DeepEqual(got, shapeErrors.Envelope()) {
+		t.Errorf("legacy ValidationErrors = %+v", got)
+	}
+
+	if got := c.popFlashedErrors(); got.Errors == nil || len(got.Errors) != 0 {
+		t.Errorf("nothing flashed = %+v, want empty errors", got)
+	}
+}
+
+func TestFlashedEnvelopeSurvivesSessionEncoding(t *testing.T) {
+	codec := scs.GobCodec{}
+	data, err := codec.Encode(time.Now().Add(time.Hour), map[string]any{"errors": shapeErrors.Envelope()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, values, err := codec.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := values["errors"].(shared.ValidationEnvelope); !ok || !reflect.DeepEqual(got, shapeErrors.Envelope()) {
+		t.Fatalf("decoded flash = %#v", values["errors"])
+	}
+}
+
+// profileInput validates itself through the BaseInput validator that
+// ParseInput attaches
+type profileInput struct {
+	*BaseInput
+	Name  string `json:"name" in:"form=name"`
+	Email string `json:"email" in:"form=email"`
+}
+
+func (i *profileInput) Validate() error {
+	i.Validator.Field("name", i.Name).Required()
+	i.Validator.Field("email", i.Email).Required().Email()
+	return i.Validator.Validate()
+}
+
+// validateInputHandler serves POST /profile, validating the body with
+// ValidateInput and echoing the parsed name
+func validateInputHandler(t *testing.T) http.Handler {
+	t.Helper()
+	handler, shutDown := TestHandler(WithRoutes(func(r Router) {
+		r.Post("/profile", func(c *Context) error {
+			in := &profileInput{}
+			if err := c.ValidateInput(in); err != nil {
+				return err
+			}
+			return c.JSON(M{"name": in.Name})
+		})
+	}))
+	t.Cleanup(shutDown)
+	return handler
+}
+
+func TestValidateInputValid(t *testing.T) {
+	w := httptest.NewRecorder()
+	validateInputHandler(t).ServeHTTP(w, jsonRequest(http.MethodPost, "/profile", `{"name":"john","email":"john@example.com"}`))
+
+	if w.Code != http.StatusOK || normalizeJSON(t, json.RawMessage(w.Body.Bytes()))["name"] != "john" {
+		t.Fatalf("got %d %s, want 200 with the name", w.Code, w.Body.String())
+	}
+}
+
+func TestValidateInputInvalid(t *testing.T) {
+	w := httptest.NewRecorder()
+	validateInputHandler(t).ServeHTTP(w, jsonRequest(http.MethodPost, "/profile", `{"name":"","email":"nope"}`))
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", w.Code)
+	}
+	var got shared.ValidationEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := shared.ValidationErrors{
+		"name":  {"This field is required"},
+		"email": {"This field must be a valid email address"},
+	}
+	if got.Message != shared.ValidationMessage || !reflect.DeepEqual(got.Errors, want) {
+		t.Fatalf("body = %+v, want the envelope with %v", got, want)
+	}
+}
+
+func TestValidateInputMalformed(t *testing.T) {
+	w := httptest.NewRecorder()
+	validateInputHandler(t).ServeHTTP(w, jsonRequest(http.MethodPost, "/profile", `{"name":`))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400 before validation runs", w.Code)
+	}
+}
+
+func TestValidateInputWithoutValidator(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/", `{"name":""}`))
+
+	var in struct {
+		Name string `json:"name"`
+	}
+	if err := c.ValidateInput(&in); err != nil {
+		t.Fatalf("ValidateInput() on a plain struct = %v, want nil", err)
+	}
+}
+
+// contactInput and addressInput overlap on name, so ValidateAll has to
+// merge the two without repeating the message
+type contactInput struct {
+	*BaseInput
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func (i *contactInput) Validate() error {
+	i.Validator.Field("name", i.Name).Required()
+	i.Validator.Field("email", i.Email).Email()
+	return i.Validator.Validate()
+}
+
+type addressInput struct {
+	*BaseInput
+	Name string `json:"name"`
+	City string `json:"city"`
+}
+
+func (i *addressInput) Validate() error {
+	i.Validator.Field("name", i.Name).Required()
+	i.Validator.Field("city", i.City).Required()
+	return i.Validator.Validate()
+}
+
+// sortQuery is decoded from the query string through its `in` tags
+type sortQuery struct {
+	*BaseInput
+	Sort string `in:"query=sort"`
+}
+
+func (i *sortQuery) Validate() error {
+	i.Validator.Field("sort", i.Sort).Required()
+	return i.Validator.Validate()
+}
+
+func TestValidateAllMergesErrors(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/profile", `{"email":"nope","city":""}`))
+
+	err := c.ValidateAll(&contactInput{}, &addressInput{}, &sortQuery{})
+
+	var errs shared.ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("ValidateAll() = %v, want shared.ValidationErrors", err)
+	}
+	want := shared.ValidationErrors{
+		"name":  {"This field is required"},
+		"email": {"This field must be a valid email address"},
+		"city":  {"This field is required"},
+		"sort":  {"This field is required"},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Fatalf("ValidateAll() errors = %v, want %v", errs, want)
+	}
+}
+
+func TestValidateAllDecodesEveryStruct(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/profile?sort=name", `{"name":"john","email":"john@example.com","city":"Dhaka"}`))
+
+	contact, address, query := &contactInput{}, &addressInput{}, &sortQuery{}
+	if err := c.ValidateAll(contact, address, query); err != nil {
+		t.Fatalf("ValidateAll() = %v, want nil", err)
+	}
+	if contact.Name != "john" || address.Name != "john" || address.City != "Dhaka" || query.Sort != "name" {
+		t.Errorf("decoded %+v %+v %+v, want the same body in both structs and the query sort", contact, address, query)
+	}
+}
+
+func TestValidateAllReturnsMalformedBody(t *testing.T) {
+	c, _ := newTestContext(jsonRequest(http.MethodPost, "/profile", `{"name":`))
+
+	err := c.ValidateAll(&contactInput{}, &addressInput{})
+	var errs shared.ValidationErrors
+	if err == nil || errors.As(err, &errs) {
+		t.Fatalf("ValidateAll() = %v, want the decode error", err)
+	}
+}
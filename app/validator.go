@@ -6,30 +6,77 @@ import (
 	"github.com/lemmego/api/db"
 	"image"
 	"io"
+	"mime/multipart"
 	"net"
 	"net/http"
+	"net/mail"
 	"net/url"
 	"os"
 	"reflect"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unicode"
 
 	"github.com/google/uuid"
 	"github.com/lemmego/api/shared"
+	"gorm.io/gorm/clause"
 )
 
 type Validator struct {
 	App
 	Errors shared.ValidationErrors
+
+	values  map[string]interface{}
+	present map[string]bool
+	rules   map[string]RuleFunc
+}
+
+// RuleFunc is a named validation rule. It receives the field value and the
+// arguments passed to VField.Rule and returns whether the value is valid and
+// the error message otherwise.
+type RuleFunc func(value interface{}, args ...any) (bool, string)
+
+var (
+	rules   = make(map[string]RuleFunc)
+	rulesMu sync.RWMutex
+)
+
+// RegisterRule makes a named rule available to every validator
+func RegisterRule(name string, fn RuleFunc) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[name] = fn
+}
+
+// RegisterRule makes a named rule available to this validator only,
+// overriding an app-wide rule of the same name
+func (v *Validator) RegisterRule(name string, fn RuleFunc) {
+	if v.rules == nil {
+		v.rules = make(map[string]RuleFunc)
+	}
+	v.rules[name] = fn
+}
+
+// rule looks up a named rule, preferring the validator's own
+func (v *Validator) rule(name string) (RuleFunc, bool) {
+	if fn, ok := v.rules[name]; ok {
+		return fn, true
+	}
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	fn, ok := rules[name]
+	return fn, ok
 }
 
 func NewValidator(app App) *Validator {
 	return &Validator{
 		App:    app,
 		Errors: make(map[string][]string),
+		values: make(map[string]interface{}),
 	}
 }
 
@@ -37,6 +84,41 @@ func (v *Validator) AddError(field, message string) {
 	v.Errors[field] = append(v.Errors[field], message)
 }
 
+// MarkPresent records which fields were present in the request input, for
+// the Sometimes rule. Context.ParseInput calls it with the submitted keys.
+func (v *Validator) MarkPresent(fields ...string) {
+	if v.present == nil {
+		v.present = make(map[string]bool)
+	}
+	for _, field := range fields {
+		v.present[field] = true
+	}
+}
+
+// Present reports whether the field was part of the input. Without
+// presence information from MarkPresent, a field counts as present when it
+// was added with a non-nil value.
+func (v *Validator) Present(field string) bool {
+	if v.present != nil {
+		return v.present[field]
+	}
+	return v.values[field] != nil
+}
+
+// AddErrors appends several messages to the given field
+func (v *Validator) AddErrors(field string, messages ...string) {
+	for _, message := range messages {
+		v.AddError(field, message)
+	}
+}
+
+// Merge appends the errors of another validation result to this validator
+func (v *Validator) Merge(other shared.ValidationErrors) {
+	for field, messages := range other {
+		v.AddErrors(field, messages...)
+	}
+}
+
 func (v *Validator) IsValid() bool {
 	return len(v.Errors) == 0
 }
@@ -54,6 +136,10 @@ func (v *Validator) ErrorsJSON() map[string][]string {
 
 // Field creates a new Field instance for chaining validation rules
 func (v *Validator) Field(name string, value interface{}) *VField {
+	if v.values == nil {
+		v.values = make(map[string]interface{})
+	}
+	v.values[name] = value
 	return &VField{
 		vee:   v,
 		name:  name,
@@ -62,9 +148,10 @@ func (v *Validator) Field(name string, value interface{}) *VField {
 }
 
 type VField struct {
-	vee   *Validator
-	name  string
-	value interface{}
+	vee     *Validator
+	name    string
+	value   interface{}
+	skipped bool
 }
 
 func (f *VField) Value() interface{} {
@@ -80,6 +167,47 @@ func (f *VField) Name() string {
 	return f.name
 }
 
+// skip discards the errors of every rule chained after it. Errors added
+// directly with Validator.AddError or Merge are kept.
+func (f *VField) skip() *VField {
+	f.skipped = true
+	return f
+}
+
+// addError records a rule failure for the field unless Sometimes or
+// Nullable skipped the rest of the chain
+func (f *VField) addError(message string) {
+	if f.skipped {
+		return
+	}
+	f.vee.AddError(f.name, message)
+}
+
+// Sometimes skips the following rules unless the field was present in the input
+func (f *VField) Sometimes() *VField {
+	if !f.vee.Present(f.name) {
+		return f.skip()
+	}
+	return f
+}
+
+// Nullable skips the following rules when the value is nil or empty
+func (f *VField) Nullable() *VField {
+	switch v := f.value.(type) {
+	case nil:
+		return f.skip()
+	case string:
+		if v == "" {
+			return f.skip()
+		}
+	default:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			return f.skip()
+		}
+	}
+	return f
+}
+
 // Required checks if the value is not empty
 func (f *VField) Required() *VField {
 	isZero := false
@@ -107,7 +235,7 @@ func (f *VField) Required() *VField {
 	}
 
 	if isZero {
-		f.vee.AddError(f.name, "This field is required")
+		f.addError("This field is required")
 	}
 	return f
 }
@@ -115,7 +243,7 @@ func (f *VField) Required() *VField {
 // Equals checks if the value is equal to the provided value
 func (f *VField) Equals(value interface{}) *VField {
 	if f.value != value {
-		f.vee.AddError(f.name, "This field must match with the provided value")
+		f.addError("This field must match with the provided value")
 	}
 	return f
 }
@@ -124,7 +252,7 @@ func (f *VField) Equals(value interface{}) *VField {
 func (f *VField) Min(min int) *VField {
 	if v, ok := f.value.(int); ok {
 		if v < min {
-			f.vee.AddError(f.name, "This field must be at least "+strconv.Itoa(min))
+			f.addError("This field must be at least " + strconv.Itoa(min))
 		}
 	}
 	return f
@@ -134,7 +262,7 @@ func (f *VField) Min(min int) *VField {
 func (f *VField) Max(max int) *VField {
 	if v, ok := f.value.(int); ok {
 		if v > max {
-			f.vee.AddError(f.name, "This field must not exceed "+strconv.Itoa(max))
+			f.addError("This field must not exceed " + strconv.Itoa(max))
 		}
 	}
 	return f
@@ -144,29 +272,99 @@ func (f *VField) Max(max int) *VField {
 func (f *VField) Between(min, max int) *VField {
 	if v, ok := f.value.(int); ok {
 		if v < min || v > max {
-			f.vee.AddError(f.name, fmt.Sprintf("This field must be between %d and %d", min, max))
+			f.addError(fmt.Sprintf("This field must be between %d and %d", min, max))
 		}
 	}
 	return f
 }
 
+// numericValue converts any int, uint or float kind to a float64
+func numericValue(value interface{}) (float64, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// Gt checks if the numeric value is greater than the bound
+func (f *VField) Gt(bound float64) *VField {
+	if v, ok := numericValue(f.value); ok && !(v > bound) {
+		f.addError("This field must be greater than " + formatNumber(bound))
+	}
+	return f
+}
+
+// Gte checks if the numeric value is greater than or equal to the bound
+func (f *VField) Gte(bound float64) *VField {
+	if v, ok := numericValue(f.value); ok && !(v >= bound) {
+		f.addError("This field must be greater than or equal to " + formatNumber(bound))
+	}
+	return f
+}
+
+// Lt checks if the numeric value is less than the bound
+func (f *VField) Lt(bound float64) *VField {
+	if v, ok := numericValue(f.value); ok && !(v < bound) {
+		f.addError("This field must be less than " + formatNumber(bound))
+	}
+	return f
+}
+
+// Lte checks if the numeric value is less than or equal to the bound
+func (f *VField) Lte(bound float64) *VField {
+	if v, ok := numericValue(f.value); ok && !(v <= bound) {
+		f.addError("This field must be less than or equal to " + formatNumber(bound))
+	}
+	return f
+}
+
 // Email checks if the value is a valid email address
 func (f *VField) Email() *VField {
 	if v, ok := f.value.(string); ok {
-		emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
-		if !emailRegex.MatchString(v) {
-			f.vee.AddError(f.name, "This field must be a valid email address")
+		if !isEmail(v) {
+			f.addError("This field must be a valid email address")
 		}
 	}
 	return f
 }
 
+// isEmail parses the address per RFC 5322, rejecting display names and
+// domains that are not dotted hostnames
+func isEmail(v string) bool {
+	addr, err := mail.ParseAddress(v)
+	if err != nil || addr.Address != v {
+		return false
+	}
+
+	domain := v[strings.LastIndex(v, "@")+1:]
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return false
+		}
+	}
+	return true
+}
+
 // Alpha checks if the value contains only alphabetic characters
 func (f *VField) Alpha() *VField {
 	if v, ok := f.value.(string); ok {
 		for _, char := range v {
 			if !unicode.IsLetter(char) {
-				f.vee.AddError(f.name, "This field must contain only alphabetic characters")
+				f.addError("This field must contain only alphabetic characters")
 				break
 			}
 		}
@@ -179,7 +377,7 @@ func (f *VField) Numeric() *VField {
 	if v, ok := f.value.(string); ok {
 		for _, char := range v {
 			if !unicode.IsDigit(char) {
-				f.vee.AddError(f.name, "This field must contain only numeric characters")
+				f.addError("This field must contain only numeric characters")
 				break
 			}
 		}
@@ -187,12 +385,156 @@ func (f *VField) Numeric() *VField {
 	return f
 }
 
+// digitString returns the string form of a string or numeric value. Floats
+// are formatted without an exponent, so JSON numbers like 1e6 count as digits.
+func digitString(value interface{}) (string, bool) {
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), true
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), true
+	}
+	return "", false
+}
+
+// digitCount returns the number of digits in the string form of value, or
+// false if it contains anything other than ASCII digits
+func digitCount(value interface{}) (int, bool) {
+	s, ok := digitString(value)
+	if !ok || s == "" {
+		return 0, false
+	}
+
+	for _, char := range s {
+		if char < '0' || char > '9' {
+			return 0, false
+		}
+	}
+	return len(s), true
+}
+
+// Digits checks if the value consists of exactly n digits. A nil value is
+// skipped; use Required to reject it.
+func (f *VField) Digits(n int) *VField {
+	if f.value == nil {
+		return f
+	}
+	if count, ok := digitCount(f.value); !ok || count != n {
+		f.addError(fmt.Sprintf("This field must be %d digits", n))
+	}
+	return f
+}
+
+// DigitsBetween checks if the value consists of min to max digits
+// (inclusive). A nil value is skipped.
+func (f *VField) DigitsBetween(min, max int) *VField {
+	if f.value == nil {
+		return f
+	}
+	if count, ok := digitCount(f.value); !ok || count < min || count > max {
+		f.addError(fmt.Sprintf("This field must be between %d and %d digits", min, max))
+	}
+	return f
+}
+
+// stripCardNumber removes the spaces and dashes commonly used to group card digits
+func stripCardNumber(s string) string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(s)
+}
+
+// luhnValid reports whether the digit string passes the Luhn checksum
+func luhnValid(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := number[i]
+		if d < '0' || d > '9' {
+			return false
+		}
+		n := int(d - '0')
+		if double {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// cardBrand returns the card brand whose issuer identification range the
+// number starts with, or "" if it matches no major brand
+func cardBrand(number string) string {
+	prefix := func(n int) int {
+		if len(number) < n {
+			return -1
+		}
+		v, _ := strconv.Atoi(number[:n])
+		return v
+	}
+
+	switch {
+	case slices.Contains([]int{5018, 5020, 5038, 5893, 6304, 6759, 6761, 6762, 6763}, prefix(4)):
+		return "maestro"
+	case number[0] == '4':
+		return "visa"
+	case prefix(2) >= 51 && prefix(2) <= 55, prefix(4) >= 2221 && prefix(4) <= 2720:
+		return "mastercard"
+	case prefix(2) == 34, prefix(2) == 37:
+		return "amex"
+	case prefix(4) == 6011, prefix(2) == 65, prefix(3) >= 644 && prefix(3) <= 649:
+		return "discover"
+	case prefix(4) >= 3528 && prefix(4) <= 3589:
+		return "jcb"
+	case prefix(3) >= 300 && prefix(3) <= 305, prefix(2) == 36, prefix(2) == 38:
+		return "diners"
+	case prefix(2) == 62:
+		return "unionpay"
+	}
+	return ""
+}
+
+// Luhn checks if the value passes the Luhn checksum, ignoring spaces and dashes
+func (f *VField) Luhn() *VField {
+	if v, ok := f.value.(string); ok {
+		if !luhnValid(stripCardNumber(v)) {
+			f.addError("This field must pass the Luhn checksum")
+		}
+	}
+	return f
+}
+
+// CreditCard checks if the value is a plausible credit card number: 12 to 19
+// digits from a known issuer range that pass the Luhn checksum
+func (f *VField) CreditCard() *VField {
+	if v, ok := f.value.(string); ok {
+		number := stripCardNumber(v)
+		if len(number) < 12 || len(number) > 19 || !luhnValid(number) || cardBrand(number) == "" {
+			f.addError("This field must be a valid credit card number")
+		}
+	}
+	return f
+}
+
 // AlphaNumeric checks if the value contains only alphanumeric characters
 func (f *VField) AlphaNumeric() *VField {
 	if v, ok := f.value.(string); ok {
 		for _, char := range v {
 			if !unicode.IsLetter(char) && !unicode.IsDigit(char) {
-				f.vee.AddError(f.name, "This field must contain only alphanumeric characters")
+				f.addError("This field must contain only alphanumeric characters")
 				break
 			}
 		}
@@ -200,12 +542,13 @@ func (f *VField) AlphaNumeric() *VField {
 	return f
 }
 
-// Date checks if the value is a valid date in the specified format
+// Date checks if the value is a valid date in the specified format. Like
+// the other date rules, dates without a zone are read in the app's time zone.
 func (f *VField) Date(layout string) *VField {
 	if v, ok := f.value.(string); ok {
-		_, err := time.Parse(layout, v)
+		_, err := ParseTime(layout, v)
 		if err != nil {
-			f.vee.AddError(f.name, "This field must be a valid date in the format "+layout)
+			f.addError("This field must be a valid date in the format " + layout)
 		}
 	}
 	return f
@@ -219,7 +562,50 @@ func (f *VField) In(validValues []string) *VField {
 				return f
 			}
 		}
-		f.vee.AddError(f.name, "This field must be one of the following: "+strings.Join(validValues, ", "))
+		f.addError("This field must be one of the following: " + strings.Join(validValues, ", "))
+	}
+	return f
+}
+
+// containsValue reports whether value equals one of values. Numbers are
+// compared by value across types, so a JSON float64 matches an int.
+func containsValue(value interface{}, values []interface{}) bool {
+	n, isNumber := numericValue(value)
+	for _, candidate := range values {
+		if isNumber {
+			if m, ok := numericValue(candidate); ok && m == n {
+				return true
+			}
+			continue
+		}
+		if candidate != nil && value != nil && reflect.TypeOf(candidate) == reflect.TypeOf(value) &&
+			reflect.TypeOf(value).Comparable() && candidate == value {
+			return true
+		}
+	}
+	return false
+}
+
+func joinValues(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// InAny checks if the value, of any comparable type, is one of the given values
+func (f *VField) InAny(values ...interface{}) *VField {
+	if f.value != nil && !containsValue(f.value, values) {
+		f.addError("This field must be one of the following: " + joinValues(values))
+	}
+	return f
+}
+
+// NotIn checks if the value, of any comparable type, is none of the given values
+func (f *VField) NotIn(values ...interface{}) *VField {
+	if f.value != nil && containsValue(f.value, values) {
+		f.addError("This field must not be one of the following: " + joinValues(values))
 	}
 	return f
 }
@@ -229,9 +615,9 @@ func (f *VField) Regex(pattern string) *VField {
 	if v, ok := f.value.(string); ok {
 		regex, err := regexp.Compile(pattern)
 		if err != nil {
-			f.vee.AddError(f.name, "Invalid regular expression pattern")
+			f.addError("Invalid regular expression pattern")
 		} else if !regex.MatchString(v) {
-			f.vee.AddError(f.name, "This field must match the pattern: "+pattern)
+			f.addError("This field must match the pattern: " + pattern)
 		}
 	}
 	return f
@@ -242,7 +628,7 @@ func (f *VField) URL() *VField {
 	if v, ok := f.value.(string); ok {
 		_, err := url.ParseRequestURI(v)
 		if err != nil {
-			f.vee.AddError(f.name, "This field must be a valid URL")
+			f.addError("This field must be a valid URL")
 		}
 	}
 	return f
@@ -253,7 +639,7 @@ func (f *VField) IP() *VField {
 	if v, ok := f.value.(string); ok {
 		ip := net.ParseIP(v)
 		if ip == nil {
-			f.vee.AddError(f.name, "This field must be a valid IP address")
+			f.addError("This field must be a valid IP address")
 		}
 	}
 	return f
@@ -264,7 +650,7 @@ func (f *VField) UUID() *VField {
 	if v, ok := f.value.(string); ok {
 		_, err := uuid.Parse(v)
 		if err != nil {
-			f.vee.AddError(f.name, "This field must be a valid UUID")
+			f.addError("This field must be a valid UUID")
 		}
 	}
 	return f
@@ -278,15 +664,15 @@ func (f *VField) Boolean() *VField {
 	case string:
 		lowercaseValue := strings.ToLower(f.value.(string))
 		if lowercaseValue != "true" && lowercaseValue != "false" {
-			f.vee.AddError(f.name, "This field must be a boolean value")
+			f.addError("This field must be a boolean value")
 		}
 	case int:
 		intValue := f.value.(int)
 		if intValue != 0 && intValue != 1 {
-			f.vee.AddError(f.name, "This field must be a boolean value")
+			f.addError("This field must be a boolean value")
 		}
 	default:
-		f.vee.AddError(f.name, "This field must be a boolean value")
+		f.addError("This field must be a boolean value")
 	}
 	return f
 }
@@ -295,8 +681,8 @@ func (f *VField) Boolean() *VField {
 func (f *VField) JSON() *VField {
 	if v, ok := f.value.(string); ok {
 		var js json.RawMessage
-		if json.Unmarshal([]byte(v), &js) != nil {
-			f.vee.AddError(f.name, "This field must be a valid JSON string")
+		if JSONUnmarshal([]byte(v), &js) != nil {
+			f.addError("This field must be a valid JSON string")
 		}
 	}
 	return f
@@ -306,7 +692,7 @@ func (f *VField) JSON() *VField {
 func (f *VField) AfterDate(afterDate time.Time) *VField {
 	if v, ok := f.value.(time.Time); ok {
 		if !v.After(afterDate) {
-			f.vee.AddError(f.name, "This field must be a date after "+afterDate.String())
+			f.addError("This field must be a date after " + afterDate.String())
 		}
 	}
 	return f
@@ -316,17 +702,97 @@ func (f *VField) AfterDate(afterDate time.Time) *VField {
 func (f *VField) BeforeDate(beforeDate time.Time) *VField {
 	if v, ok := f.value.(time.Time); ok {
 		if !v.Before(beforeDate) {
-			f.vee.AddError(f.name, "This field must be a date before "+beforeDate.String())
+			f.addError("This field must be a date before " + beforeDate.String())
 		}
 	}
 	return f
 }
 
+// dateValue returns the value as a time, parsing strings with the layout
+func dateValue(layout string, value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := ParseTime(layout, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
+
+// After checks if the date, given as a string in layout or a time.Time, is after the reference date
+func (f *VField) After(layout string, reference string) *VField {
+	ref, err := ParseTime(layout, reference)
+	if err != nil {
+		f.addError("Invalid reference date " + reference)
+		return f
+	}
+	if f.value == nil || f.value == "" {
+		return f
+	}
+	if v, ok := dateValue(layout, f.value); !ok {
+		f.addError("This field must be a valid date in the format " + layout)
+	} else if !v.After(ref) {
+		f.addError("This field must be a date after " + reference)
+	}
+	return f
+}
+
+// Before checks if the date, given as a string in layout or a time.Time, is before the reference date
+func (f *VField) Before(layout string, reference string) *VField {
+	ref, err := ParseTime(layout, reference)
+	if err != nil {
+		f.addError("Invalid reference date " + reference)
+		return f
+	}
+	if f.value == nil || f.value == "" {
+		return f
+	}
+	if v, ok := dateValue(layout, f.value); !ok {
+		f.addError("This field must be a valid date in the format " + layout)
+	} else if !v.Before(ref) {
+		f.addError("This field must be a date before " + reference)
+	}
+	return f
+}
+
+// DateAfterField checks if the date is after the date of another field,
+// which must have been added to the validator with Field beforehand. The
+// check is skipped when the other field is missing or not a valid date.
+func (f *VField) DateAfterField(layout string, field string) *VField {
+	other, ok := dateValue(layout, f.vee.values[field])
+	if !ok || f.value == nil || f.value == "" {
+		return f
+	}
+	if v, ok := dateValue(layout, f.value); !ok {
+		f.addError("This field must be a valid date in the format " + layout)
+	} else if !v.After(other) {
+		f.addError("This field must be a date after " + field)
+	}
+	return f
+}
+
+// DateBeforeField checks if the date is before the date of another field,
+// which must have been added to the validator with Field beforehand. The
+// check is skipped when the other field is missing or not a valid date.
+func (f *VField) DateBeforeField(layout string, field string) *VField {
+	other, ok := dateValue(layout, f.vee.values[field])
+	if !ok || f.value == nil || f.value == "" {
+		return f
+	}
+	if v, ok := dateValue(layout, f.value); !ok {
+		f.addError("This field must be a valid date in the format " + layout)
+	} else if !v.Before(other) {
+		f.addError("This field must be a date before " + field)
+	}
+	return f
+}
+
 // StartsWith checks if the string starts with the specified substring
 func (f *VField) StartsWith(prefix string) *VField {
 	if v, ok := f.value.(string); ok {
 		if !strings.HasPrefix(v, prefix) {
-			f.vee.AddError(f.name, "This field must start with "+prefix)
+			f.addError("This field must start with " + prefix)
 		}
 	}
 	return f
@@ -336,7 +802,7 @@ func (f *VField) StartsWith(prefix string) *VField {
 func (f *VField) EndsWith(suffix string) *VField {
 	if v, ok := f.value.(string); ok {
 		if !strings.HasSuffix(v, suffix) {
-			f.vee.AddError(f.name, "This field must end with "+suffix)
+			f.addError("This field must end with " + suffix)
 		}
 	}
 	return f
@@ -346,7 +812,7 @@ func (f *VField) EndsWith(suffix string) *VField {
 func (f *VField) Contains(substring string) *VField {
 	if v, ok := f.value.(string); ok {
 		if !strings.Contains(v, substring) {
-			f.vee.AddError(f.name, "This field must contain "+substring)
+			f.addError("This field must contain " + substring)
 		}
 	}
 	return f
@@ -357,19 +823,19 @@ func (f *VField) Dimensions(width, height int) *VField {
 	if v, ok := f.value.(string); ok {
 		file, err := os.Open(v)
 		if err != nil {
-			f.vee.AddError(f.name, "Unable to open the file")
+			f.addError("Unable to open the file")
 			return f
 		}
 		defer file.Close()
 
 		img, _, err := image.DecodeConfig(file)
 		if err != nil {
-			f.vee.AddError(f.name, "Unable to decode the image")
+			f.addError("Unable to decode the image")
 			return f
 		}
 
 		if img.Width != width || img.Height != height {
-			f.vee.AddError(f.name, fmt.Sprintf("Image dimensions must be %dx%d", width, height))
+			f.addError(fmt.Sprintf("Image dimensions must be %dx%d", width, height))
 		}
 	}
 	return f
@@ -380,7 +846,7 @@ func (f *VField) MimeTypes(allowedTypes []string) *VField {
 	if v, ok := f.value.(string); ok {
 		file, err := os.Open(v)
 		if err != nil {
-			f.vee.AddError(f.name, "Unable to open the file")
+			f.addError("Unable to open the file")
 			return f
 		}
 		defer file.Close()
@@ -388,7 +854,7 @@ func (f *VField) MimeTypes(allowedTypes []string) *VField {
 		buffer := make([]byte, 512)
 		_, err = file.Read(buffer)
 		if err != nil && err != io.EOF {
-			f.vee.AddError(f.name, "Unable to read the file")
+			f.addError("Unable to read the file")
 			return f
 		}
 
@@ -400,7 +866,60 @@ func (f *VField) MimeTypes(allowedTypes []string) *VField {
 			}
 		}
 
-		f.vee.AddError(f.name, "File type must be one of: "+strings.Join(allowedTypes, ", "))
+		f.addError("File type must be one of: " + strings.Join(allowedTypes, ", "))
+	}
+	return f
+}
+
+// fileSize returns the size of a file given by path or by an uploaded
+// multipart.FileHeader, ok being false for any other value
+func fileSize(value interface{}) (size int64, ok bool, err error) {
+	switch v := value.(type) {
+	case *multipart.FileHeader:
+		if v == nil {
+			return 0, false, nil
+		}
+		return v.Size, true, nil
+	case multipart.FileHeader:
+		return v.Size, true, nil
+	case string:
+		info, err := os.Stat(v)
+		if err != nil {
+			return 0, true, err
+		}
+		return info.Size(), true, nil
+	}
+	return 0, false, nil
+}
+
+// MaxFileSize checks if the file is at most the given number of bytes
+func (f *VField) MaxFileSize(bytes int64) *VField {
+	size, ok, err := fileSize(f.value)
+	if !ok {
+		return f
+	}
+	if err != nil {
+		f.addError("Unable to open the file")
+		return f
+	}
+	if size > bytes {
+		f.addError(fmt.Sprintf("File size must not exceed %d bytes", bytes))
+	}
+	return f
+}
+
+// MinFileSize checks if the file is at least the given number of bytes
+func (f *VField) MinFileSize(bytes int64) *VField {
+	size, ok, err := fileSize(f.value)
+	if !ok {
+		return f
+	}
+	if err != nil {
+		f.addError("Unable to open the file")
+		return f
+	}
+	if size < bytes {
+		f.addError(fmt.Sprintf("File size must be at least %d bytes", bytes))
 	}
 	return f
 }
@@ -410,7 +929,7 @@ func (f *VField) Timezone() *VField {
 	if v, ok := f.value.(string); ok {
 		_, err := time.LoadLocation(v)
 		if err != nil {
-			f.vee.AddError(f.name, "Invalid timezone")
+			f.addError("Invalid timezone")
 		}
 	}
 	return f
@@ -421,13 +940,13 @@ func (f *VField) ActiveURL() *VField {
 	if v, ok := f.value.(string); ok {
 		resp, err := http.Get(v)
 		if err != nil {
-			f.vee.AddError(f.name, "The URL is not active or reachable")
+			f.addError("The URL is not active or reachable")
 			return f
 		}
 		defer resp.Body.Close()
 
 		if resp.StatusCode != http.StatusOK {
-			f.vee.AddError(f.name, "The URL returned a non-OK status")
+			f.addError("The URL returned a non-OK status")
 		}
 	}
 	return f
@@ -438,7 +957,7 @@ func (f *VField) AlphaDash() *VField {
 	if v, ok := f.value.(string); ok {
 		re := regexp.MustCompile("^[a-zA-Z0-9-_]+$")
 		if !re.MatchString(v) {
-			f.vee.AddError(f.name, "This field may only contain alpha-numeric characters, dashes, and underscores")
+			f.addError("This field may only contain alpha-numeric characters, dashes, and underscores")
 		}
 	}
 	return f
@@ -449,7 +968,7 @@ func (f *VField) Ascii() *VField {
 	if v, ok := f.value.(string); ok {
 		for _, char := range v {
 			if char > unicode.MaxASCII {
-				f.vee.AddError(f.name, "This field may only contain ASCII characters")
+				f.addError("This field may only contain ASCII characters")
 				break
 			}
 		}
@@ -462,7 +981,7 @@ func (f *VField) MacAddress() *VField {
 	if v, ok := f.value.(string); ok {
 		_, err := net.ParseMAC(v)
 		if err != nil {
-			f.vee.AddError(f.name, "This field must be a valid MAC address")
+			f.addError("This field must be a valid MAC address")
 		}
 	}
 	return f
@@ -473,7 +992,7 @@ func (f *VField) ULID() *VField {
 	if v, ok := f.value.(string); ok {
 		re := regexp.MustCompile("^[0-9A-HJKMNP-TV-Z]{26}$")
 		if !re.MatchString(v) {
-			f.vee.AddError(f.name, "This field must be a valid ULID")
+			f.addError("This field must be a valid ULID")
 		}
 	}
 	return f
@@ -485,7 +1004,7 @@ func (f *VField) Distinct() *VField {
 		seen := make(map[interface{}]bool)
 		for _, value := range slice {
 			if seen[value] {
-				f.vee.AddError(f.name, "This field must contain only unique values")
+				f.addError("This field must contain only unique values")
 				break
 			}
 			seen[value] = true
@@ -499,18 +1018,18 @@ func (f *VField) Filled() *VField {
 	switch val := f.value.(type) {
 	case string:
 		if val == "" {
-			f.vee.AddError(f.name, "This field must be filled")
+			f.addError("This field must be filled")
 		}
 	case []interface{}:
 		if len(val) == 0 {
-			f.vee.AddError(f.name, "This field must be filled")
+			f.addError("This field must be filled")
 		}
 	case map[string]interface{}:
 		if len(val) == 0 {
-			f.vee.AddError(f.name, "This field must be filled")
+			f.addError("This field must be filled")
 		}
 	case nil:
-		f.vee.AddError(f.name, "This field must be filled")
+		f.addError("This field must be filled")
 	}
 	return f
 }
@@ -520,16 +1039,28 @@ func (f *VField) HexColor() *VField {
 	if v, ok := f.value.(string); ok {
 		re := regexp.MustCompile("^#([A-Fa-f0-9]{6}|[A-Fa-f0-9]{3})$")
 		if !re.MatchString(v) {
-			f.vee.AddError(f.name, "This field must be a valid hexadecimal color code")
+			f.addError("This field must be a valid hexadecimal color code")
 		}
 	}
 	return f
 }
 
+// identifierRegex matches a plain SQL identifier, optionally schema-qualified
+var identifierRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Unique checks that no row in table has the value in column. The table and
+// column must be plain identifiers; anything else is reported as a validation
+// error without querying. The keys of whereClauses are used as raw SQL
+// conditions and must never be derived from user input.
 func (f *VField) Unique(table string, column string, whereClauses ...map[string]interface{}) *VField {
 	var count int64
 
-	query := db.Get().DB().Table(table).Where(fmt.Sprintf("%s = ?", column), f.value)
+	if !identifierRegex.MatchString(table) || !identifierRegex.MatchString(column) {
+		f.addError("This field cannot be checked for uniqueness")
+		return f
+	}
+
+	query := db.Get().DB().Table(table).Where(clause.Eq{Column: clause.Column{Name: column}, Value: f.value})
 
 	if len(whereClauses) > 0 {
 		for key, value := range whereClauses[0] {
@@ -540,7 +1071,7 @@ func (f *VField) Unique(table string, column string, whereClauses ...map[string]
 	query.Count(&count)
 
 	if count > 0 {
-		f.vee.AddError(f.name, "This field must be unique")
+		f.addError("This field must be unique")
 	}
 
 	return f
@@ -555,12 +1086,12 @@ func (f *VField) ForEach(rules ...func(*VField) *VField) *VField {
 	}
 
 	if slice.Kind() != reflect.Slice && slice.Kind() != reflect.Array {
-		f.vee.AddError(f.name, "This field must be an array or slice")
+		f.addError("This field must be an array or slice")
 		return f
 	}
 
 	if slice.Len() == 0 {
-		f.vee.AddError(f.name, "This field cannot be empty")
+		f.addError("This field cannot be empty")
 		return f
 	}
 
@@ -579,7 +1110,83 @@ func (f *VField) ForEach(rules ...func(*VField) *VField) *VField {
 // Custom allows defining a custom validation rule
 func (f *VField) Custom(validateFunc func(v interface{}) (bool, string)) *VField {
 	if isValid, errorMessage := validateFunc(f.value); !isValid {
-		f.vee.AddError(f.name, errorMessage)
+		f.addError(errorMessage)
+	}
+	return f
+}
+
+// Rule applies a rule registered with RegisterRule by name
+func (f *VField) Rule(name string, args ...any) *VField {
+	fn, ok := f.vee.rule(name)
+	if !ok {
+		f.addError("Unknown validation rule " + name)
+		return f
+	}
+	if isValid, errorMessage := fn(f.value, args...); !isValid {
+		f.addError(errorMessage)
+	}
+	return f
+}
+
+// Slug checks if the string is a slug of lowercase letters and digits separated by single hyphens
+func (f *VField) Slug() *VField {
+	if v, ok := f.value.(string); ok {
+		re := regexp.MustCompile("^[a-z0-9]+(-[a-z0-9]+)*$")
+		if !re.MatchString(v) {
+			f.addError("This field must be a valid slug")
+		}
+	}
+	return f
+}
+
+// UsernameOptions configures the Username rule
+type UsernameOptions struct {
+	// MinLength is the minimum length, 3 if zero
+	MinLength int
+
+	// MaxLength is the maximum length, 32 if zero
+	MaxLength int
+
+	// AllowedChars lists characters allowed besides ASCII letters and
+	// digits, "_.-" if empty
+	AllowedChars string
+}
+
+// Username checks if the string is a username of the configured length that
+// starts with a letter or digit and contains only the allowed characters
+func (f *VField) Username(opts ...UsernameOptions) *VField {
+	if v, ok := f.value.(string); ok {
+		o := UsernameOptions{}
+		if len(opts) > 0 {
+			o = opts[0]
+		}
+		if o.MinLength <= 0 {
+			o.MinLength = 3
+		}
+		if o.MaxLength <= 0 {
+			o.MaxLength = 32
+		}
+		if o.AllowedChars == "" {
+			o.AllowedChars = "_.-"
+		}
+
+		if len(v) < o.MinLength || len(v) > o.MaxLength {
+			f.addError(fmt.Sprintf("This field must be between %d and %d characters", o.MinLength, o.MaxLength))
+			return f
+		}
+
+		var allowed strings.Builder
+		for _, char := range o.AllowedChars {
+			if char < unicode.MaxASCII && !unicode.IsLetter(char) && !unicode.IsDigit(char) {
+				allowed.WriteRune('\\')
+			}
+			allowed.WriteRune(char)
+		}
+
+		re := regexp.MustCompile("^[a-zA-Z0-9][a-zA-Z0-9" + allowed.String() + "]*$")
+		if !re.MatchString(v) {
+			f.addError("This field must be a valid username")
+		}
 	}
 	return f
 }
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidateStruct validates the exported fields of the struct pointed to by v
+// according to their `validate` tags, e.g. `validate:"required,email,max=255"`,
+// and returns the collected shared.ValidationErrors, or nil if all pass.
+// Fields are named by their json tag, falling back to the Go field name.
+//
+// Supported rules: required, filled, nullable, sometimes, email, alpha,
+// alpha_num, alpha_dash, ascii, numeric, boolean, url, ip, uuid, ulid, json,
+// slug, timezone, min=N, max=N (length for strings, value for numbers),
+// digits=N, date=LAYOUT, in=a|b|c, not_in=a|b|c, starts_with=S and
+// ends_with=S. Any other name is looked up in the RegisterRule registry,
+// with its arguments split on "|".
+//
+// c may be nil; when given, its request input decides which fields are
+// present for the sometimes rule.
+func ValidateStruct(c *Context, v any) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New("ValidateStruct requires a non-nil struct")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("ValidateStruct requires a struct")
+	}
+
+	var vee *Validator
+	if c != nil {
+		vee = NewValidator(c.App())
+		vee.MarkPresent(c.inputKeys()...)
+	} else {
+		vee = NewValidator(nil)
+	}
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag, ok := field.Tag.Lookup("validate")
+		if !ok || tag == "" || tag == "-" || !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			name = field.Name
+		}
+
+		f := vee.Field(name, rv.Field(i).Interface())
+		for _, rule := range strings.Split(tag, ",") {
+			ruleName, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+			if err := applyTagRule(f, ruleName, arg); err != nil {
+				return fmt.Errorf("field %s: %w", field.Name, err)
+			}
+		}
+	}
+
+	return vee.Validate()
+}
+
+// applyTagRule runs one rule of a validate tag against the field
+func applyTagRule(f *VField, name string, arg string) error {
+	intArg := func() (int, error) {
+		n, err := strconv.Atoi(arg)
+		if err != nil {
+			return 0, fmt.Errorf("rule %s needs an integer argument, got %q", name, arg)
+		}
+		return n, nil
+	}
+
+	switch name {
+	case "":
+	case "required":
+		f.Required()
+	case "filled":
+		f.Filled()
+	case "nullable":
+		f.Nullable()
+	case "sometimes":
+		f.Sometimes()
+	case "email":
+		f.Email()
+	case "alpha":
+		f.Alpha()
+	case "alpha_num":
+		f.AlphaNumeric()
+	case "alpha_dash":
+		f.AlphaDash()
+	case "ascii":
+		f.Ascii()
+	case "numeric":
+		f.Numeric()
+	case "boolean":
+		f.Boolean()
+	case "url":
+		f.URL()
+	case "ip":
+		f.IP()
+	case "uuid":
+		f.UUID()
+	case "ulid":
+		f.ULID()
+	case "json":
+		f.JSON()
+	case "slug":
+		f.Slug()
+	case "timezone":
+		f.Timezone()
+	case "min", "max":
+		n, err := intArg()
+		if err != nil {
+			return err
+		}
+		f.lengthOrValue(name, n)
+	case "digits":
+		n, err := intArg()
+		if err != nil {
+			return err
+		}
+		f.Digits(n)
+	case "date":
+		f.Date(arg)
+	case "in":
+		f.InAny(memberArgs(f.value, arg)...)
+	case "not_in":
+		f.NotIn(memberArgs(f.value, arg)...)
+	case "starts_with":
+		f.StartsWith(arg)
+	case "ends_with":
+		f.EndsWith(arg)
+	default:
+		f.Rule(name, splitTagArgs(arg)...)
+	}
+	return nil
+}
+
+// lengthOrValue applies a min or max bound to a string's length or a number's value
+func (f *VField) lengthOrValue(rule string, bound int) {
+	if s, ok := f.value.(string); ok {
+		length := utf8.RuneCountInString(s)
+		if rule == "min" && length < bound {
+			f.addError("This field must be at least " + strconv.Itoa(bound) + " characters")
+		}
+		if rule == "max" && length > bound {
+			f.addError("This field must not be longer than " + strconv.Itoa(bound) + " characters")
+		}
+		return
+	}
+
+	if rule == "min" {
+		f.Gte(float64(bound))
+	} else {
+		f.Lte(float64(bound))
+	}
+}
+
+func splitTagArgs(arg string) []any {
+	if arg == "" {
+		return nil
+	}
+	parts := strings.Split(arg, "|")
+	args := make([]any, len(parts))
+	for i, part := range parts {
+		args[i] = part
+	}
+	return args
+}
+
+// memberArgs splits the arguments of an in or not_in tag. Tag arguments are
+// always text, so for a numeric field they are parsed as numbers and
+// `in=1|2` matches the int 1.
+func memberArgs(value any, arg string) []any {
+	args := splitTagArgs(arg)
+	if _, ok := numericValue(value); !ok {
+		return args
+	}
+	for i, a := range args {
+		if n, err := strconv.ParseFloat(a.(string), 64); err == nil {
+			args[i] = n
+		}
+	}
+	return args
+}
@@ -0,0 +1,133 @@
+package app
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/lemmego/api/shared"
+)
+
+type tagSignup struct {
+	Name     string  `json:"name" validate:"required,min=2,max=20"`
+	Email    string  `json:"email" validate:"required,email"`
+	Age      int     `json:"age" validate:"min=18"`
+	Plan     string  `json:"plan" validate:"in=free|pro"`
+	Seats    int     `json:"seats" validate:"in=1|5|10"`
+	Nickname *string `json:"nickname" validate:"nullable,alpha"`
+	Internal string
+	ignored  string `validate:"required"`
+}
+
+func TestValidateStructValid(t *testing.T) {
+	in := tagSignup{Name: "john", Email: "john@example.com", Age: 30, Plan: "pro", Seats: 5}
+	if err := ValidateStruct(nil, &in); err != nil {
+		t.Fatalf("ValidateStruct() = %v", err)
+	}
+}
+
+func TestValidateStructAggregatesErrors(t *testing.T) {
+	in := tagSignup{Name: "j", Email: "", Age: 12, Plan: "gold", Seats: 3}
+
+	err := ValidateStruct(nil, &in)
+	var got shared.ValidationErrors
+	if !errors.As(err, &got) {
+		t.Fatalf("ValidateStruct() = %v, want ValidationErrors", err)
+	}
+
+	want := shared.ValidationErrors{
+		"name":  {"This field must be at least 2 characters"},
+		"email": {"This field is required", "This field must be a valid email address"},
+		"age":   {"This field must be greater than or equal to 18"},
+		"plan":  {"This field must be one of the following: free, pro"},
+		"seats": {"This field must be one of the following: 1, 5, 10"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("errors = %v, want %v", got, want)
+	}
+}
+
+func TestValidateStructFieldNames(t *testing.T) {
+	type input struct {
+		Title string `validate:"required"`
+		Body  string `json:"content,omitempty" validate:"required"`
+		Skip  string `json:"-" validate:"required"`
+	}
+
+	err := ValidateStruct(nil, input{})
+	var got shared.ValidationErrors
+	if !errors.As(err, &got) {
+		t.Fatalf("ValidateStruct() = %v", err)
+	}
+	for _, name := range []string{"Title", "content", "Skip"} {
+		if !got.Has(name) {
+			t.Errorf("no error for %q in %v", name, got)
+		}
+	}
+}
+
+func TestValidateStructInvalidTarget(t *testing.T) {
+	var nilPtr *tagSignup
+	for _, v := range []any{nilPtr, "text", 42} {
+		if err := ValidateStruct(nil, v); err == nil || errors.As(err, new(shared.ValidationErrors)) {
+			t.Errorf("ValidateStruct(%v) = %v, want a usage error", v, err)
+		}
+	}
+
+	type badArg struct {
+		Name string `validate:"max=ten"`
+	}
+	if err := ValidateStruct(nil, badArg{}); err == nil || errors.As(err, new(shared.ValidationErrors)) {
+		t.Errorf("ValidateStruct() with max=ten = %v, want a usage error", err)
+	}
+}
+
+func TestValidateStructSometimesUsesRequestInput(t *testing.T) {
+	type update struct {
+		Name  string `json:"name" validate:"sometimes,required"`
+		Email string `json:"email" validate:"sometimes,email"`
+	}
+
+	c, _ := newTestContext(jsonRequest(http.MethodPatch, "/", `{"email":"bad"}`))
+	err := ValidateStruct(c, &update{Email: "bad"})
+
+	want := shared.ValidationErrors{"email": {"This field must be a valid email address"}}
+	var got shared.ValidationErrors
+	if !errors.As(err, &got) || !reflect.DeepEqual(got, want) {
+		t.Fatalf("ValidateStruct() = %v, want %v", err, want)
+	}
+}
+
+func TestInAnyStaysStrictOutsideTags(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("seats", 1).InAny("1", "5")
+	v.Field("code", "1").InAny(1, 5)
+	if v.IsValid() {
+		t.Fatal("InAny matched a number against its string form")
+	}
+
+	v = NewValidator(nil)
+	v.Field("seats", 1).NotIn("1")
+	if !v.IsValid() {
+		t.Fatalf("NotIn rejected a number for its string form: %v", v.Errors)
+	}
+}
+
+func TestValidateStructInParsesNumbers(t *testing.T) {
+	type input struct {
+		Seats float64 `json:"seats" validate:"in=1|5"`
+		Tier  int     `json:"tier" validate:"not_in=0"`
+		Code  string  `json:"code" validate:"in=01|02"`
+	}
+
+	if err := ValidateStruct(nil, input{Seats: 5, Tier: 2, Code: "01"}); err != nil {
+		t.Fatalf("ValidateStruct() = %v", err)
+	}
+
+	err := ValidateStruct(nil, input{Seats: 2, Tier: 0, Code: "1"})
+	var got shared.ValidationErrors
+	if !errors.As(err, &got) || !got.Has("seats") || !got.Has("tier") || !got.Has("code") {
+		t.Fatalf("ValidateStruct() = %v, want seats, tier and code errors", err)
+	}
+}
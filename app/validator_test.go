@@ -0,0 +1,801 @@
+package app
+
+import (
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/api/shared"
+	"github.com/lemmego/api/utils"
+)
+
+func TestValidatorAddErrors(t *testing.T) {
+	v := NewValidator(nil)
+	v.AddErrors("email", "is required", "must be an email")
+	v.AddErrors("name")
+
+	want := shared.ValidationErrors{"email": {"is required", "must be an email"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+	if v.IsValid() {
+		t.Fatal("validator with errors reported valid")
+	}
+}
+
+func TestValidatorMergeDisjoint(t *testing.T) {
+	v := NewValidator(nil)
+	v.AddError("email", "is required")
+	v.Merge(shared.ValidationErrors{"name": {"is too short"}})
+
+	want := shared.ValidationErrors{
+		"email": {"is required"},
+		"name":  {"is too short"},
+	}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestValidatorMergeOverlapping(t *testing.T) {
+	v := NewValidator(nil)
+	v.AddError("email", "is required")
+
+	other := shared.ValidationErrors{"email": {"is already taken"}}
+	v.Merge(other)
+
+	want := shared.ValidationErrors{"email": {"is required", "is already taken"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+	if len(other["email"]) != 1 {
+		t.Fatalf("Merge modified its argument: %v", other)
+	}
+}
+
+func TestValidatorMergeEmpty(t *testing.T) {
+	v := NewValidator(nil)
+	v.Merge(nil)
+	v.Merge(shared.ValidationErrors{})
+	if !v.IsValid() || v.Validate() != nil {
+		t.Fatalf("merging nothing made the validator invalid: %v", v.Errors)
+	}
+}
+
+func TestNumericComparisonRules(t *testing.T) {
+	rules := map[string]func(*VField, float64) *VField{
+		"Gt":  (*VField).Gt,
+		"Gte": (*VField).Gte,
+		"Lt":  (*VField).Lt,
+		"Lte": (*VField).Lte,
+	}
+
+	// Whether each rule passes for a value below, equal to and above the bound
+	want := map[string][3]bool{
+		"Gt":  {false, false, true},
+		"Gte": {false, true, true},
+		"Lt":  {true, false, false},
+		"Lte": {true, true, false},
+	}
+
+	values := map[string][3]any{
+		"int":     {int(9), int(10), int(11)},
+		"int8":    {int8(9), int8(10), int8(11)},
+		"int64":   {int64(9), int64(10), int64(11)},
+		"uint":    {uint(9), uint(10), uint(11)},
+		"uint16":  {uint16(9), uint16(10), uint16(11)},
+		"float32": {float32(9.5), float32(10), float32(10.5)},
+		"float64": {9.999, 10.0, 10.001},
+	}
+
+	for name, rule := range rules {
+		for kind, cases := range values {
+			for i, value := range cases {
+				v := NewValidator(nil)
+				rule(v.Field("n", value), 10)
+				if got := v.IsValid(); got != want[name][i] {
+					t.Errorf("%s(10) with %s %v: valid = %v, want %v", name, kind, value, got, want[name][i])
+				}
+			}
+		}
+	}
+}
+
+func TestNumericComparisonMessages(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("a", 1).Gt(2.5)
+	v.Field("b", 1).Gte(2)
+	v.Field("c", 3).Lt(2)
+	v.Field("d", 3).Lte(2)
+
+	want := shared.ValidationErrors{
+		"a": {"This field must be greater than 2.5"},
+		"b": {"This field must be greater than or equal to 2"},
+		"c": {"This field must be less than 2"},
+		"d": {"This field must be less than or equal to 2"},
+	}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestNumericComparisonIgnoresNonNumeric(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("s", "5").Gt(10)
+	v.Field("nil", nil).Lt(0)
+	if !v.IsValid() {
+		t.Fatalf("non-numeric values failed comparison rules: %v", v.Errors)
+	}
+}
+
+func TestDigits(t *testing.T) {
+	tests := []struct {
+		value any
+		valid bool
+	}{
+		{"1234", true},
+		{"0042", true},
+		{1234, true},
+		{uint16(1234), true},
+		{float64(1234), true},
+		{float32(1234), true},
+		{"123", false},
+		{"12345", false},
+		{"12a4", false},
+		{"12.4", false},
+		{"-123", false},
+		{"", false},
+		{12.5, false},
+		{-123, false},
+		{true, false},
+		{[]string{"1234"}, false},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator(nil)
+		v.Field("pin", tt.value).Digits(4)
+		if v.IsValid() != tt.valid {
+			t.Errorf("Digits(4) with %#v: valid = %v, want %v", tt.value, v.IsValid(), tt.valid)
+		}
+	}
+}
+
+func TestDigitsLargeFloat(t *testing.T) {
+	// JSON decodes numbers as float64; 1e6 must not be checked as "1e+06"
+	v := NewValidator(nil)
+	v.Field("n", 1e6).Digits(7)
+	if !v.IsValid() {
+		t.Fatalf("1e6 failed Digits(7): %v", v.Errors)
+	}
+}
+
+func TestDigitsBetween(t *testing.T) {
+	tests := []struct {
+		value any
+		valid bool
+	}{
+		{"123", true},
+		{"1234", true},
+		{"12345", true},
+		{12345, true},
+		{"12", false},
+		{"123456", false},
+		{"12 34", false},
+		{"abc", false},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator(nil)
+		v.Field("zip", tt.value).DigitsBetween(3, 5)
+		if v.IsValid() != tt.valid {
+			t.Errorf("DigitsBetween(3, 5) with %#v: valid = %v, want %v", tt.value, v.IsValid(), tt.valid)
+		}
+	}
+
+	v := NewValidator(nil)
+	v.Field("zip", "1").DigitsBetween(3, 5)
+	want := shared.ValidationErrors{"zip": {"This field must be between 3 and 5 digits"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestDigitsSkipsNil(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("pin", nil).Digits(4)
+	v.Field("zip", nil).DigitsBetween(3, 5)
+	if !v.IsValid() {
+		t.Fatalf("nil values failed digit rules: %v", v.Errors)
+	}
+}
+
+func TestFileSizeRulesWithPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "upload.txt")
+	if err := os.WriteFile(path, make([]byte, 100), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		rule  func(*VField) *VField
+		valid bool
+	}{
+		{"max above", func(f *VField) *VField { return f.MaxFileSize(101) }, true},
+		{"max equal", func(f *VField) *VField { return f.MaxFileSize(100) }, true},
+		{"max below", func(f *VField) *VField { return f.MaxFileSize(99) }, false},
+		{"min below", func(f *VField) *VField { return f.MinFileSize(99) }, true},
+		{"min equal", func(f *VField) *VField { return f.MinFileSize(100) }, true},
+		{"min above", func(f *VField) *VField { return f.MinFileSize(101) }, false},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator(nil)
+		tt.rule(v.Field("file", path))
+		if v.IsValid() != tt.valid {
+			t.Errorf("%s: valid = %v, want %v (%v)", tt.name, v.IsValid(), tt.valid, v.Errors)
+		}
+	}
+}
+
+func TestFileSizeRulesWithMissingPath(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("file", filepath.Join(t.TempDir(), "missing")).MaxFileSize(10)
+
+	want := shared.ValidationErrors{"file": {"Unable to open the file"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestFileSizeRulesWithHeader(t *testing.T) {
+	// The header has no backing file, so only its Size can be consulted
+	header := &multipart.FileHeader{Filename: "upload.txt", Size: 100}
+
+	v := NewValidator(nil)
+	v.Field("ok", header).MaxFileSize(100).MinFileSize(100)
+	v.Field("ok_value", *header).MaxFileSize(100)
+	v.Field("big", header).MaxFileSize(50)
+	v.Field("small", header).MinFileSize(200)
+
+	want := shared.ValidationErrors{
+		"big":   {"File size must not exceed 50 bytes"},
+		"small": {"File size must be at least 200 bytes"},
+	}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestFileSizeRulesIgnoreOtherValues(t *testing.T) {
+	var header *multipart.FileHeader
+	v := NewValidator(nil)
+	v.Field("nil", nil).MaxFileSize(1)
+	v.Field("nil_header", header).MinFileSize(1)
+	v.Field("number", 5).MaxFileSize(1)
+	if !v.IsValid() {
+		t.Fatalf("non-file values failed size rules: %v", v.Errors)
+	}
+}
+
+func TestCreditCard(t *testing.T) {
+	valid := map[string]string{
+		"visa":         "4111111111111111",
+		"visa grouped": "4012 8888 8888 1881",
+		"mastercard":   "5555-5555-5555-4444",
+		"mastercard 2": "2223003122003222",
+		"amex":         "378282246310005",
+		"amex 2":       "3714 496353 98431",
+		"discover":     "6011111111111117",
+		"jcb":          "3530111333300000",
+		"diners":       "30569309025904",
+		"diners 38":    "38520000023237",
+		"unionpay":     "6200000000000005",
+		"maestro":      "6759649826438453",
+		"discover 2":   "6011000990139424",
+	}
+	for brand, number := range valid {
+		v := NewValidator(nil)
+		v.Field("card", number).CreditCard()
+		if !v.IsValid() {
+			t.Errorf("%s %s rejected: %v", brand, number, v.Errors)
+		}
+	}
+
+	invalid := map[string]string{
+		"bad checksum":  "4111111111111112",
+		"unknown iin":   "9111111111111110",
+		"unassigned 6x": "6000000000000007",
+		"too short":     "79927398713",
+		"too long":      "41111111111111111111",
+		"letters":       "4111a11111111111",
+		"empty":         "",
+	}
+	for reason, number := range invalid {
+		v := NewValidator(nil)
+		v.Field("card", number).CreditCard()
+		want := shared.ValidationErrors{"card": {"This field must be a valid credit card number"}}
+		if !reflect.DeepEqual(v.Errors, want) {
+			t.Errorf("%s %q: Errors = %v, want %v", reason, number, v.Errors, want)
+		}
+	}
+}
+
+func TestCardBrand(t *testing.T) {
+	tests := map[string]string{
+		"4111111111111111": "visa",
+		"5555555555554444": "mastercard",
+		"2223003122003222": "mastercard",
+		"378282246310005":  "amex",
+		"6011111111111117": "discover",
+		"6011000990139424": "discover",
+		"6445644564456445": "discover",
+		"6500000000000002": "discover",
+		"3530111333300000": "jcb",
+		"30569309025904":   "diners",
+		"6200000000000005": "unionpay",
+		"5018000000000009": "maestro",
+		"5020000000000000": "maestro",
+		"5038000000000000": "maestro",
+		"5893000000000000": "maestro",
+		"6304000000000000": "maestro",
+		"6759649826438453": "maestro",
+		"6761000000000000": "maestro",
+		"6763000000000000": "maestro",
+		"6764000000000000": "",
+		"6000000000000000": "",
+		"5000000000000000": "",
+		"5600000000000000": "",
+		"9111111111111110": "",
+		"4":                "visa",
+		"6":                "",
+	}
+	for number, want := range tests {
+		if got := cardBrand(number); got != want {
+			t.Errorf("cardBrand(%q) = %q, want %q", number, got, want)
+		}
+	}
+}
+
+func TestLuhn(t *testing.T) {
+	tests := map[string]bool{
+		"79927398713":      true,
+		"7992-7398-713":    true,
+		"9111111111111110": true,
+		"79927398710":      false,
+		"4111111111111112": false,
+		"1":                false,
+		"12a4":             false,
+	}
+	for number, want := range tests {
+		v := NewValidator(nil)
+		v.Field("n", number).Luhn()
+		if v.IsValid() != want {
+			t.Errorf("Luhn(%q) valid = %v, want %v", number, v.IsValid(), want)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	valid := []string{
+		"user@example.museum",
+		"A@B.co",
+		"first.last+tag@example.com",
+		"USER@EXAMPLE.COM",
+		"o'brien@mail.example.org",
+		"user@sub-domain.example.travel",
+	}
+	for _, email := range valid {
+		v := NewValidator(nil)
+		v.Field("email", email).Email()
+		if !v.IsValid() {
+			t.Errorf("%q rejected", email)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"plainaddress",
+		"@example.com",
+		"user@",
+		"user@localhost",
+		"user@example..com",
+		"user@-example.com",
+		"user@example-.com",
+		"user@@example.com",
+		"John <john@example.com>",
+		" user@example.com",
+		"user name@example.com",
+	}
+	for _, email := range invalid {
+		v := NewValidator(nil)
+		v.Field("email", email).Email()
+		want := shared.ValidationErrors{"email": {"This field must be a valid email address"}}
+		if !reflect.DeepEqual(v.Errors, want) {
+			t.Errorf("%q: Errors = %v, want %v", email, v.Errors, want)
+		}
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]bool{
+		"hello":        true,
+		"hello-world":  true,
+		"post-2024-v2": true,
+		"a":            true,
+		"42":           true,
+		"":             false,
+		"Hello":        false,
+		"hello--world": false,
+		"-hello":       false,
+		"hello-":       false,
+		"hello_world":  false,
+		"hello world":  false,
+		"héllo":        false,
+		"hello/world":  false,
+	}
+	for slug, want := range tests {
+		v := NewValidator(nil)
+		v.Field("slug", slug).Slug()
+		if v.IsValid() != want {
+			t.Errorf("Slug(%q) valid = %v, want %v", slug, v.IsValid(), want)
+		}
+	}
+}
+
+func TestUsername(t *testing.T) {
+	tests := map[string]bool{
+		"jane":                  true,
+		"jane_doe":              true,
+		"jane.doe-2":            true,
+		"J4ne":                  true,
+		"abc":                   true,
+		"ab":                    false,
+		"_jane":                 false,
+		".jane":                 false,
+		"jane doe":              false,
+		"jane@doe":              false,
+		"jäne":                  false,
+		strings.Repeat("a", 33): false,
+	}
+	for username, want := range tests {
+		v := NewValidator(nil)
+		v.Field("username", username).Username()
+		if v.IsValid() != want {
+			t.Errorf("Username(%q) valid = %v, want %v (%v)", username, v.IsValid(), want, v.Errors)
+		}
+	}
+}
+
+func TestUsernameOptions(t *testing.T) {
+	opts := UsernameOptions{MinLength: 5, MaxLength: 8, AllowedChars: "]-"}
+	tests := map[string]bool{
+		"jane]":     true,
+		"jane-doe":  true,
+		"jane":      false,
+		"jane_doe":  false,
+		"jane.doe":  false,
+		"jane-doe1": false,
+	}
+	for username, want := range tests {
+		v := NewValidator(nil)
+		v.Field("username", username).Username(opts)
+		if v.IsValid() != want {
+			t.Errorf("Username(%q) valid = %v, want %v (%v)", username, v.IsValid(), want, v.Errors)
+		}
+	}
+
+	v := NewValidator(nil)
+	v.Field("username", "ab").Username(opts)
+	want := shared.ValidationErrors{"username": {"This field must be between 5 and 8 characters"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestGeneratedIDsPassValidators(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		v := NewValidator(nil)
+		v.Field("uuid", utils.NewUUID()).UUID()
+		v.Field("ulid", utils.NewULID()).ULID()
+		if !v.IsValid() {
+			t.Fatalf("generated IDs failed validation: %v", v.Errors)
+		}
+	}
+}
+
+func TestInAnyAndNotIn(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  any
+		values []any
+		member bool
+	}{
+		{"int member", 2, []any{1, 2, 3}, true},
+		{"int non-member", 4, []any{1, 2, 3}, false},
+		{"string member", "draft", []any{"draft", "published"}, true},
+		{"string non-member", "archived", []any{"draft", "published"}, false},
+		{"string is case sensitive", "Draft", []any{"draft"}, false},
+		{"json float64 matches int", float64(2), []any{1, 2, 3}, true},
+		{"int64 matches uint8", int64(7), []any{uint8(7)}, true},
+		{"fractional float is not int", 2.5, []any{2, 3}, false},
+		{"bool member", true, []any{true}, true},
+		{"bool non-member", false, []any{true}, false},
+		{"empty set", 1, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := NewValidator(nil)
+			in.Field("f", tt.value).InAny(tt.values...)
+			if in.IsValid() != tt.member {
+				t.Errorf("InAny(%v) with %v: valid = %v, want %v", tt.values, tt.value, in.IsValid(), tt.member)
+			}
+
+			notIn := NewValidator(nil)
+			notIn.Field("f", tt.value).NotIn(tt.values...)
+			if notIn.IsValid() == tt.member {
+				t.Errorf("NotIn(%v) with %v: valid = %v, want %v", tt.values, tt.value, notIn.IsValid(), !tt.member)
+			}
+		})
+	}
+}
+
+func TestInAnyAndNotInMessages(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("status", 9).InAny(1, 2, 3)
+	v.Field("role", "root").NotIn("root", "admin")
+
+	want := shared.ValidationErrors{
+		"status": {"This field must be one of the following: 1, 2, 3"},
+		"role":   {"This field must not be one of the following: root, admin"},
+	}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestInAnyAndNotInSkipNil(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("a", nil).InAny(1, 2)
+	v.Field("b", nil).NotIn(nil)
+	if !v.IsValid() {
+		t.Fatalf("nil value failed membership rules: %v", v.Errors)
+	}
+}
+
+func TestAfterAndBeforeBoundaries(t *testing.T) {
+	const layout = "2006-01-02"
+	tests := []struct {
+		value  any
+		after  bool
+		before bool
+	}{
+		{"2024-06-14", false, true},
+		{"2024-06-15", false, false},
+		{"2024-06-16", true, false},
+		{time.Date(2024, 6, 15, 0, 0, 0, 1, Location()), true, false},
+		{time.Date(2024, 6, 14, 23, 59, 59, 0, Location()), false, true},
+	}
+
+	for _, tt := range tests {
+		after := NewValidator(nil)
+		after.Field("d", tt.value).After(layout, "2024-06-15")
+		if after.IsValid() != tt.after {
+			t.Errorf("After(2024-06-15) with %v: valid = %v, want %v", tt.value, after.IsValid(), tt.after)
+		}
+
+		before := NewValidator(nil)
+		before.Field("d", tt.value).Before(layout, "2024-06-15")
+		if before.IsValid() != tt.before {
+			t.Errorf("Before(2024-06-15) with %v: valid = %v, want %v", tt.value, before.IsValid(), tt.before)
+		}
+	}
+}
+
+func TestAfterAndBeforeInvalidInput(t *testing.T) {
+	const layout = "2006-01-02"
+	v := NewValidator(nil)
+	v.Field("bad_ref", "2024-06-15").After(layout, "15/06/2024")
+	v.Field("bad_value", "June 15").Before(layout, "2024-06-15")
+	v.Field("empty", "").After(layout, "2024-06-15")
+	v.Field("nil", nil).Before(layout, "2024-06-15")
+
+	want := shared.ValidationErrors{
+		"bad_ref":   {"Invalid reference date 15/06/2024"},
+		"bad_value": {"This field must be a valid date in the format 2006-01-02"},
+	}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestDateFieldComparisonBoundaries(t *testing.T) {
+	const layout = "2006-01-02"
+	tests := []struct {
+		end   string
+		after bool
+	}{
+		{"2024-06-14", false},
+		{"2024-06-15", false},
+		{"2024-06-16", true},
+	}
+
+	for _, tt := range tests {
+		v := NewValidator(nil)
+		v.Field("start", "2024-06-15")
+		v.Field("end", tt.end).DateAfterField(layout, "start")
+		if v.IsValid() != tt.after {
+			t.Errorf("end %s DateAfterField(start 2024-06-15): valid = %v, want %v", tt.end, v.IsValid(), tt.after)
+		}
+
+		v = NewValidator(nil)
+		v.Field("end", "2024-06-15")
+		v.Field("start", tt.end).DateBeforeField(layout, "end")
+		wantBefore := tt.end < "2024-06-15"
+		if v.IsValid() != wantBefore {
+			t.Errorf("start %s DateBeforeField(end 2024-06-15): valid = %v, want %v", tt.end, v.IsValid(), wantBefore)
+		}
+	}
+}
+
+func TestDateFieldComparisonSkipsMissingField(t *testing.T) {
+	const layout = "2006-01-02"
+	v := NewValidator(nil)
+	v.Field("end", "2024-06-15").DateAfterField(layout, "start")
+	v.Field("start", "not a date")
+	v.Field("until", "2024-01-01").DateAfterField(layout, "start")
+	if !v.IsValid() {
+		t.Fatalf("comparison against a missing or invalid field failed: %v", v.Errors)
+	}
+
+	v.Field("since", "2024-01-01")
+	v.Field("to", "bogus").DateAfterField(layout, "since")
+	want := shared.ValidationErrors{"to": {"This field must be a valid date in the format 2006-01-02"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestSometimes(t *testing.T) {
+	tests := []struct {
+		name    string
+		present bool
+		value   any
+		valid   bool
+	}{
+		{"absent", false, nil, true},
+		{"present and empty", true, "", false},
+		{"present and invalid", true, "not-an-email", false},
+		{"present and valid", true, "john@example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(nil)
+			if tt.present {
+				v.MarkPresent("email")
+			} else {
+				v.MarkPresent("name")
+			}
+			v.Field("email", tt.value).Sometimes().Required().Email()
+			if v.IsValid() != tt.valid {
+				t.Errorf("valid = %v, want %v (errors %v)", v.IsValid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestNullable(t *testing.T) {
+	var nilPtr *string
+	tests := []struct {
+		name  string
+		value any
+		valid bool
+	}{
+		{"absent", nil, true},
+		{"nil pointer", nilPtr, true},
+		{"present and empty", "", true},
+		{"present and invalid", "not-an-email", false},
+		{"present and valid", "john@example.com", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewValidator(nil)
+			v.Field("backup_email", tt.value).Nullable().Email()
+			if v.IsValid() != tt.valid {
+				t.Errorf("valid = %v, want %v (errors %v)", v.IsValid(), tt.valid, v.Errors)
+			}
+		})
+	}
+}
+
+func TestSkipDoesNotSwallowDirectErrors(t *testing.T) {
+	v := NewValidator(nil)
+	v.MarkPresent("name")
+	v.Field("email", nil).Sometimes().Required()
+	v.Field("phone", "").Nullable().Required()
+
+	v.AddError("email", "is already taken")
+	v.Merge(shared.ValidationErrors{"phone": {"is blocked"}})
+
+	want := shared.ValidationErrors{
+		"email": {"is already taken"},
+		"phone": {"is blocked"},
+	}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestSkipEndsWithTheChain(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("code", "").Nullable().Required()
+	v.Field("code", "").Required()
+
+	want := shared.ValidationErrors{"code": {"This field is required"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
+
+func TestRegisterRule(t *testing.T) {
+	RegisterRule("test_divisible_by", func(value any, args ...any) (bool, string) {
+		n, ok := value.(int)
+		d := args[0].(int)
+		return ok && n%d == 0, "This field must be divisible by " + strconv.Itoa(d)
+	})
+
+	v := NewValidator(nil)
+	v.Field("a", 9).Rule("test_divisible_by", 3)
+	v.Field("b", 10).Rule("test_divisible_by", 3)
+
+	want := shared.ValidationErrors{"b": {"This field must be divisible by 3"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+
+	other := NewValidator(nil)
+	other.Field("c", 8).Rule("test_divisible_by", 4)
+	if !other.IsValid() {
+		t.Errorf("app-wide rule failed on another validator: %v", other.Errors)
+	}
+}
+
+func TestValidatorRegisterRuleOverridesAppRule(t *testing.T) {
+	RegisterRule("test_even", func(value any, args ...any) (bool, string) {
+		n, _ := value.(int)
+		return n%2 == 0, "This field must be even"
+	})
+
+	v := NewValidator(nil)
+	v.RegisterRule("test_even", func(value any, args ...any) (bool, string) {
+		return true, ""
+	})
+	v.Field("n", 3).Rule("test_even")
+	if !v.IsValid() {
+		t.Errorf("validator rule did not override the app rule: %v", v.Errors)
+	}
+
+	plain := NewValidator(nil)
+	plain.Field("n", 3).Rule("test_even")
+	if plain.IsValid() {
+		t.Error("validator-local rule leaked into another validator")
+	}
+}
+
+func TestRuleUnknownAndSkipped(t *testing.T) {
+	v := NewValidator(nil)
+	v.Field("a", 1).Rule("test_no_such_rule")
+	v.Field("b", nil).Nullable().Rule("test_no_such_rule")
+
+	want := shared.ValidationErrors{"a": {"Unknown validation rule test_no_such_rule"}}
+	if !reflect.DeepEqual(v.Errors, want) {
+		t.Fatalf("Errors = %v, want %v", v.Errors, want)
+	}
+}
@@ -0,0 +1,199 @@
+// Package apptest runs requests through a lemmego application's router and
+// middleware in-process, for testing handlers without binding a port.
+//
+//	ta := apptest.NewTestApp(app.WithRoutes(routes))
+//	defer ta.Close()
+//
+//	ta.Post("/users").JSON(app.M{"name": "john"}).Do().
+//		AssertStatus(t, http.StatusCreated).
+//		AssertJSONPath(t, "name", "john")
+package apptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lemmego/api/app"
+)
+
+// TestApp serves requests in-process and keeps the cookies set by its
+// responses, so a session carries over between requests like in a browser
+type TestApp struct {
+	handler  http.Handler
+	shutDown func()
+
+	mu      sync.Mutex
+	cookies map[string]*http.Cookie
+}
+
+// NewTestApp boots a fresh application with the given options, see
+// app.TestHandler
+func NewTestApp(optFuncs ...app.OptFunc) *TestApp {
+	handler, shutDown := app.TestHandler(optFuncs...)
+	return &TestApp{
+		handler:  handler,
+		shutDown: shutDown,
+		cookies:  map[string]*http.Cookie{},
+	}
+}
+
+// Close shuts the application down
+func (ta *TestApp) Close() {
+	ta.shutDown()
+}
+
+// Handler returns the application's http.Handler
+func (ta *TestApp) Handler() http.Handler {
+	return ta.handler
+}
+
+// ClearCookies forgets the cookies collected so far, starting a new session
+func (ta *TestApp) ClearCookies() {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+	ta.cookies = map[string]*http.Cookie{}
+}
+
+// Request starts building a request with the given method and path
+func (ta *TestApp) Request(method, path string) *Request {
+	return &Request{
+		app:    ta,
+		method: method,
+		path:   path,
+		header: http.Header{},
+	}
+}
+
+func (ta *TestApp) Get(path string) *Request {
+	return ta.Request(http.MethodGet, path)
+}
+
+func (ta *TestApp) Post(path string) *Request {
+	return ta.Request(http.MethodPost, path)
+}
+
+func (ta *TestApp) Put(path string) *Request {
+	return ta.Request(http.MethodPut, path)
+}
+
+func (ta *TestApp) Patch(path string) *Request {
+	return ta.Request(http.MethodPatch, path)
+}
+
+func (ta *TestApp) Delete(path string) *Request {
+	return ta.Request(http.MethodDelete, path)
+}
+
+func (ta *TestApp) addCookies(r *http.Request) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+	for _, cookie := range ta.cookies {
+		r.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+}
+
+func (ta *TestApp) storeCookies(cookies []*http.Cookie) {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+	for _, cookie := range cookies {
+		expired := cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now()))
+		if expired || cookie.Value == "" {
+			delete(ta.cookies, cookie.Name)
+			continue
+		}
+		ta.cookies[cookie.Name] = cookie
+	}
+}
+
+// Request is a request under construction
+type Request struct {
+	app    *TestApp
+	method string
+	path   string
+	header http.Header
+	query  url.Values
+	body   io.Reader
+	err    error
+}
+
+// Header sets a request header
+func (r *Request) Header(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+// Query adds a query parameter
+func (r *Request) Query(key, value string) *Request {
+	if r.query == nil {
+		r.query = url.Values{}
+	}
+	r.query.Add(key, value)
+	return r
+}
+
+// Cookie sends a cookie with this request only
+func (r *Request) Cookie(name, value string) *Request {
+	r.header.Add("Cookie", (&http.Cookie{Name: name, Value: value}).String())
+	return r
+}
+
+// Body sets a raw body with the given content type
+func (r *Request) Body(contentType string, body io.Reader) *Request {
+	r.header.Set("Content-Type", contentType)
+	r.body = body
+	return r
+}
+
+// JSON encodes body as the JSON request body and asks for a JSON response
+func (r *Request) JSON(body any) *Request {
+	data, err := json.Marshal(body)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	r.header.Set("Accept", "application/json")
+	return r.Body("application/json", bytes.NewReader(data))
+}
+
+// Form sends values as a url-encoded form body
+func (r *Request) Form(values url.Values) *Request {
+	return r.Body("application/x-www-form-urlencoded", strings.NewReader(values.Encode()))
+}
+
+// Do serves the request through the application and records the response
+func (r *Request) Do() *Response {
+	if r.err != nil {
+		return &Response{err: r.err}
+	}
+
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+
+	req := httptest.NewRequest(r.method, target, r.body)
+	for key, values := range r.header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	r.app.addCookies(req)
+
+	rec := httptest.NewRecorder()
+	r.app.handler.ServeHTTP(rec, req)
+
+	res := &Response{Recorder: rec}
+	r.app.storeCookies(res.Cookies())
+	return res
+}
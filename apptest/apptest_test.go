@@ -0,0 +1,106 @@
+package apptest
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/session"
+)
+
+func init() {
+	app.RegisterService(func(a app.App) error {
+		a.AddService(&session.Session{SessionManager: scs.New()})
+		return nil
+	})
+}
+
+func routes(r app.Router) {
+	r.Get("/hello", func(c *app.Context) error {
+		return c.Text([]byte("hello " + c.Query("name")))
+	})
+	r.Post("/users", func(c *app.Context) error {
+		var body app.M
+		if err := c.DecodeJSON(&body); err != nil {
+			return err
+		}
+		return c.Status(http.StatusCreated).JSON(app.M{"user": body})
+	})
+	r.Post("/form", func(c *app.Context) error {
+		form, err := c.Form()
+		if err != nil {
+			return err
+		}
+		return c.Text([]byte(form["name"][0]))
+	})
+	r.Post("/login", func(c *app.Context) error {
+		c.PutSession("user", "john")
+		return c.Redirect("/me")
+	})
+	r.Get("/me", func(c *app.Context) error {
+		return c.Text([]byte("user=" + c.GetSessionString("user")))
+	})
+}
+
+func TestGet(t *testing.T) {
+	ta := NewTestApp(app.WithRoutes(routes))
+	defer ta.Close()
+
+	ta.Get("/hello").Query("name", "john").Do().
+		AssertOK(t).
+		AssertContains(t, "hello john")
+
+	ta.Get("/missing").Do().AssertStatus(t, http.StatusNotFound)
+}
+
+func TestPostJSON(t *testing.T) {
+	ta := NewTestApp(app.WithRoutes(routes))
+	defer ta.Close()
+
+	ta.Post("/users").JSON(app.M{"name": "john", "tags": []string{"a"}}).Do().
+		AssertStatus(t, http.StatusCreated).
+		AssertHeader(t, "Content-Type", "application/json").
+		AssertJSON(t, `{"user": {"name": "john", "tags": ["a"]}}`).
+		AssertJSONPath(t, "user.name", "john").
+		AssertJSONPath(t, "user.tags.0", "a")
+}
+
+func TestPostForm(t *testing.T) {
+	ta := NewTestApp(app.WithRoutes(routes))
+	defer ta.Close()
+
+	ta.Post("/form").Form(url.Values{"name": {"jane"}}).Do().
+		AssertOK(t).
+		AssertContains(t, "jane")
+}
+
+func TestJSONEncodeError(t *testing.T) {
+	ta := NewTestApp(app.WithRoutes(routes))
+	defer ta.Close()
+
+	res := ta.Post("/users").JSON(make(chan int)).Do()
+	if res.Err() == nil {
+		t.Fatal("Err() = nil, want the JSON encoding error")
+	}
+	if res.Status() != 0 {
+		t.Errorf("Status() = %d, want 0 for a request that was never sent", res.Status())
+	}
+}
+
+func TestSessionCarriesOver(t *testing.T) {
+	ta := NewTestApp(app.WithRoutes(routes))
+	defer ta.Close()
+
+	ta.Get("/me").Do().AssertContains(t, "user=")
+
+	ta.Post("/login").Do().AssertRedirect(t, "/me")
+	ta.Get("/me").Do().AssertOK(t).AssertContains(t, "user=john")
+
+	ta.ClearCookies()
+	res := ta.Get("/me").Do()
+	if res.Body() != "user=" {
+		t.Errorf("body after ClearCookies = %q, want an empty session", res.Body())
+	}
+}
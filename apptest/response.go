@@ -0,0 +1,215 @@
+package apptest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// Response is a recorded response with assertion helpers. The assertions
+// report failures through t and return the response for chaining.
+type Response struct {
+	Recorder *httptest.ResponseRecorder
+	err      error
+}
+
+// Err returns the error that prevented the request from being sent, if any
+func (r *Response) Err() error {
+	return r.err
+}
+
+// Status returns the response status code
+func (r *Response) Status() int {
+	if r.Recorder == nil {
+		return 0
+	}
+	return r.Recorder.Code
+}
+
+// Body returns the response body
+func (r *Response) Body() string {
+	if r.Recorder == nil {
+		return ""
+	}
+	return r.Recorder.Body.String()
+}
+
+// Header returns the response headers
+func (r *Response) Header() http.Header {
+	if r.Recorder == nil {
+		return http.Header{}
+	}
+	return r.Recorder.Header()
+}
+
+// Cookies returns the cookies set by the response
+func (r *Response) Cookies() []*http.Cookie {
+	if r.Recorder == nil {
+		return nil
+	}
+	return r.Recorder.Result().Cookies()
+}
+
+// DecodeJSON decodes the response body into v
+func (r *Response) DecodeJSON(v any) error {
+	return json.Unmarshal([]byte(r.Body()), v)
+}
+
+func (r *Response) ok(t testing.TB) bool {
+	t.Helper()
+	if r.err != nil {
+		t.Errorf("apptest: request failed: %v", r.err)
+		return false
+	}
+	return true
+}
+
+// AssertStatus checks the status code
+func (r *Response) AssertStatus(t testing.TB, status int) *Response {
+	t.Helper()
+	if r.ok(t) && r.Status() != status {
+		t.Errorf("apptest: expected status %d, got %d, body: %s", status, r.Status(), r.Body())
+	}
+	return r
+}
+
+// AssertOK checks for a 200 status code
+func (r *Response) AssertOK(t testing.TB) *Response {
+	t.Helper()
+	return r.AssertStatus(t, http.StatusOK)
+}
+
+// AssertRedirect checks for a redirect to location
+func (r *Response) AssertRedirect(t testing.TB, location string) *Response {
+	t.Helper()
+	if !r.ok(t) {
+		return r
+	}
+	if r.Status() < 300 || r.Status() > 399 {
+		t.Errorf("apptest: expected a redirect, got status %d", r.Status())
+	}
+	if got := r.Header().Get("Location"); got != location {
+		t.Errorf("apptest: expected redirect to %q, got %q", location, got)
+	}
+	return r
+}
+
+// AssertHeader checks a response header's value
+func (r *Response) AssertHeader(t testing.TB, key, value string) *Response {
+	t.Helper()
+	if got := r.Header().Get(key); r.ok(t) && got != value {
+		t.Errorf("apptest: expected header %s to be %q, got %q", key, value, got)
+	}
+	return r
+}
+
+// AssertContains checks that the body contains s
+func (r *Response) AssertContains(t testing.TB, s string) *Response {
+	t.Helper()
+	if r.ok(t) && !strings.Contains(r.Body(), s) {
+		t.Errorf("apptest: expected body to contain %q, got: %s", s, r.Body())
+	}
+	return r
+}
+
+// AssertJSON checks that the body is JSON equal to expected, which is
+// compared after a round trip through encoding/json, so maps, structs and
+// raw JSON strings can all be used
+func (r *Response) AssertJSON(t testing.TB, expected any) *Response {
+	t.Helper()
+	if !r.ok(t) {
+		return r
+	}
+
+	var got, want any
+	if err := r.DecodeJSON(&got); err != nil {
+		t.Errorf("apptest: response is not JSON: %v, body: %s", err, r.Body())
+		return r
+	}
+	if err := normalizeJSON(expected, &want); err != nil {
+		t.Errorf("apptest: cannot encode expected JSON: %v", err)
+		return r
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apptest: expected JSON %s, got %s", mustMarshal(want), r.Body())
+	}
+	return r
+}
+
+// AssertJSONPath checks the value at a dot-separated path of the JSON body,
+// e.g. "data.users.0.name"
+func (r *Response) AssertJSONPath(t testing.TB, path string, expected any) *Response {
+	t.Helper()
+	if !r.ok(t) {
+		return r
+	}
+
+	var doc any
+	if err := r.DecodeJSON(&doc); err != nil {
+		t.Errorf("apptest: response is not JSON: %v, body: %s", err, r.Body())
+		return r
+	}
+
+	got, found := lookupPath(doc, path)
+	if !found {
+		t.Errorf("apptest: JSON path %q not found in %s", path, r.Body())
+		return r
+	}
+
+	var want any
+	if err := normalizeJSON(expected, &want); err != nil {
+		t.Errorf("apptest: cannot encode expected JSON: %v", err)
+		return r
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("apptest: expected %q to be %s, got %s", path, mustMarshal(want), mustMarshal(got))
+	}
+	return r
+}
+
+// normalizeJSON round-trips v through encoding/json into out. Strings that
+// hold valid JSON documents are decoded as such.
+func normalizeJSON(v any, out *any) error {
+	if s, ok := v.(string); ok && json.Valid([]byte(s)) && strings.ContainsAny(strings.TrimSpace(s)[:1], "{[") {
+		return json.Unmarshal([]byte(s), out)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func lookupPath(doc any, path string) (any, bool) {
+	if path == "" {
+		return doc, true
+	}
+	for _, key := range strings.Split(path, ".") {
+		switch node := doc.(type) {
+		case map[string]any:
+			v, ok := node[key]
+			if !ok {
+				return nil, false
+			}
+			doc = v
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			doc = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return doc, true
+}
+
+func mustMarshal(v any) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	DRIVER_MEMORY = "memory" // Not shared between processes
+	DRIVER_REDIS  = "redis"
+)
+
+// Cache stores values under string keys for a limited time. A ttl of zero
+// or less keeps the value until it is forgotten.
+type Cache interface {
+	// Get returns the value stored under key and whether it was found
+	Get(key string) (any, bool)
+	// Put stores value under key for ttl
+	Put(key string, value any, ttl time.Duration) error
+	// Forget removes the value stored under key
+	Forget(key string) error
+	// Remember returns the value stored under key, or calls fn and stores
+	// its result for ttl. A failing fn stores nothing.
+	Remember(key string, ttl time.Duration, fn func() (any, error)) (any, error)
+}
+
+// Repository is the cache registered in the service container, backed by
+// the store chosen by cache.driver
+type Repository struct {
+	Cache
+}
+
+func NewRepository(store Cache) *Repository {
+	return &Repository{store}
+}
+
+var repository *Repository
+var once sync.Once
+
+func Get() *Repository {
+	return repository
+}
+
+func Set(store Cache) {
+	if repository == nil {
+		once.Do(func() {
+			repository = NewRepository(store)
+		})
+	}
+}
+
+// remember implements Remember on top of a store's Get and Put
+func remember(c Cache, key string, ttl time.Duration, fn func() (any, error)) (any, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	value, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Put(key, value, ttl); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
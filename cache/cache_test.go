@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// testStore runs the Cache contract against a store. sleep lets a fake
+// store advance its clock instead of waiting.
+func testStore(t *testing.T, c Cache, sleep func(time.Duration)) {
+	t.Run("get and put", func(t *testing.T) {
+		if _, ok := c.Get("missing"); ok {
+			t.Fatal("Get(missing) found a value")
+		}
+		if err := c.Put("name", "john", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, ok := c.Get("name"); !ok || v != "john" {
+			t.Fatalf("Get(name) = %v, %v, want john", v, ok)
+		}
+		if err := c.Put("name", "jane", time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := c.Get("name"); v != "jane" {
+			t.Fatalf("Get(name) after overwrite = %v, want jane", v)
+		}
+	})
+
+	t.Run("types survive", func(t *testing.T) {
+		if err := c.Put("count", 42, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+		if v, _ := c.Get("count"); v != 42 {
+			t.Fatalf("Get(count) = %#v, want int 42", v)
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		if err := c.Put("short", "v", 20*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Put("forever", "v", 0); err != nil {
+			t.Fatal(err)
+		}
+		sleep(30 * time.Millisecond)
+		if _, ok := c.Get("short"); ok {
+			t.Error("Get(short) found a value after its ttl")
+		}
+		if _, ok := c.Get("forever"); !ok {
+			t.Error("Get(forever) lost a value stored without a ttl")
+		}
+	})
+
+	t.Run("forget", func(t *testing.T) {
+		c.Put("gone", "v", time.Minute)
+		if err := c.Forget("gone"); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := c.Get("gone"); ok {
+			t.Error("Get(gone) found a forgotten value")
+		}
+		if err := c.Forget("never-set"); err != nil {
+			t.Errorf("Forget(never-set) = %v, want nil", err)
+		}
+	})
+
+	t.Run("remember", func(t *testing.T) {
+		calls := 0
+		compute := func() (any, error) {
+			calls++
+			return "computed", nil
+		}
+
+		for i := 0; i < 2; i++ {
+			v, err := c.Remember("report", time.Minute, compute)
+			if err != nil || v != "computed" {
+				t.Fatalf("Remember() = %v, %v, want computed", v, err)
+			}
+		}
+		if calls != 1 {
+			t.Errorf("fn called %d times, want once", calls)
+		}
+
+		boom := errors.New("boom")
+		if _, err := c.Remember("failing", time.Minute, func() (any, error) { return nil, boom }); !errors.Is(err, boom) {
+			t.Fatalf("Remember() error = %v, want boom", err)
+		}
+		if _, ok := c.Get("failing"); ok {
+			t.Error("Remember stored the result of a failing fn")
+		}
+	})
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(), time.Sleep)
+}
+
+func TestRepository(t *testing.T) {
+	store := NewMemoryStore()
+	repo := NewRepository(store)
+	repo.Put("key", "value", 0)
+
+	if v, ok := store.Get("key"); !ok || v != "value" {
+		t.Errorf("store.Get(key) = %v, %v, want the value put through the repository", v, ok)
+	}
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryItem struct {
+	value   any
+	expires time.Time
+}
+
+func (i memoryItem) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// MemoryStore keeps values in the process's memory. Expired values are
+// dropped when they are read.
+type MemoryStore struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{items: map[string]memoryItem{}}
+}
+
+func (m *MemoryStore) Get(key string) (any, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	item, ok := m.items[key]
+	if !ok {
+		return nil, false
+	}
+	if item.expired(time.Now()) {
+		delete(m.items, key)
+		return nil, false
+	}
+	return item.value, true
+}
+
+func (m *MemoryStore) Put(key string, value any, ttl time.Duration) error {
+	item := memoryItem{value: value}
+	if ttl > 0 {
+		item.expires = time.Now().Add(ttl)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = item
+	return nil
+}
+
+func (m *MemoryStore) Forget(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+func (m *MemoryStore) Remember(key string, ttl time.Duration, fn func() (any, error)) (any, error) {
+	return remember(m, key, ttl, fn)
+}
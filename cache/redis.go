@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// RedisStore keeps values in Redis, gob-encoded so they come back with
+// their Go types. Like session values, custom types stored behind an
+// interface must be registered with gob.Register.
+type RedisStore struct {
+	pool   *redis.Pool
+	prefix string
+}
+
+// NewRedisStore returns a store using connections from pool, with prefix
+// prepended to every key
+func NewRedisStore(pool *redis.Pool, prefix string) *RedisStore {
+	return &RedisStore{pool: pool, prefix: prefix}
+}
+
+type redisEntry struct {
+	Value any
+}
+
+func (r *RedisStore) Get(key string) (any, bool) {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	data, err := redis.Bytes(conn.Do("GET", r.prefix+key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry redisEntry
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&entry); err != nil {
+		return nil, false
+	}
+	return entry.Value, true
+}
+
+func (r *RedisStore) Put(key string, value any, ttl time.Duration) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(redisEntry{value}); err != nil {
+		return err
+	}
+
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	if ttl > 0 {
+		_, err := conn.Do("SET", r.prefix+key, buf.Bytes(), "PX", max(ttl.Milliseconds(), 1))
+		return err
+	}
+	_, err := conn.Do("SET", r.prefix+key, buf.Bytes())
+	return err
+}
+
+func (r *RedisStore) Forget(key string) error {
+	conn := r.pool.Get()
+	defer conn.Close()
+
+	_, err := conn.Do("DEL", r.prefix+key)
+	return err
+}
+
+func (r *RedisStore) Remember(key string, ttl time.Duration, fn func() (any, error)) (any, error) {
+	return remember(r, key, ttl, fn)
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// fakeRedis answers the GET, SET (with PX) and DEL commands RedisStore
+// sends, on a clock the test advances
+type fakeRedis struct {
+	mu      sync.Mutex
+	now     time.Time
+	data    map[string][]byte
+	expires map[string]time.Time
+}
+
+func newFakeRedis() *fakeRedis {
+	return &fakeRedis{now: time.Now(), data: map[string][]byte{}, expires: map[string]time.Time{}}
+}
+
+func (f *fakeRedis) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func (f *fakeRedis) pool() *redis.Pool {
+	return &redis.Pool{Dial: func() (redis.Conn, error) { return fakeConn{f}, nil }}
+}
+
+type fakeConn struct{ f *fakeRedis }
+
+func (c fakeConn) Close() error                      { return nil }
+func (c fakeConn) Err() error                        { return nil }
+func (c fakeConn) Send(string, ...interface{}) error { return nil }
+func (c fakeConn) Flush() error                      { return nil }
+func (c fakeConn) Receive() (interface{}, error)     { return nil, nil }
+
+func (c fakeConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	f := c.f
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch strings.ToUpper(cmd) {
+	case "":
+		return nil, nil
+	case "GET":
+		key := args[0].(string)
+		if at, ok := f.expires[key]; ok && !f.now.Before(at) {
+			delete(f.data, key)
+			delete(f.expires, key)
+		}
+		if data, ok := f.data[key]; ok {
+			return data, nil
+		}
+		return nil, nil
+	case "SET":
+		key := args[0].(string)
+		f.data[key] = args[1].([]byte)
+		delete(f.expires, key)
+		if len(args) == 4 && args[2] == "PX" {
+			ms, _ := strconv.ParseInt(fmt.Sprint(args[3]), 10, 64)
+			f.expires[key] = f.now.Add(time.Duration(ms) * time.Millisecond)
+		}
+		return "OK", nil
+	case "DEL":
+		key := args[0].(string)
+		_, ok := f.data[key]
+		delete(f.data, key)
+		delete(f.expires, key)
+		if ok {
+			return int64(1), nil
+		}
+		return int64(0), nil
+	}
+	return nil, fmt.Errorf("fake redis: unsupported command %s", cmd)
+}
+
+func TestRedisStore(t *testing.T) {
+	f := newFakeRedis()
+	testStore(t, NewRedisStore(f.pool(), "app:"), f.advance)
+}
+
+func TestRedisStorePrefix(t *testing.T) {
+	f := newFakeRedis()
+	store := NewRedisStore(f.pool(), "app:")
+
+	if err := store.Put("name", "john", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.data["app:name"]; !ok {
+		t.Errorf("stored keys = %v, want app:name", f.data)
+	}
+	if _, ok := NewRedisStore(f.pool(), "other:").Get("name"); ok {
+		t.Error("a store with another prefix read the value")
+	}
+}
@@ -3,12 +3,34 @@ package cmder
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"strings"
 
 	"github.com/manifoldco/promptui"
 )
 
+// ErrPromptInterrupted is the result error of a prompt the user cancelled
+// with Ctrl+C
+var ErrPromptInterrupted = errors.New("cmder: prompt interrupted")
+
+// ErrNoItems is the result error of a Select or MultiSelect without items
+var ErrNoItems = errors.New("cmder: no items to select from")
+
+// ExitOnInterrupt makes a cancelled prompt exit the process instead of
+// returning ErrPromptInterrupted. The application's CLI turns it on while
+// running commands.
+var ExitOnInterrupt = false
+
+// Stdin and Stdout are what prompts read answers from and draw to, the
+// terminal when nil. Setting Stdin scripts the answers, e.g. in tests; end
+// each answer with "\r", which is what a terminal sends for Enter.
+var (
+	Stdin  io.ReadCloser
+	Stdout io.WriteCloser
+)
+
 type PromptResultType int
 
 type Item struct {
@@ -29,6 +51,9 @@ type PromptResult struct {
 	ShouldAskNext bool
 	Result        interface{}
 	Error         error
+
+	// answers holds the results named with As by the earlier steps of the chain
+	answers map[string]any
 }
 
 type ValidateFunc func(string) error
@@ -53,37 +78,131 @@ func (pr *PromptResult) Fill(ptr any) Prompter {
 	return pr
 }
 
+// As records the current result under name, for FillStruct to set at the
+// end of the chain
+func (pr *PromptResult) As(name string) Prompter {
+	if pr.ShouldAskNext {
+		if pr.answers == nil {
+			pr.answers = map[string]any{}
+		}
+		pr.answers[name] = pr.Result
+	}
+	return pr
+}
+
+// FillStruct sets the fields of the struct pointed to by ptr from the results
+// recorded with As. A field is matched by its `prompt` tag, falling back to
+// its name, case-insensitively. Results that were never asked leave their
+// fields untouched.
+//
+// As and FillStruct are not part of Prompter, so assert the
+// *PromptResult each step returns:
+//
+//	var cfg Config
+//	name := cmder.Ask("Name", nil).(*cmder.PromptResult).As("name")
+//	name.Confirm("Force?", 'n').(*cmder.PromptResult).As("force").
+//		(*cmder.PromptResult).FillStruct(&cfg)
+func (pr *PromptResult) FillStruct(ptr any) Prompter {
+	rv := reflect.ValueOf(ptr)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic("FillStruct() must be called with a pointer to a struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	for name, answer := range pr.answers {
+		if answer == nil {
+			continue
+		}
+		for i := 0; i < rt.NumField(); i++ {
+			field := rt.Field(i)
+			key := field.Tag.Get("prompt")
+			if key == "" {
+				key = field.Name
+			}
+			if !field.IsExported() || !strings.EqualFold(key, name) {
+				continue
+			}
+
+			value := reflect.ValueOf(answer)
+			switch {
+			case value.Type().AssignableTo(field.Type):
+				rv.Field(i).Set(value)
+			case value.Type().ConvertibleTo(field.Type) && value.Kind() == field.Type.Kind():
+				rv.Field(i).Set(value.Convert(field.Type))
+			default:
+				panic(fmt.Sprintf("FillStruct(): cannot set %s (%s) from %T", field.Name, field.Type, answer))
+			}
+			break
+		}
+	}
+	return pr
+}
+
+// carry hands the results recorded so far to the next step of the chain
+func (pr *PromptResult) carry(next Prompter) Prompter {
+	if result, ok := next.(*PromptResult); ok && result != pr && len(pr.answers) > 0 {
+		if result.answers == nil {
+			result.answers = map[string]any{}
+		}
+		for name, answer := range pr.answers {
+			if _, ok := result.answers[name]; !ok {
+				result.answers[name] = answer
+			}
+		}
+	}
+	return next
+}
+
 func (pr *PromptResult) Ask(question string, validator ValidateFunc) Prompter {
 	if pr.ShouldAskNext {
-		return Ask(question, validator)
+		return pr.carry(Ask(question, validator))
+	}
+	return pr
+}
+
+// AskWithDefault continues the chain with AskWithDefault(). It is not part
+// of the Prompter interface, so assert a *PromptResult to use it mid-chain.
+func (pr *PromptResult) AskWithDefault(question string, defaultVal string, validator ValidateFunc) Prompter {
+	if pr.ShouldAskNext {
+		return pr.carry(AskWithDefault(question, defaultVal, validator))
+	}
+	return pr
+}
+
+// Password continues the chain with Password(). Like AskWithDefault, it is
+// only available on *PromptResult.
+func (pr *PromptResult) Password(question string, validator ValidateFunc) Prompter {
+	if pr.ShouldAskNext {
+		return pr.carry(Password(question, validator))
 	}
 	return pr
 }
 
 func (pr *PromptResult) Confirm(question string, defaultValue rune) Prompter {
 	if pr.ShouldAskNext {
-		return Confirm(question, defaultValue)
+		return pr.carry(Confirm(question, defaultValue))
 	}
 	return pr
 }
 
 func (pr *PromptResult) AskRepeat(question string, validator ValidateFunc, prompts ...func(result any) Prompter) Prompter {
 	if pr.ShouldAskNext {
-		return AskRecurring(question, validator, prompts...)
+		return pr.carry(AskRecurring(question, validator, prompts...))
 	}
 	return pr
 }
 
 func (pr *PromptResult) Select(label string, items []string) Prompter {
 	if pr.ShouldAskNext {
-		return Select(label, items)
+		return pr.carry(Select(label, items))
 	}
 	return pr
 }
 
 func (pr *PromptResult) MultiSelect(label string, allItems []*Item, selectedPos int) Prompter {
 	if pr.ShouldAskNext {
-		return MultiSelect(label, allItems, selectedPos)
+		return pr.carry(MultiSelect(label, allItems, selectedPos))
 	}
 	return pr
 }
@@ -91,7 +210,7 @@ func (pr *PromptResult) MultiSelect(label string, allItems []*Item, selectedPos
 func (pr *PromptResult) When(cb func(result interface{}) bool, thenPrompt func(prompt Prompter) Prompter) Prompter {
 	if pr.ShouldAskNext {
 		if cb(pr.Result) {
-			return thenPrompt(pr)
+			return pr.carry(thenPrompt(pr))
 		}
 	}
 	return pr
@@ -106,15 +225,70 @@ func Ask(question string, validator ValidateFunc) Prompter {
 
 	prompt := promptui.Prompt{
 		Label:    question,
+		Stdin:    Stdin,
+		Stdout:   Stdout,
 		Validate: promptui.ValidateFunc(validator),
 	}
 
 	res, err := prompt.Run()
 	if err != nil {
-		if err == promptui.ErrInterrupt {
-			os.Exit(-1)
+		return &PromptResult{Type: PromptResultTypeNormal, ShouldAskNext: false, Result: nil, Error: interrupted(err)}
+	}
+	return &PromptResult{Type: PromptResultTypeNormal, ShouldAskNext: true, Result: res, Error: nil}
+}
+
+// AskWithDefault() prompts like Ask() but returns defaultVal on empty input
+func AskWithDefault(question string, defaultVal string, validator ValidateFunc) Prompter {
+	if validator == nil {
+		validator = func(input string) error {
+			return nil
 		}
-		return &PromptResult{Type: PromptResultTypeNormal, ShouldAskNext: false, Result: nil, Error: err}
+	}
+
+	prompt := promptui.Prompt{
+		Label:   question,
+		Stdin:   Stdin,
+		Stdout:  Stdout,
+		Default: defaultVal,
+		Validate: func(input string) error {
+			if input == "" {
+				input = defaultVal
+			}
+			return validator(input)
+		},
+	}
+
+	res, err := prompt.Run()
+	if err != nil {
+		return &PromptResult{Type: PromptResultTypeNormal, ShouldAskNext: false, Result: nil, Error: interrupted(err)}
+	}
+
+	if res == "" {
+		res = defaultVal
+	}
+
+	return &PromptResult{Type: PromptResultTypeNormal, ShouldAskNext: true, Result: res, Error: nil}
+}
+
+// Password() prompts for a secret, masking the typed characters
+func Password(question string, validator ValidateFunc) Prompter {
+	if validator == nil {
+		validator = func(input string) error {
+			return nil
+		}
+	}
+
+	prompt := promptui.Prompt{
+		Label:    question,
+		Stdin:    Stdin,
+		Stdout:   Stdout,
+		Validate: promptui.ValidateFunc(validator),
+		Mask:     '*',
+	}
+
+	res, err := prompt.Run()
+	if err != nil {
+		return &PromptResult{Type: PromptResultTypeNormal, ShouldAskNext: false, Result: nil, Error: interrupted(err)}
 	}
 	return &PromptResult{Type: PromptResultTypeNormal, ShouldAskNext: true, Result: res, Error: nil}
 }
@@ -135,7 +309,9 @@ func Confirm(question string, defaultVal rune) Prompter {
 	}
 
 	q := promptui.Prompt{
-		Label: question + labelSuffix,
+		Label:  question + labelSuffix,
+		Stdin:  Stdin,
+		Stdout: Stdout,
 		Validate: func(s string) error {
 			if s != "" && s != "y" && s != "Y" && s != "n" && s != "N" {
 				return errors.New("Input must be either of y, Y, n, N")
@@ -146,31 +322,57 @@ func Confirm(question string, defaultVal rune) Prompter {
 
 	res, err := q.Run()
 	if err != nil {
-		if err == promptui.ErrInterrupt {
-			os.Exit(-1)
-		}
-		return &PromptResult{Type: PromptResultTypeBoolean, ShouldAskNext: false, Result: false, Error: err}
+		return &PromptResult{Type: PromptResultTypeBoolean, ShouldAskNext: false, Result: false, Error: interrupted(err)}
 	}
 
-	if res == "" {
-		res = string(defaultVal)
+	return &PromptResult{Type: PromptResultTypeBoolean, ShouldAskNext: true, Result: confirmed(res, defaultVal), Error: nil}
+}
+
+// ConfirmBool prompts like Confirm() and returns the answer as a plain bool
+func ConfirmBool(question string, defaultVal rune) (bool, error) {
+	pr := Confirm(question, defaultVal).(*PromptResult)
+	if pr.Error != nil {
+		return false, pr.Error
 	}
+	return pr.Result.(bool), nil
+}
 
-	return &PromptResult{Type: PromptResultTypeBoolean, ShouldAskNext: true, Result: res == "y" || res == "Y", Error: nil}
+// confirmed reports whether a Confirm() answer is a yes, an empty answer
+// taking defaultVal
+func confirmed(answer string, defaultVal rune) bool {
+	if answer == "" {
+		answer = string(defaultVal)
+	}
+	return answer == "y" || answer == "Y"
+}
+
+// interrupted maps promptui's interrupt error to ErrPromptInterrupted,
+// exiting instead when ExitOnInterrupt is set
+func interrupted(err error) error {
+	if !errors.Is(err, promptui.ErrInterrupt) {
+		return err
+	}
+	if ExitOnInterrupt {
+		os.Exit(-1)
+	}
+	return ErrPromptInterrupted
 }
 
 func Select(label string, items []string) Prompter {
+	if len(items) == 0 {
+		return &PromptResult{Type: PromptResultTypeSelect, ShouldAskNext: false, Result: nil, Error: ErrNoItems}
+	}
+
 	prompt := promptui.Select{
-		Label: label,
-		Items: items,
+		Label:  label,
+		Stdin:  Stdin,
+		Stdout: Stdout,
+		Items:  items,
 	}
 
 	_, result, err := prompt.Run()
 	if err != nil {
-		if err == promptui.ErrInterrupt {
-			os.Exit(-1)
-		}
-		return &PromptResult{Type: PromptResultTypeSelect, ShouldAskNext: false, Result: nil, Error: err}
+		return &PromptResult{Type: PromptResultTypeSelect, ShouldAskNext: false, Result: nil, Error: interrupted(err)}
 	}
 
 	return &PromptResult{Type: PromptResultTypeSelect, ShouldAskNext: true, Result: result, Error: nil}
@@ -178,6 +380,10 @@ func Select(label string, items []string) Prompter {
 
 // MultiSelect() prompts user to select one or more items in the given slice
 func MultiSelect(label string, allItems []*Item, selectedPos int) Prompter {
+	if len(allItems) == 0 {
+		return &PromptResult{Type: PromptResultTypeMultiSelect, ShouldAskNext: false, Result: nil, Error: ErrNoItems}
+	}
+
 	// Always prepend a "Done" item to the slice if it doesn't
 	// already exist.
 	var doneID = "Done ✅"
@@ -204,6 +410,8 @@ func MultiSelect(label string, allItems []*Item, selectedPos int) Prompter {
 
 	prompt := promptui.Select{
 		Label:     label,
+		Stdin:     Stdin,
+		Stdout:    Stdout,
 		Items:     allItems,
 		Templates: templates,
 		Size:      5,
@@ -214,10 +422,7 @@ func MultiSelect(label string, allItems []*Item, selectedPos int) Prompter {
 
 	selectionIdx, _, err := prompt.Run()
 	if err != nil {
-		if err == promptui.ErrInterrupt {
-			os.Exit(-1)
-		}
-		return &PromptResult{Type: PromptResultTypeMultiSelect, ShouldAskNext: false, Result: nil, Error: err}
+		return &PromptResult{Type: PromptResultTypeMultiSelect, ShouldAskNext: false, Result: nil, Error: interrupted(err)}
 	}
 
 	chosenItem := allItems[selectionIdx]
@@ -251,16 +456,15 @@ func AskRecurring(question string, validator ValidateFunc, prompts ...func(resul
 	for !inputsFinished {
 		prompt := promptui.Prompt{
 			Label:    question + " (press enter when finished)",
+			Stdin:    Stdin,
+			Stdout:   Stdout,
 			Validate: promptui.ValidateFunc(validator),
 		}
 
 		input, err := prompt.Run()
 
 		if err != nil {
-			if err == promptui.ErrInterrupt {
-				os.Exit(-1)
-			}
-			return &PromptResult{Type: PromptResultTypeRecurring, ShouldAskNext: false, Result: nil, Error: err}
+			return &PromptResult{Type: PromptResultTypeRecurring, ShouldAskNext: false, Result: nil, Error: interrupted(err)}
 		}
 
 		if input == "" {
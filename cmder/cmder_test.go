@@ -0,0 +1,244 @@
+package cmder
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// script feeds input to the next prompt and returns what it draws
+func script(t *testing.T, input string) *bytes.Buffer {
+	t.Helper()
+	var out bytes.Buffer
+	Stdin = io.NopCloser(strings.NewReader(input))
+	Stdout = nopWriteCloser{&out}
+	t.Cleanup(func() {
+		Stdin = nil
+		Stdout = nil
+	})
+	return &out
+}
+
+func result(t *testing.T, p Prompter) any {
+	t.Helper()
+	pr := p.(*PromptResult)
+	if pr.Error != nil {
+		t.Fatalf("prompt failed: %v", pr.Error)
+	}
+	return pr.Result
+}
+
+func TestAskWithDefaultFallsBack(t *testing.T) {
+	script(t, "\r")
+	if got := result(t, AskWithDefault("Name", "app", nil)); got != "app" {
+		t.Fatalf("result = %q, want the default", got)
+	}
+}
+
+func TestAskWithDefaultTakesInput(t *testing.T) {
+	script(t, "blog\r")
+	if got := result(t, AskWithDefault("Name", "app", nil)); got != "blog" {
+		t.Fatalf("result = %q, want blog", got)
+	}
+}
+
+func TestAskWithDefaultValidatesDefault(t *testing.T) {
+	var validated []string
+	validator := func(input string) error {
+		validated = append(validated, input)
+		return nil
+	}
+
+	script(t, "\r")
+	result(t, AskWithDefault("Name", "app", validator))
+	if len(validated) == 0 || validated[len(validated)-1] != "app" {
+		t.Fatalf("validator saw %q, want the default last", validated)
+	}
+}
+
+func TestPasswordMasksInput(t *testing.T) {
+	out := script(t, "hunter2\r")
+
+	if got := result(t, Password("Password", nil)); got != "hunter2" {
+		t.Fatalf("result = %q, want hunter2", got)
+	}
+	if strings.Contains(out.String(), "hunter2") {
+		t.Fatal("password was echoed")
+	}
+	if !strings.Contains(out.String(), "*******") {
+		t.Fatalf("masked input not drawn: %q", out.String())
+	}
+}
+
+func TestPromptResultChainsDefaultsAndPasswords(t *testing.T) {
+	script(t, "\r")
+	first := AskWithDefault("User", "root", nil).(*PromptResult)
+
+	script(t, "s3cret\r")
+	next := first.As("user").(*PromptResult).Password("Password", nil).(*PromptResult).As("password").(*PromptResult)
+
+	var creds struct {
+		User     string
+		Password string
+	}
+	next.FillStruct(&creds)
+	if creds.User != "root" || creds.Password != "s3cret" {
+		t.Fatalf("creds = %+v", creds)
+	}
+}
+
+func TestPromptEndOfInput(t *testing.T) {
+	script(t, "")
+	if pr := Password("Password", nil).(*PromptResult); pr.Error == nil || pr.ShouldAskNext {
+		t.Fatalf("prompt without input = %+v, want an error", pr)
+	}
+}
+
+func TestPromptValidationError(t *testing.T) {
+	errShort := errors.New("too short")
+	script(t, "ab\r")
+	pr := Ask("Name", func(s string) error {
+		if len(s) < 3 {
+			return errShort
+		}
+		return nil
+	}).(*PromptResult)
+	if pr.ShouldAskNext {
+		t.Fatalf("invalid answer accepted: %+v", pr)
+	}
+}
+
+func TestAsFillStructTwoSteps(t *testing.T) {
+	type config struct {
+		AppName string `prompt:"name"`
+		Force   bool
+		Port    string
+		Skipped string
+	}
+	cfg := config{Port: "3000", Skipped: "kept"}
+
+	script(t, "blog\r")
+	name := Ask("Name", nil).(*PromptResult).As("name")
+
+	script(t, "y\r")
+	force := name.Confirm("Force?", 'n').(*PromptResult).As("FORCE").(*PromptResult)
+	force.FillStruct(&cfg)
+
+	want := config{AppName: "blog", Force: true, Port: "3000", Skipped: "kept"}
+	if cfg != want {
+		t.Fatalf("cfg = %+v, want %+v", cfg, want)
+	}
+}
+
+func TestFillStructSkipsFailedSteps(t *testing.T) {
+	var cfg struct{ Name string }
+
+	script(t, "")
+	Ask("Name", nil).(*PromptResult).As("name").(*PromptResult).FillStruct(&cfg)
+	if cfg.Name != "" {
+		t.Fatalf("Name = %q, want it unset after a failed prompt", cfg.Name)
+	}
+}
+
+func TestFillStructPanics(t *testing.T) {
+	pr := &PromptResult{ShouldAskNext: true, Result: "blog"}
+	pr.As("port")
+
+	for name, target := range map[string]any{
+		"non-pointer":     struct{ Port int }{},
+		"pointer to int":  new(int),
+		"mismatched type": &struct{ Port int }{},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("FillStruct(%s) did not panic", name)
+				}
+			}()
+			pr.FillStruct(target)
+		}()
+	}
+}
+
+func TestPromptInterrupted(t *testing.T) {
+	prompts := map[string]func() Prompter{
+		"Ask":            func() Prompter { return Ask("Name", nil) },
+		"AskWithDefault": func() Prompter { return AskWithDefault("Name", "app", nil) },
+		"Password":       func() Prompter { return Password("Password", nil) },
+		"Confirm":        func() Prompter { return Confirm("Sure?", 'y') },
+		"Select":         func() Prompter { return Select("Driver", []string{"sqlite", "mysql"}) },
+	}
+
+	for name, prompt := range prompts {
+		t.Run(name, func(t *testing.T) {
+			script(t, "\x03")
+			pr := prompt().(*PromptResult)
+			if !errors.Is(pr.Error, ErrPromptInterrupted) || pr.ShouldAskNext {
+				t.Fatalf("Ctrl+C result = %+v, want ErrPromptInterrupted", pr)
+			}
+			if next := pr.Ask("Next", nil).(*PromptResult); next != pr {
+				t.Fatal("the chain asked the next prompt after an interrupt")
+			}
+		})
+	}
+}
+
+func TestInterruptedKeepsOtherErrors(t *testing.T) {
+	script(t, "")
+	pr := Ask("Name", nil).(*PromptResult)
+	if pr.Error == nil || errors.Is(pr.Error, ErrPromptInterrupted) {
+		t.Fatalf("end of input error = %v, want an error other than ErrPromptInterrupted", pr.Error)
+	}
+}
+
+func TestConfirmBool(t *testing.T) {
+	tests := []struct {
+		input      string
+		defaultVal rune
+		want       bool
+	}{
+		{"y\r", 'n', true},
+		{"Y\r", 'n', true},
+		{"n\r", 'y', false},
+		{"N\r", 'y', false},
+		{"\r", 'y', true},
+		{"\r", 'Y', true},
+		{"\r", 'n', false},
+		{"\r", 'N', false},
+	}
+
+	for _, tt := range tests {
+		script(t, tt.input)
+		got, err := ConfirmBool("Sure?", tt.defaultVal)
+		if err != nil || got != tt.want {
+			t.Errorf("ConfirmBool(%q, %c) = %v, %v, want %v", tt.input, tt.defaultVal, got, err, tt.want)
+		}
+	}
+}
+
+func TestConfirmBoolErrors(t *testing.T) {
+	script(t, "\x03")
+	if got, err := ConfirmBool("Sure?", 'y'); got || !errors.Is(err, ErrPromptInterrupted) {
+		t.Errorf("ConfirmBool() after Ctrl+C = %v, %v, want false, ErrPromptInterrupted", got, err)
+	}
+
+	script(t, "")
+	if got, err := ConfirmBool("Sure?", 'y'); got || err == nil {
+		t.Errorf("ConfirmBool() without input = %v, %v, want false and an error", got, err)
+	}
+}
+
+func TestConfirmRejectsInvalidDefault(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("ConfirmBool() with default 'x' did not panic")
+		}
+	}()
+	ConfirmBool("Sure?", 'x')
+}
@@ -0,0 +1,152 @@
+package cmder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output is where spinners and progress bars are drawn. When it is not a
+// terminal they only print their final line.
+var Output io.Writer = os.Stderr
+
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Spinner shows label next to a spinner until the returned stop function is
+// called. Calling stop more than once is a no-op.
+//
+//	stop := cmder.Spinner("Introspecting tables")
+//	defer stop()
+func Spinner(label string) (stop func()) {
+	out := Output
+	if !isTerminal(out) {
+		var once sync.Once
+		return func() {
+			once.Do(func() { fmt.Fprintf(out, "%s... done\n", label) })
+		}
+	}
+
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		for i := 0; ; i++ {
+			fmt.Fprintf(out, "\r%s %s", spinnerFrames[i%len(spinnerFrames)], label)
+			select {
+			case <-done:
+				fmt.Fprintf(out, "\r\033[K✔ %s\n", label)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+		})
+	}
+}
+
+// Progress is a progress bar advancing towards a fixed total
+type Progress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	tty      bool
+	total    int
+	current  int
+	finished bool
+}
+
+// ProgressBar returns a bar for total steps, drawn on Output
+//
+//	bar := cmder.ProgressBar(len(files))
+//	for _, f := range files {
+//		write(f)
+//		bar.Increment()
+//	}
+//	bar.Finish()
+func ProgressBar(total int) *Progress {
+	p := &Progress{out: Output, tty: isTerminal(Output), total: total}
+	p.draw()
+	return p
+}
+
+// Add advances the bar by n steps, stopping at the total
+func (p *Progress) Add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+	p.current = min(max(p.current+n, 0), max(p.total, 0))
+	p.drawLocked()
+}
+
+// Increment advances the bar by one step
+func (p *Progress) Increment() {
+	p.Add(1)
+}
+
+// Current returns the number of steps done
+func (p *Progress) Current() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+// Finish completes the bar and ends its line. Further calls are no-ops.
+func (p *Progress) Finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.finished {
+		return
+	}
+	p.current = max(p.total, 0)
+	p.finished = true
+	if p.tty {
+		p.drawLocked()
+		fmt.Fprintln(p.out)
+	} else {
+		fmt.Fprintln(p.out, p.line())
+	}
+}
+
+func (p *Progress) draw() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.drawLocked()
+}
+
+func (p *Progress) drawLocked() {
+	if p.tty {
+		fmt.Fprintf(p.out, "\r%s", p.line())
+	}
+}
+
+const progressWidth = 30
+
+func (p *Progress) line() string {
+	percent := 100
+	if p.total > 0 {
+		percent = p.current * 100 / p.total
+	}
+	filled := progressWidth * percent / 100
+	return fmt.Sprintf("[%s%s] %d/%d %3d%%", strings.Repeat("=", filled), strings.Repeat(" ", progressWidth-filled), p.current, p.total, percent)
+}
@@ -0,0 +1,89 @@
+package cmder
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// captureOutput points Output at a buffer, which is not a terminal
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	old := Output
+	Output = &buf
+	t.Cleanup(func() { Output = old })
+	return &buf
+}
+
+func TestSpinnerWithoutTerminal(t *testing.T) {
+	out := captureOutput(t)
+
+	stop := Spinner("Introspecting tables")
+	if out.Len() != 0 {
+		t.Fatalf("spinner drew %q before stopping, want nothing", out.String())
+	}
+	stop()
+	stop()
+
+	if got := out.String(); got != "Introspecting tables... done\n" {
+		t.Errorf("output = %q, want a single final line", got)
+	}
+}
+
+func TestProgressBarWithoutTerminal(t *testing.T) {
+	out := captureOutput(t)
+
+	bar := ProgressBar(4)
+	bar.Increment()
+	bar.Add(2)
+	if out.Len() != 0 {
+		t.Fatalf("bar drew %q before finishing, want nothing", out.String())
+	}
+	if bar.Current() != 3 {
+		t.Errorf("Current() = %d, want 3", bar.Current())
+	}
+
+	bar.Finish()
+	bar.Finish()
+	bar.Increment()
+
+	want := "[" + strings.Repeat("=", 30) + "] 4/4 100%\n"
+	if got := out.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+	if strings.ContainsAny(out.String(), "\r\033") {
+		t.Error("output contains terminal control characters")
+	}
+}
+
+func TestProgressClampsSteps(t *testing.T) {
+	captureOutput(t)
+
+	bar := ProgressBar(3)
+	bar.Add(10)
+	if bar.Current() != 3 {
+		t.Errorf("Current() after overshooting = %d, want 3", bar.Current())
+	}
+	bar.Add(-10)
+	if bar.Current() != 0 {
+		t.Errorf("Current() after going negative = %d, want 0", bar.Current())
+	}
+}
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		current, total int
+		want           string
+	}{
+		{0, 4, "[" + strings.Repeat(" ", 30) + "] 0/4   0%"},
+		{1, 4, "[" + strings.Repeat("=", 7) + strings.Repeat(" ", 23) + "] 1/4  25%"},
+		{0, 0, "[" + strings.Repeat("=", 30) + "] 0/0 100%"},
+	}
+	for _, tt := range tests {
+		p := &Progress{current: tt.current, total: tt.total}
+		if got := p.line(); got != tt.want {
+			t.Errorf("line(%d/%d) = %q, want %q", tt.current, tt.total, got, tt.want)
+		}
+	}
+}
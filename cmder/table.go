@@ -0,0 +1,110 @@
+package cmder
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// minColumnWidth is the narrowest a column gets truncated to
+const minColumnWidth = 3
+
+// Table renders rows as an aligned ASCII table on stdout. When the COLUMNS
+// environment variable gives the terminal width, the widest columns are
+// truncated to fit it.
+func Table(headers []string, rows [][]string) {
+	width, _ := strconv.Atoi(os.Getenv("COLUMNS"))
+	WriteTable(os.Stdout, width, headers, rows)
+}
+
+// WriteTable renders the table on w, truncating the widest columns to fit
+// maxWidth characters. A maxWidth of zero or less doesn't truncate.
+func WriteTable(w io.Writer, maxWidth int, headers []string, rows [][]string) error {
+	columns := len(headers)
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		return nil
+	}
+
+	widths := make([]int, columns)
+	for _, row := range append([][]string{headers}, rows...) {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+	fitWidths(widths, maxWidth)
+
+	border := "+"
+	for _, width := range widths {
+		border += strings.Repeat("-", width+2) + "+"
+	}
+
+	var b strings.Builder
+	b.WriteString(border + "\n")
+	if len(headers) > 0 {
+		writeTableRow(&b, widths, headers)
+		b.WriteString(border + "\n")
+	}
+	for _, row := range rows {
+		writeTableRow(&b, widths, row)
+	}
+	if len(rows) > 0 {
+		b.WriteString(border + "\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// fitWidths shrinks the widest column one character at a time until the
+// table fits maxWidth or every column is at minColumnWidth
+func fitWidths(widths []int, maxWidth int) {
+	if maxWidth <= 0 {
+		return
+	}
+
+	total := 1
+	for _, width := range widths {
+		total += width + 3
+	}
+
+	for total > maxWidth {
+		widest := 0
+		for i, width := range widths {
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= minColumnWidth {
+			return
+		}
+		widths[widest]--
+		total--
+	}
+}
+
+func writeTableRow(b *strings.Builder, widths []int, row []string) {
+	b.WriteString("|")
+	for i, width := range widths {
+		cell := ""
+		if i < len(row) {
+			cell = truncate(row[i], width)
+		}
+		fmt.Fprintf(b, " %s%s |", cell, strings.Repeat(" ", width-utf8.RuneCountInString(cell)))
+	}
+	b.WriteString("\n")
+}
+
+// truncate cuts s to width characters, ending it with an ellipsis
+func truncate(s string, width int) string {
+	if utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
@@ -0,0 +1,95 @@
+package cmder
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func renderTable(t *testing.T, maxWidth int, headers []string, rows [][]string) string {
+	t.Helper()
+	var b strings.Builder
+	if err := WriteTable(&b, maxWidth, headers, rows); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestWriteTableAligns(t *testing.T) {
+	got := renderTable(t, 0, []string{"Name", "Type"}, [][]string{
+		{"id", "integer"},
+		{"created_at", "timestamp"},
+		{"naïve", "text"},
+	})
+
+	want := `+------------+-----------+
+| Name       | Type      |
++------------+-----------+
+| id         | integer   |
+| created_at | timestamp |
+| naïve      | text      |
++------------+-----------+
+`
+	if got != want {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTableRaggedRows(t *testing.T) {
+	got := renderTable(t, 0, []string{"A"}, [][]string{{"1", "extra"}, {}})
+
+	want := `+---+-------+
+| A |       |
++---+-------+
+| 1 | extra |
+|   |       |
++---+-------+
+`
+	if got != want {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTableTruncatesToWidth(t *testing.T) {
+	got := renderTable(t, 24, []string{"Column", "Comment"}, [][]string{
+		{"id", "primary key of the users table"},
+	})
+
+	want := `+--------+-------------+
+| Column | Comment     |
++--------+-------------+
+| id     | primary ke… |
++--------+-------------+
+`
+	if got != want {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(got, "\n"), "\n") {
+		if n := utf8.RuneCountInString(line); n != 24 {
+			t.Errorf("line %q is %d characters, want 24", line, n)
+		}
+	}
+}
+
+func TestWriteTableKeepsMinimumWidth(t *testing.T) {
+	got := renderTable(t, 5, []string{"Name"}, [][]string{{"users"}})
+
+	want := `+-----+
+| Na… |
++-----+
+| us… |
++-----+
+`
+	if got != want {
+		t.Errorf("table =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteTableEmpty(t *testing.T) {
+	if got := renderTable(t, 0, nil, nil); got != "" {
+		t.Errorf("empty table = %q, want nothing", got)
+	}
+	if got := renderTable(t, 0, []string{"Name"}, nil); got != "+------+\n| Name |\n+------+\n" {
+		t.Errorf("headers only = %q", got)
+	}
+}
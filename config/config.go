@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 
+	"github.com/joho/godotenv"
 	_ "github.com/joho/godotenv/autoload"
 )
 
@@ -156,6 +157,75 @@ func MustEnv[T any](key string, fallback T) T {
 	return result
 }
 
+// Env retrieves an environment variable converted to T. The bool result is false
+// when the variable is absent or cannot be converted.
+func Env[T any](key string) (T, bool) {
+	var result T
+
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return result, false
+	}
+
+	switch any(result).(type) {
+	case int:
+		i, err := strconv.Atoi(value)
+		if err != nil {
+			return result, false
+		}
+		result = any(i).(T)
+	case float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return result, false
+		}
+		result = any(f).(T)
+	case bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return result, false
+		}
+		result = any(b).(T)
+	case string:
+		result = any(value).(T)
+	default:
+		return result, false
+	}
+
+	return result, true
+}
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func()
+)
+
+// OnReload registers fn to run after ReloadEnv has re-read a .env file, so
+// values derived from the environment can be refreshed. Hooks run in the
+// order they were registered.
+func OnReload(fn func()) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// ReloadEnv re-reads the given .env file, overriding variables that are
+// already set, and runs the OnReload hooks if it succeeds
+func ReloadEnv(path string) error {
+	if err := godotenv.Overload(path); err != nil {
+		return err
+	}
+
+	reloadMu.Lock()
+	hooks := append([]func(){}, reloadHooks...)
+	reloadMu.Unlock()
+
+	for _, fn := range hooks {
+		fn()
+	}
+	return nil
+}
+
 // Set sets a configuration value in the singleton instance
 func Set(key string, value interface{}) {
 	instance.Set(key, value)
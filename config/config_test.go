@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvPresent(t *testing.T) {
+	t.Setenv("CFG_TEST_INT", "42")
+	t.Setenv("CFG_TEST_FLOAT", "1.5")
+	t.Setenv("CFG_TEST_BOOL", "true")
+	t.Setenv("CFG_TEST_STRING", "hello")
+
+	if v, ok := Env[int]("CFG_TEST_INT"); !ok || v != 42 {
+		t.Errorf("Env[int] = %v, %v", v, ok)
+	}
+	if v, ok := Env[float64]("CFG_TEST_FLOAT"); !ok || v != 1.5 {
+		t.Errorf("Env[float64] = %v, %v", v, ok)
+	}
+	if v, ok := Env[bool]("CFG_TEST_BOOL"); !ok || !v {
+		t.Errorf("Env[bool] = %v, %v", v, ok)
+	}
+	if v, ok := Env[string]("CFG_TEST_STRING"); !ok || v != "hello" {
+		t.Errorf("Env[string] = %v, %v", v, ok)
+	}
+}
+
+func TestEnvAbsentOrInvalid(t *testing.T) {
+	t.Setenv("CFG_TEST_BAD_INT", "forty-two")
+
+	if v, ok := Env[int]("CFG_TEST_MISSING"); ok || v != 0 {
+		t.Errorf("missing: Env[int] = %v, %v", v, ok)
+	}
+	if v, ok := Env[int]("CFG_TEST_BAD_INT"); ok || v != 0 {
+		t.Errorf("invalid: Env[int] = %v, %v", v, ok)
+	}
+	if _, ok := Env[[]string]("CFG_TEST_BAD_INT"); ok {
+		t.Error("unsupported type reported ok")
+	}
+}
+
+func TestReloadEnv(t *testing.T) {
+	t.Setenv("CFG_TEST_RELOAD", "old")
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("CFG_TEST_RELOAD=new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReloadEnv(path); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := Env[string]("CFG_TEST_RELOAD"); v != "new" {
+		t.Fatalf("after reload = %q, want new", v)
+	}
+
+	if err := ReloadEnv(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Fatal("reloading a missing file returned no error")
+	}
+}
+
+func TestOnReload(t *testing.T) {
+	var calls []string
+	OnReload(func() { calls = append(calls, "first:"+os.Getenv("CFG_TEST_HOOK")) })
+	OnReload(func() { calls = append(calls, "second") })
+	t.Cleanup(func() { reloadHooks = nil })
+	t.Setenv("CFG_TEST_HOOK", "old")
+
+	if err := ReloadEnv(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Fatal("reloading a missing file returned no error")
+	}
+	if len(calls) != 0 {
+		t.Fatalf("hooks ran after a failed reload: %v", calls)
+	}
+
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte("CFG_TEST_HOOK=new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReloadEnv(path); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first:new", "second"}; !reflect.DeepEqual(calls, want) {
+		t.Fatalf("hook calls = %v, want %v", calls, want)
+	}
+}
@@ -131,6 +131,12 @@ func (conn *Connection) DB() *gorm.DB {
 	return conn.db
 }
 
+// WithContext returns a session bound to ctx, so queries run through it are
+// aborted when ctx is cancelled or its deadline passes
+func (conn *Connection) WithContext(ctx context.Context) *gorm.DB {
+	return conn.db.WithContext(ctx)
+}
+
 func (conn *Connection) SqlDB() *sql.DB {
 	if sqlDb, err := conn.db.DB(); err != nil {
 		panic(err)
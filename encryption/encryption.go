@@ -0,0 +1,64 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+var ErrInvalidCiphertext = errors.New("encryption: invalid ciphertext")
+
+// Encrypter encrypts and authenticates data with AES-256-GCM
+type Encrypter struct {
+	aead cipher.AEAD
+}
+
+// New returns an Encrypter for the given key. Keys of any length are
+// accepted; they are stretched to 32 bytes with SHA-256.
+func New(key []byte) (*Encrypter, error) {
+	if len(key) == 0 {
+		return nil, errors.New("encryption: key must not be empty")
+	}
+
+	sum := sha256.Sum256(key)
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Encrypter{aead: aead}, nil
+}
+
+// Encrypt returns the nonce followed by the sealed plaintext
+func (e *Encrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens data produced by Encrypt, failing with ErrInvalidCiphertext
+// if it was tampered with or encrypted with another key
+func (e *Encrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	nonceSize := e.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := e.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
@@ -0,0 +1,57 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	e, err := New([]byte("app-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext := []byte("session data")
+	ciphertext, err := e.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, plaintext) {
+		t.Fatal("ciphertext contains the plaintext")
+	}
+
+	got, err := e.Decrypt(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt() = %q, want %q", got, plaintext)
+	}
+}
+
+func TestDecryptRejectsTamperedData(t *testing.T) {
+	e, _ := New([]byte("app-key"))
+	ciphertext, _ := e.Encrypt([]byte("session data"))
+
+	tampered := append([]byte{}, ciphertext...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := e.Decrypt(tampered); err == nil {
+		t.Error("tampered ciphertext decrypted")
+	}
+
+	if _, err := e.Decrypt(ciphertext[:4]); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("short ciphertext error = %v, want ErrInvalidCiphertext", err)
+	}
+
+	other, _ := New([]byte("other-key"))
+	if _, err := other.Decrypt(ciphertext); err == nil {
+		t.Error("ciphertext decrypted with the wrong key")
+	}
+}
+
+func TestNewRejectsEmptyKey(t *testing.T) {
+	if _, err := New(nil); err == nil {
+		t.Fatal("New(nil) succeeded")
+	}
+}
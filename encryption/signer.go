@@ -0,0 +1,32 @@
+package encryption
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// Signer authenticates data with HMAC-SHA256
+type Signer struct {
+	key []byte
+}
+
+// NewSigner returns a Signer for the given key
+func NewSigner(key []byte) (*Signer, error) {
+	if len(key) == 0 {
+		return nil, errors.New("encryption: key must not be empty")
+	}
+	return &Signer{key: key}, nil
+}
+
+// Sign returns the MAC of data
+func (s *Signer) Sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// Verify reports whether signature is the MAC of data, in constant time
+func (s *Signer) Verify(data []byte, signature []byte) bool {
+	return hmac.Equal(s.Sign(data), signature)
+}
@@ -30,7 +30,11 @@ func (fm *FilesystemManager) Get(diskName ...string) (fsys.FS, error) {
 	}
 
 	if _, ok := fm.disks[name]; !ok {
-		fm.disks[name] = Resolve(name)
+		disk := Resolve(name)
+		if enabled, _ := config.Get("filesystems.metrics", false).(bool); enabled {
+			disk = WithMetrics(disk, DefaultMetrics)
+		}
+		fm.disks[name] = disk
 	}
 
 	return fm.disks[name], nil
@@ -0,0 +1,199 @@
+package fs
+
+import (
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lemmego/fsys"
+)
+
+// DefaultMetrics collects the operations of the disks resolved by the
+// FilesystemManager when filesystems.metrics is enabled
+var DefaultMetrics = NewMetrics()
+
+// OpStats holds the totals of one operation on one driver
+type OpStats struct {
+	Count    uint64
+	Errors   uint64
+	Duration time.Duration
+}
+
+type opKey struct {
+	driver string
+	op     string
+}
+
+// Metrics records the count, errors and latency of storage operations per
+// driver and operation, and exposes them in the Prometheus text format
+type Metrics struct {
+	mu  sync.Mutex
+	ops map[opKey]*OpStats
+}
+
+func NewMetrics() *Metrics {
+	return &Metrics{ops: map[opKey]*OpStats{}}
+}
+
+// Observe records one operation that took d and failed if err is non-nil
+func (m *Metrics) Observe(driver, op string, d time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := opKey{driver, op}
+	stats, ok := m.ops[key]
+	if !ok {
+		stats = &OpStats{}
+		m.ops[key] = stats
+	}
+	stats.Count++
+	stats.Duration += d
+	if err != nil {
+		stats.Errors++
+	}
+}
+
+// Stats returns the totals recorded for op on driver
+func (m *Metrics) Stats(driver, op string) OpStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if stats, ok := m.ops[opKey{driver, op}]; ok {
+		return *stats
+	}
+	return OpStats{}
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format: fs_operations_total, fs_operation_errors_total and the
+// fs_operation_duration_seconds summary, labelled by driver and operation
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	keys := make([]opKey, 0, len(m.ops))
+	stats := make(map[opKey]OpStats, len(m.ops))
+	for key, s := range m.ops {
+		keys = append(keys, key)
+		stats[key] = *s
+	}
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].driver != keys[j].driver {
+			return keys[i].driver < keys[j].driver
+		}
+		return keys[i].op < keys[j].op
+	})
+
+	metrics := []struct {
+		name, kind, help string
+		value            func(OpStats) any
+	}{
+		{"fs_operations_total", "counter", "Storage operations performed.", func(s OpStats) any { return s.Count }},
+		{"fs_operation_errors_total", "counter", "Storage operations that failed.", func(s OpStats) any { return s.Errors }},
+		{"fs_operation_duration_seconds", "summary", "Time spent in storage operations.", nil},
+		{"fs_operation_duration_seconds_sum", "", "", func(s OpStats) any { return s.Duration.Seconds() }},
+		{"fs_operation_duration_seconds_count", "", "", func(s OpStats) any { return s.Count }},
+	}
+
+	for _, metric := range metrics {
+		if metric.kind != "" {
+			if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind); err != nil {
+				return err
+			}
+		}
+		if metric.value == nil {
+			continue
+		}
+
+		for _, key := range keys {
+			if _, err := fmt.Fprintf(w, "%s{driver=%q,operation=%q} %v\n", metric.name, key.driver, key.op, metric.value(stats[key])); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Handler serves the metrics in the Prometheus text format, for mounting
+// on a scrape endpoint
+func (m *Metrics) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := m.WritePrometheus(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WithMetrics wraps disk so every operation is recorded in m under the
+// disk's driver name
+func WithMetrics(disk fsys.FS, m *Metrics) fsys.FS {
+	return &instrumentedFS{FS: disk, metrics: m}
+}
+
+type instrumentedFS struct {
+	fsys.FS
+	metrics *Metrics
+}
+
+// observe records op; err is read when the deferred call runs, so it
+// reflects the wrapped method's result
+func (f *instrumentedFS) observe(op string, start time.Time, err *error) {
+	f.metrics.Observe(f.FS.Driver(), op, time.Since(start), *err)
+}
+
+func (f *instrumentedFS) Read(path string) (rc io.ReadCloser, err error) {
+	defer f.observe("read", time.Now(), &err)
+	return f.FS.Read(path)
+}
+
+func (f *instrumentedFS) Write(path string, contents []byte) (err error) {
+	defer f.observe("write", time.Now(), &err)
+	return f.FS.Write(path, contents)
+}
+
+func (f *instrumentedFS) Delete(path string) (err error) {
+	defer f.observe("delete", time.Now(), &err)
+	return f.FS.Delete(path)
+}
+
+func (f *instrumentedFS) Exists(path string) (exists bool, err error) {
+	defer f.observe("exists", time.Now(), &err)
+	return f.FS.Exists(path)
+}
+
+func (f *instrumentedFS) Rename(oldPath, newPath string) (err error) {
+	defer f.observe("rename", time.Now(), &err)
+	return f.FS.Rename(oldPath, newPath)
+}
+
+func (f *instrumentedFS) Copy(sourcePath, destinationPath string) (err error) {
+	defer f.observe("copy", time.Now(), &err)
+	return f.FS.Copy(sourcePath, destinationPath)
+}
+
+func (f *instrumentedFS) CreateDirectory(path string) (err error) {
+	defer f.observe("create_directory", time.Now(), &err)
+	return f.FS.CreateDirectory(path)
+}
+
+func (f *instrumentedFS) GetUrl(path string) (url string, err error) {
+	defer f.observe("get_url", time.Now(), &err)
+	return f.FS.GetUrl(path)
+}
+
+func (f *instrumentedFS) Open(path string) (file *os.File, err error) {
+	defer f.observe("open", time.Now(), &err)
+	return f.FS.Open(path)
+}
+
+func (f *instrumentedFS) Upload(file multipart.File, header *multipart.FileHeader, dir string) (out *os.File, err error) {
+	defer f.observe("upload", time.Now(), &err)
+	return f.FS.Upload(file, header, dir)
+}
@@ -0,0 +1,102 @@
+package fs
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lemmego/fsys"
+)
+
+func TestMetricsCountsOperations(t *testing.T) {
+	m := NewMetrics()
+	disk := WithMetrics(fsys.NewMemoryStorage(), m)
+
+	if err := disk.Write("a.txt", []byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err := disk.Write("b.txt", []byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	rc, err := disk.Read("a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc.Close()
+	if _, err := disk.Read("missing.txt"); err == nil {
+		t.Fatal("reading a missing file returned no error")
+	}
+	if err := disk.Delete("missing.txt"); err == nil {
+		t.Fatal("deleting a missing file returned no error")
+	}
+
+	tests := []struct {
+		op            string
+		count, errors uint64
+	}{
+		{"write", 2, 0},
+		{"read", 2, 1},
+		{"delete", 1, 1},
+		{"exists", 0, 0},
+	}
+	for _, tt := range tests {
+		got := m.Stats(fsys.DRIVER_MEMORY, tt.op)
+		if got.Count != tt.count || got.Errors != tt.errors {
+			t.Errorf("%s stats = %d ops, %d errors, want %d, %d", tt.op, got.Count, got.Errors, tt.count, tt.errors)
+		}
+	}
+}
+
+func TestMetricsObserve(t *testing.T) {
+	m := NewMetrics()
+	m.Observe("s3", "write", 100*time.Millisecond, nil)
+	m.Observe("s3", "write", 300*time.Millisecond, errors.New("timeout"))
+	m.Observe("local", "write", time.Millisecond, nil)
+
+	want := OpStats{Count: 2, Errors: 1, Duration: 400 * time.Millisecond}
+	if got := m.Stats("s3", "write"); got != want {
+		t.Errorf("s3 write = %+v, want %+v", got, want)
+	}
+	if got := m.Stats("local", "write"); got.Count != 1 || got.Errors != 0 {
+		t.Errorf("local write = %+v, want one success", got)
+	}
+	if got := m.Stats("gcs", "read"); got != (OpStats{}) {
+		t.Errorf("unrecorded stats = %+v, want zero", got)
+	}
+}
+
+func TestMetricsPrometheusOutput(t *testing.T) {
+	m := NewMetrics()
+	m.Observe("s3", "write", 1500*time.Millisecond, nil)
+	m.Observe("s3", "write", 500*time.Millisecond, errors.New("timeout"))
+	m.Observe("local", "read", 0, nil)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("response = %d %q, want 200 in the Prometheus text format", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	want := `# HELP fs_operations_total Storage operations performed.
+# TYPE fs_operations_total counter
+fs_operations_total{driver="local",operation="read"} 1
+fs_operations_total{driver="s3",operation="write"} 2
+# HELP fs_operation_errors_total Storage operations that failed.
+# TYPE fs_operation_errors_total counter
+fs_operation_errors_total{driver="local",operation="read"} 0
+fs_operation_errors_total{driver="s3",operation="write"} 1
+# HELP fs_operation_duration_seconds Time spent in storage operations.
+# TYPE fs_operation_duration_seconds summary
+fs_operation_duration_seconds_sum{driver="local",operation="read"} 0
+fs_operation_duration_seconds_sum{driver="s3",operation="write"} 2
+fs_operation_duration_seconds_count{driver="local",operation="read"} 1
+fs_operation_duration_seconds_count{driver="s3",operation="write"} 2
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("metrics =\n%s\nwant\n%s", got, want)
+	}
+}
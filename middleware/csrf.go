@@ -1,16 +1,86 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
 	inertia "github.com/romsar/gonertia"
 	"net/http"
+	"os"
 	"strings"
 
 	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/encryption"
 	"github.com/lemmego/api/req"
 	"github.com/lemmego/api/utils"
 )
 
-func matchedToken(c *app.Context) bool {
+const (
+	// CSRFSession stores the token in the session, the default
+	CSRFSession = "session"
+	// CSRFCookie uses the double-submit-cookie pattern: the token lives only
+	// in the XSRF-TOKEN cookie, signed with APP_KEY, and must be echoed in the
+	// X-XSRF-TOKEN header or the _token field, so no session is needed
+	CSRFCookie = "cookie"
+)
+
+// CSRFOptions configures VerifyCSRFWith
+type CSRFOptions struct {
+	// Mode is CSRFSession or CSRFCookie, defaulting to the csrf.mode config
+	Mode string
+	// Except lists path prefixes that skip the token check, e.g. "/webhooks"
+	// for endpoints called by other services. They are relative to the
+	// router's base path, like route patterns.
+	Except []string
+	// KeepToken keeps one token for the whole session instead of rotating
+	// it after every matched request, so several tabs or concurrent AJAX
+	// requests can share it
+	KeepToken bool
+}
+
+// exempt reports whether the request falls under one of the Except
+// prefixes, which are relative to the router's base path
+func (o CSRFOptions) exempt(c *app.Context) bool {
+	for _, prefix := range o.Except {
+		if underPrefix(c.Request().URL.Path, routeURL(c, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// routeURL applies the router's base path to p
+func routeURL(c *app.Context, p string) string {
+	if r, ok := c.App().Router().(interface{ URL(string) string }); ok {
+		return r.URL(p)
+	}
+	return p
+}
+
+func underPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// isStatic reports whether the request is for the static directory mounted
+// on app.static_url
+func isStatic(c *app.Context) bool {
+	url, _ := config.Get("app.static_url", "/static/").(string)
+	return url != "" && underPrefix(c.Request().URL.Path, routeURL(c, url))
+}
+
+func (o CSRFOptions) mode() string {
+	if o.Mode != "" {
+		return o.Mode
+	}
+	if mode, _ := config.Get("csrf.mode", CSRFSession).(string); mode != "" {
+		return mode
+	}
+	return CSRFSession
+}
+
+func matchedToken(c *app.Context, rotate bool) bool {
 	sessionToken := c.GetSessionString("_token")
 	token := getTokenFromRequest(c)
 
@@ -19,7 +89,7 @@ func matchedToken(c *app.Context) bool {
 		matched = sessionToken == token
 	}
 
-	if matched {
+	if matched && rotate {
 		c.PutSession("_token", utils.GenerateRandomString(40))
 	}
 
@@ -53,9 +123,30 @@ func getTokenFromRequest(c *app.Context) string {
 	return token
 }
 
+// VerifyCSRF checks the CSRF token of every non-reading request in the
+// csrf.mode mode, rotating the session token after each match
 func VerifyCSRF(c *app.Context) error {
-	if c.IsReading() || matchedToken(c) {
-		if c.WantsHTML() && !strings.HasPrefix(c.Request().URL.Path, "/static") {
+	return verifyCSRF(c, CSRFOptions{})
+}
+
+// VerifyCSRFWith returns VerifyCSRF configured with opts
+func VerifyCSRFWith(opts CSRFOptions) app.Handler {
+	return func(c *app.Context) error {
+		return verifyCSRF(c, opts)
+	}
+}
+
+func verifyCSRF(c *app.Context, opts CSRFOptions) error {
+	if opts.exempt(c) {
+		return c.Next()
+	}
+
+	if opts.mode() == CSRFCookie {
+		return verifyCSRFCookie(c)
+	}
+
+	if c.IsReading() || matchedToken(c, !opts.KeepToken) {
+		if c.WantsHTML() && !isStatic(c) {
 			token := ""
 			if val, ok := c.GetSession("_token").(string); ok && val != "" {
 				token = val
@@ -70,17 +161,65 @@ func VerifyCSRF(c *app.Context) error {
 				i.ShareProp("csrfToken", token)
 			}
 
-			http.SetCookie(c.ResponseWriter(), &http.Cookie{
-				Name:  "XSRF-TOKEN",
-				Value: token,
-				Path:  "/",
-				//HttpOnly: true,                 // Not accessible via JavaScript
-				Secure:   true,                 // Send only over HTTPS
-				SameSite: http.SameSiteLaxMode, // Prevents the browser from sending this cookie along with cross-site requests
-			})
+			setXSRFCookie(c, token)
 		}
 		return c.Next()
 	}
 
 	return c.PageExpired()
 }
+
+// verifyCSRFCookie checks that the token sent with a non-reading request
+// matches the XSRF-TOKEN cookie, and hands out the cookie when it's missing.
+// The cookie carries an HMAC keyed with APP_KEY, so a cookie planted from a
+// sibling subdomain is rejected before the comparison.
+func verifyCSRFCookie(c *app.Context) error {
+	signer, err := encryption.NewSigner([]byte(os.Getenv("APP_KEY")))
+	if err != nil {
+		return errors.New("csrf: APP_KEY must be set to sign the XSRF-TOKEN cookie")
+	}
+
+	cookieToken := ""
+	if cookie := c.Cookie("XSRF-TOKEN"); cookie != nil && validCSRFToken(signer, cookie.Value) {
+		cookieToken = cookie.Value
+	}
+
+	if !c.IsReading() {
+		token := getTokenFromRequest(c)
+		if cookieToken == "" || token == "" || subtle.ConstantTimeCompare([]byte(cookieToken), []byte(token)) != 1 {
+			return c.PageExpired()
+		}
+	}
+
+	if cookieToken == "" {
+		cookieToken = signCSRFToken(signer, utils.GenerateRandomString(40))
+		setXSRFCookie(c, cookieToken)
+	}
+	c.Set("_token", cookieToken)
+	return c.Next()
+}
+
+// signCSRFToken appends the token's HMAC, e.g. "<token>.<signature>"
+func signCSRFToken(signer *encryption.Signer, token string) string {
+	return token + "." + base64.RawURLEncoding.EncodeToString(signer.Sign([]byte(token)))
+}
+
+func validCSRFToken(signer *encryption.Signer, value string) bool {
+	token, sig, ok := strings.Cut(value, ".")
+	if !ok || token == "" {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(sig)
+	return err == nil && signer.Verify([]byte(token), signature)
+}
+
+func setXSRFCookie(c *app.Context, token string) {
+	http.SetCookie(c.ResponseWriter(), &http.Cookie{
+		Name:  "XSRF-TOKEN",
+		Value: token,
+		Path:  "/",
+		//HttpOnly: true,                 // Not accessible via JavaScript
+		Secure:   true,                 // Send only over HTTPS
+		SameSite: http.SameSiteLaxMode, // Prevents the browser from sending this cookie along with cross-site requests
+	})
+}
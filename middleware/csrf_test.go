@@ -0,0 +1,289 @@
+package middleware
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/apptest"
+	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/encryption"
+	"github.com/lemmego/api/session"
+)
+
+func init() {
+	app.RegisterService(func(a app.App) error {
+		a.AddService(&session.Session{SessionManager: scs.New()})
+		return nil
+	})
+}
+
+// csrfApp serves "/form", "/submit", "/webhooks/stripe" and "/static/app.css"
+// behind VerifyCSRFWith(opts). Every handler echoes the token it was handed.
+func csrfApp(opts CSRFOptions, optFuncs ...app.OptFunc) *apptest.TestApp {
+	echo := func(c *app.Context) error {
+		token, _ := c.Get("_token").(string)
+		return c.Text([]byte(token))
+	}
+	routes := func(r app.Router) {
+		r.Get("/form", VerifyCSRFWith(opts), echo)
+		r.Post("/submit", VerifyCSRFWith(opts), echo)
+		r.Post("/webhooks/stripe", VerifyCSRFWith(opts), echo)
+		r.Get("/static/app.css", VerifyCSRFWith(opts), echo)
+	}
+	return apptest.NewTestApp(append(optFuncs, app.WithRoutes(routes))...)
+}
+
+// csrfToken fetches the form page and returns the token it was given
+func csrfToken(t *testing.T, ta *apptest.TestApp, path string) string {
+	t.Helper()
+	res := ta.Get(path).Header("Accept", "text/html").Do().AssertOK(t)
+	if res.Body() == "" {
+		t.Fatalf("GET %s issued no token", path)
+	}
+	return res.Body()
+}
+
+func submit(ta *apptest.TestApp, path, token string) *apptest.Response {
+	return ta.Post(path).Header("Accept", "text/html").Header("X-XSRF-TOKEN", token).Do()
+}
+
+func TestCSRFRejectsMissingOrWrongToken(t *testing.T) {
+	ta := csrfApp(CSRFOptions{})
+	defer ta.Close()
+
+	token := csrfToken(t, ta, "/form")
+	submit(ta, "/submit", "").AssertStatus(t, 419)
+	submit(ta, "/submit", token+"x").AssertStatus(t, 419)
+	submit(ta, "/submit", token).AssertOK(t)
+}
+
+func TestCSRFAcceptsFormToken(t *testing.T) {
+	ta := csrfApp(CSRFOptions{})
+	defer ta.Close()
+
+	token := csrfToken(t, ta, "/form")
+	ta.Post("/submit").Form(url.Values{"_token": {token}}).Do().AssertOK(t)
+}
+
+func TestCSRFRotatesToken(t *testing.T) {
+	ta := csrfApp(CSRFOptions{})
+	defer ta.Close()
+
+	token := csrfToken(t, ta, "/form")
+	res := submit(ta, "/submit", token).AssertOK(t)
+	if res.Body() == token {
+		t.Fatal("the token was not rotated after a matched request")
+	}
+	submit(ta, "/submit", token).AssertStatus(t, 419)
+	submit(ta, "/submit", res.Body()).AssertOK(t)
+}
+
+func TestCSRFKeepTokenIsStable(t *testing.T) {
+	ta := csrfApp(CSRFOptions{KeepToken: true})
+	defer ta.Close()
+
+	token := csrfToken(t, ta, "/form")
+	for i := 0; i < 3; i++ {
+		if res := submit(ta, "/submit", token).AssertOK(t); res.Body() != token {
+			t.Fatalf("request %d got token %q, want the same %q", i, res.Body(), token)
+		}
+	}
+	if got := csrfToken(t, ta, "/form"); got != token {
+		t.Errorf("GET after submits issued %q, want the same %q", got, token)
+	}
+}
+
+func TestCSRFExemptPaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		basePath string
+		except   []string
+		path     string
+		want     int
+	}{
+		{"exempt prefix", "", []string{"/webhooks"}, "/webhooks/stripe", http.StatusOK},
+		{"trailing slash", "", []string{"/webhooks/"}, "/webhooks/stripe", http.StatusOK},
+		{"exact path", "", []string{"/webhooks/stripe"}, "/webhooks/stripe", http.StatusOK},
+		{"prefix is not a path segment", "", []string{"/web"}, "/webhooks/stripe", 419},
+		{"other path", "", []string{"/webhooks"}, "/submit", 419},
+		{"under base path", "/api", []string{"/webhooks"}, "/api/webhooks/stripe", http.StatusOK},
+		{"other path under base path", "/api", []string{"/webhooks"}, "/api/submit", 419},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := csrfApp(CSRFOptions{Except: tt.except}, app.WithBasePath(tt.basePath))
+			defer ta.Close()
+
+			ta.Post(tt.path).Do().AssertStatus(t, tt.want)
+		})
+	}
+}
+
+func TestCSRFSkipsStaticURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		basePath  string
+		staticURL any
+		path      string
+		wantToken bool
+	}{
+		{"default static url", "", nil, "/static/app.css", false},
+		{"page", "", nil, "/form", true},
+		{"under base path", "/api", nil, "/api/static/app.css", false},
+		{"configured static url", "", "/assets/", "/static/app.css", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Set("app.static_url", tt.staticURL)
+			defer config.Set("app.static_url", nil)
+
+			ta := csrfApp(CSRFOptions{}, app.WithBasePath(tt.basePath))
+			defer ta.Close()
+
+			res := ta.Get(tt.path).Header("Accept", "text/html").Do().AssertOK(t)
+			if got := res.Body() != ""; got != tt.wantToken {
+				t.Errorf("issued a token = %v, want %v", got, tt.wantToken)
+			}
+		})
+	}
+}
+
+// xsrfCookie returns the XSRF-TOKEN cookie set by res
+func xsrfCookie(t *testing.T, res *apptest.Response) string {
+	t.Helper()
+	for _, cookie := range res.Cookies() {
+		if cookie.Name == "XSRF-TOKEN" {
+			return cookie.Value
+		}
+	}
+	t.Fatal("no XSRF-TOKEN cookie was set")
+	return ""
+}
+
+func TestCSRFCookieModeIssuesSignedToken(t *testing.T) {
+	t.Setenv("APP_KEY", "test-app-key")
+	ta := csrfApp(CSRFOptions{Mode: CSRFCookie})
+	defer ta.Close()
+
+	res := ta.Get("/form").Do().AssertOK(t)
+	cookie := xsrfCookie(t, res)
+	if res.Body() != cookie {
+		t.Errorf("_token = %q, want the cookie value %q", res.Body(), cookie)
+	}
+	token, _, ok := strings.Cut(cookie, ".")
+	if !ok || len(token) != 40 {
+		t.Fatalf("cookie = %q, want a 40 character token and its signature", cookie)
+	}
+
+	// The cookie is kept while it's valid
+	if res := ta.Get("/form").Do().AssertOK(t); res.Body() != cookie || len(res.Cookies()) != 0 {
+		t.Errorf("second GET = %q with %d cookies, want the same token and no new cookie", res.Body(), len(res.Cookies()))
+	}
+}
+
+func TestCSRFCookieMode(t *testing.T) {
+	t.Setenv("APP_KEY", "test-app-key")
+
+	signer, err := encryption.NewSigner([]byte("test-app-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherSigner, err := encryption.NewSigner([]byte("other-app-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := signCSRFToken(signer, "abc")
+	forged := signCSRFToken(otherSigner, "abc")
+
+	tests := []struct {
+		name   string
+		cookie string
+		header string
+		want   int
+	}{
+		{"matching cookie and header", valid, valid, http.StatusOK},
+		{"mismatching header", valid, signCSRFToken(signer, "abd"), 419},
+		{"header without signature", valid, "abc", 419},
+		{"missing header", valid, "", 419},
+		{"missing cookie", "", valid, 419},
+		{"unsigned cookie and header", "abc", "abc", 419},
+		{"cookie signed with another key", forged, forged, 419},
+		{"tampered signature", valid + "x", valid + "x", 419},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ta := csrfApp(CSRFOptions{Mode: CSRFCookie})
+			defer ta.Close()
+
+			req := ta.Post("/submit")
+			if tt.cookie != "" {
+				req.Cookie("XSRF-TOKEN", tt.cookie)
+			}
+			if tt.header != "" {
+				req.Header("X-XSRF-TOKEN", tt.header)
+			}
+			req.Do().AssertStatus(t, tt.want)
+		})
+	}
+}
+
+func TestCSRFCookieModeRoundTrip(t *testing.T) {
+	t.Setenv("APP_KEY", "test-app-key")
+	ta := csrfApp(CSRFOptions{Mode: CSRFCookie})
+	defer ta.Close()
+
+	token := xsrfCookie(t, ta.Get("/form").Do().AssertOK(t))
+	ta.Post("/submit").Header("X-XSRF-TOKEN", token).Do().AssertOK(t)
+	ta.Post("/submit").Form(url.Values{"_token": {token}}).Do().AssertOK(t)
+	ta.Post("/submit").Do().AssertStatus(t, 419)
+}
+
+func TestCSRFCookieModeRequiresAppKey(t *testing.T) {
+	t.Setenv("APP_KEY", "")
+	ta := csrfApp(CSRFOptions{Mode: CSRFCookie})
+	defer ta.Close()
+
+	res := ta.Get("/form").Do()
+	if res.Status() != http.StatusInternalServerError {
+		t.Errorf("status = %d without APP_KEY, want 500", res.Status())
+	}
+}
+
+var tokenField = regexp.MustCompile(`name="_token" value="([^"]*)"`)
+
+func TestCSRFRenderedFormPostsBack(t *testing.T) {
+	t.Setenv("APP_KEY", "test-app-key")
+
+	for _, mode := range []string{CSRFSession, CSRFCookie} {
+		t.Run(mode, func(t *testing.T) {
+			opts := CSRFOptions{Mode: mode}
+			ta := apptest.NewTestApp(app.WithRoutes(func(r app.Router) {
+				r.Get("/page", VerifyCSRFWith(opts), func(c *app.Context) error {
+					return c.Render("csrf_form.page.gohtml", nil)
+				})
+				r.Post("/submit", VerifyCSRFWith(opts), func(c *app.Context) error {
+					return c.Text([]byte("sent"))
+				})
+			}))
+			defer ta.Close()
+
+			res := ta.Get("/page").Header("Accept", "text/html").Do().AssertOK(t)
+			match := tokenField.FindStringSubmatch(res.Body())
+			if match == nil || match[1] == "" {
+				t.Fatalf("rendered form %q has no token", res.Body())
+			}
+
+			ta.Post("/submit").Header("Accept", "text/html").Form(url.Values{"_token": {match[1]}}).Do().
+				AssertOK(t).
+				AssertContains(t, "sent")
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/config"
+)
+
+type DumpOptions struct {
+	// Logger receives the dumps, slog.Default() if nil
+	Logger *slog.Logger
+
+	// Level the dumps are logged at, slog.LevelInfo by default. Nothing is
+	// buffered when the logger doesn't log at this level.
+	Level slog.Level
+
+	// RedactHeaders lists request and response headers whose values are
+	// hidden. Authorization, Cookie and Set-Cookie are always redacted.
+	RedactHeaders []string
+
+	// RedactFields lists JSON body fields, at any depth, and form fields
+	// whose values are hidden
+	RedactFields []string
+
+	// MaxBodySize caps how many bytes of each body are logged, 64KB if zero
+	MaxBodySize int
+
+	// MaxBufferSize caps how many bytes of each body are buffered for
+	// redaction, 1MB if zero. Larger bodies are logged only when no
+	// RedactFields are set, since they can't be redacted reliably.
+	MaxBufferSize int
+}
+
+type dumpRecorder struct {
+	*responseRecorder
+	body     bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (r *dumpRecorder) Write(b []byte) (int, error) {
+	remaining := r.limit - r.body.Len()
+	if remaining > 0 {
+		r.body.Write(b[:min(len(b), remaining)])
+	}
+	if len(b) > remaining {
+		r.overflow = true
+	}
+	return r.responseRecorder.Write(b)
+}
+
+// Dump logs full request and response bodies and headers for debugging.
+// It does nothing when app.env is "production" or the logger doesn't log
+// at DumpOptions.Level. The request body is buffered and restored, so
+// handlers can still read it.
+func Dump(opts ...*DumpOptions) app.HTTPMiddleware {
+	o := &DumpOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+
+	limit := o.MaxBodySize
+	if limit <= 0 {
+		limit = 64 << 10
+	}
+
+	bufferLimit := o.MaxBufferSize
+	if bufferLimit <= 0 {
+		bufferLimit = 1 << 20
+	}
+	bufferLimit = max(bufferLimit, limit)
+
+	redactHeaders := []string{"Authorization", "Cookie", "Set-Cookie"}
+	for _, h := range o.RedactHeaders {
+		redactHeaders = append(redactHeaders, http.CanonicalHeaderKey(h))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			logger := o.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+
+			if config.Get("app.env") == "production" || !logger.Enabled(r.Context(), o.Level) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var reqBody []byte
+			reqOverflow := false
+			if r.Body != nil {
+				var err error
+				if reqBody, err = io.ReadAll(io.LimitReader(r.Body, int64(bufferLimit)+1)); err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				if len(reqBody) > bufferLimit {
+					reqOverflow = true
+				}
+				// Hand the handler what was buffered followed by the unread rest
+				r.Body = struct {
+					io.Reader
+					io.Closer
+				}{io.MultiReader(bytes.NewReader(reqBody), r.Body), r.Body}
+			}
+
+			recorder := &dumpRecorder{
+				responseRecorder: &responseRecorder{ResponseWriter: w, status: http.StatusOK},
+				limit:            bufferLimit,
+			}
+			next.ServeHTTP(recorder, r)
+
+			logger.Log(r.Context(), o.Level, "request dump",
+				slog.Group("request",
+					slog.String("method", r.Method),
+					slog.String("url", r.URL.String()),
+					slog.Any("headers", redactHeaderValues(r.Header, redactHeaders)),
+					slog.String("body", dumpBody(reqBody, reqOverflow, r.Header.Get("Content-Type"), o.RedactFields, limit)),
+				),
+				slog.Group("response",
+					slog.Int("status", recorder.Status()),
+					slog.Any("headers", redactHeaderValues(w.Header(), redactHeaders)),
+					slog.String("body", dumpBody(recorder.body.Bytes(), recorder.overflow, w.Header().Get("Content-Type"), o.RedactFields, limit)),
+				),
+			)
+		})
+	}
+}
+
+// dumpBody redacts the whole buffered body and then truncates it to limit.
+// A body that overflowed the buffer is omitted if it needed redacting.
+func dumpBody(body []byte, overflow bool, contentType string, fields []string, limit int) string {
+	if overflow && len(fields) > 0 {
+		return "[body too large to redact]"
+	}
+
+	out := redactBody(body, contentType, fields)
+	if len(out) > limit {
+		return out[:limit]
+	}
+	return out
+}
+
+func redactHeaderValues(h http.Header, redact []string) map[string]string {
+	out := make(map[string]string, len(h))
+	for key, values := range h {
+		if slices.Contains(redact, key) {
+			out[key] = redacted
+			continue
+		}
+		out[key] = strings.Join(values, ", ")
+	}
+	return out
+}
+
+// redactBody hides the given fields when the body is JSON or a urlencoded
+// form, returning any other body unchanged
+func redactBody(body []byte, contentType string, fields []string) string {
+	if len(fields) == 0 || len(body) == 0 {
+		return string(body)
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		return redactForm(body, fields)
+	}
+
+	var decoded any
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return string(body)
+	}
+
+	encoded, err := json.Marshal(redactValue(decoded, fields))
+	if err != nil {
+		return string(body)
+	}
+	return string(encoded)
+}
+
+func redactForm(body []byte, fields []string) string {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return string(body)
+	}
+
+	for key, items := range values {
+		if slices.ContainsFunc(fields, func(f string) bool { return strings.EqualFold(f, key) }) {
+			for i := range items {
+				items[i] = redacted
+			}
+		}
+	}
+	return values.Encode()
+}
+
+func redactValue(v any, fields []string) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, item := range val {
+			if slices.ContainsFunc(fields, func(f string) bool { return strings.EqualFold(f, key) }) {
+				val[key] = redacted
+				continue
+			}
+			val[key] = redactValue(item, fields)
+		}
+	case []any:
+		for i, item := range val {
+			val[i] = redactValue(item, fields)
+		}
+	}
+	return v
+}
@@ -0,0 +1,175 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// dumpEntry runs handler behind Dump and returns the logged request and
+// response groups
+func dumpEntry(t *testing.T, opts *DumpOptions, handler http.HandlerFunc, r *http.Request) (map[string]any, map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	opts.Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	Dump(opts)(handler).ServeHTTP(httptest.NewRecorder(), r)
+
+	entry := map[string]any{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("dump output is not JSON: %v: %s", err, buf.String())
+	}
+	request, _ := entry["request"].(map[string]any)
+	response, _ := entry["response"].(map[string]any)
+	return request, response
+}
+
+func TestDumpBodyReadableDownstream(t *testing.T) {
+	body := `{"name":"jane"}`
+	var seen string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+		w.Write([]byte("ok"))
+	}
+
+	request, response := dumpEntry(t, &DumpOptions{}, handler, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if seen != body {
+		t.Errorf("handler read %q, want %q", seen, body)
+	}
+	if request["body"] != body {
+		t.Errorf("logged request body = %v", request["body"])
+	}
+	if response["body"] != "ok" {
+		t.Errorf("logged response body = %v", response["body"])
+	}
+}
+
+func TestDumpBodyPastBufferReadableDownstream(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	var seen string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seen = string(b)
+	}
+
+	opts := &DumpOptions{MaxBodySize: 10, MaxBufferSize: 20}
+	request, _ := dumpEntry(t, opts, handler, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if seen != body {
+		t.Errorf("handler read %d bytes, want %d", len(seen), len(body))
+	}
+	if request["body"] != body[:10] {
+		t.Errorf("logged request body = %v, want it truncated to 10 bytes", request["body"])
+	}
+}
+
+func TestDumpRedactsJSONFields(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"token":"abc","user":{"password":"hunter2"}}`))
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"password":"hunter2","name":"jane"}`))
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Secret", "s3cret")
+
+	opts := &DumpOptions{RedactFields: []string{"password", "token"}, RedactHeaders: []string{"x-secret"}}
+	request, response := dumpEntry(t, opts, handler, r)
+
+	for _, body := range []any{request["body"], response["body"]} {
+		s, _ := body.(string)
+		if strings.Contains(s, "hunter2") || strings.Contains(s, "abc") {
+			t.Errorf("body not redacted: %s", s)
+		}
+		if !strings.Contains(s, redacted) {
+			t.Errorf("body missing redaction marker: %s", s)
+		}
+	}
+
+	headers, _ := request["headers"].(map[string]any)
+	if headers["Authorization"] != redacted || headers["X-Secret"] != redacted {
+		t.Errorf("headers not redacted: %v", headers)
+	}
+}
+
+func TestDumpRedactsFormFields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("password=hunter2&name=jane"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	request, _ := dumpEntry(t, &DumpOptions{RedactFields: []string{"password"}}, func(http.ResponseWriter, *http.Request) {}, r)
+
+	body, _ := request["body"].(string)
+	if strings.Contains(body, "hunter2") || !strings.Contains(body, "name=jane") {
+		t.Errorf("form body not redacted: %s", body)
+	}
+}
+
+func TestDumpRedactsBeforeTruncating(t *testing.T) {
+	body := `{"name":"jane","password":"hunter2"}`
+	opts := &DumpOptions{RedactFields: []string{"password"}, MaxBodySize: len(body) - 4}
+	request, _ := dumpEntry(t, opts, func(http.ResponseWriter, *http.Request) {}, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if s, _ := request["body"].(string); strings.Contains(s, "hunter") {
+		t.Errorf("truncated body leaked a redacted field: %s", s)
+	}
+}
+
+func TestDumpOmitsOversizedBodyWhenRedacting(t *testing.T) {
+	body := `{"password":"hunter2","padding":"` + strings.Repeat("a", 100) + `"}`
+	opts := &DumpOptions{RedactFields: []string{"password"}, MaxBodySize: 10, MaxBufferSize: 20}
+	request, _ := dumpEntry(t, opts, func(http.ResponseWriter, *http.Request) {}, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+
+	if s, _ := request["body"].(string); strings.Contains(s, "hunter") {
+		t.Errorf("oversized body leaked a redacted field: %s", s)
+	}
+}
+
+func TestDumpLevel(t *testing.T) {
+	tests := []struct {
+		name        string
+		loggerLevel slog.Level
+		dumpLevel   slog.Level
+		wantLogged  bool
+	}{
+		{"default level on an info logger", slog.LevelInfo, 0, true},
+		{"debug dumps on an info logger", slog.LevelInfo, slog.LevelDebug, false},
+		{"debug dumps on a debug logger", slog.LevelDebug, slog.LevelDebug, true},
+		{"warn dumps on an error logger", slog.LevelError, slog.LevelWarn, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			opts := &DumpOptions{
+				Logger: slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: tt.loggerLevel})),
+				Level:  tt.dumpLevel,
+			}
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("body"))
+			body := r.Body
+			var buffered bool
+			Dump(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, recorded := w.(*dumpRecorder)
+				buffered = recorded || r.Body != body
+			})).ServeHTTP(httptest.NewRecorder(), r)
+
+			if logged := buf.Len() > 0; logged != tt.wantLogged {
+				t.Errorf("logged = %v, want %v: %s", logged, tt.wantLogged, buf.String())
+			}
+			if buffered != tt.wantLogged {
+				t.Errorf("buffered = %v, want it only when the dump is logged", buffered)
+			}
+			if tt.wantLogged && !strings.Contains(buf.String(), `"level":"`+tt.dumpLevel.String()+`"`) {
+				t.Errorf("dump = %s, want it at %v", buf.String(), tt.dumpLevel)
+			}
+		})
+	}
+}
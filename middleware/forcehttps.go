@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/req"
+)
+
+// ForceHTTPS redirects plain HTTP GET and HEAD requests to their https
+// equivalent with a 301 and rejects other methods with a 403, since
+// redirecting them would drop the request body. X-Forwarded-Proto is only
+// honored for requests coming from one of the trusted proxies, which
+// default to app.trusted_proxies when none are given.
+func ForceHTTPS(trustedProxies ...string) app.HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trusted := trustedProxies
+			if trusted == nil {
+				trusted = app.TrustedProxies()
+			}
+
+			if req.IsSecure(r, trusted) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				http.Error(w, "HTTPS is required", http.StatusForbidden)
+				return
+			}
+
+			target := "https://" + r.Host + r.URL.RequestURI()
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		})
+	}
+}
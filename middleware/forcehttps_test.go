@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+func TestForceHTTPS(t *testing.T) {
+	handler := ForceHTTPS("10.0.0.1")(okHandler())
+
+	tests := []struct {
+		name         string
+		method       string
+		target       string
+		remoteAddr   string
+		tls          bool
+		proto        string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"get redirects", http.MethodGet, "http://example.com/a?b=1", "192.0.2.1:1", false, "", http.StatusMovedPermanently, "https://example.com/a?b=1"},
+		{"head redirects", http.MethodHead, "http://example.com/", "192.0.2.1:1", false, "", http.StatusMovedPermanently, "https://example.com/"},
+		{"post is refused", http.MethodPost, "http://example.com/", "192.0.2.1:1", false, "", http.StatusForbidden, ""},
+		{"tls passes", http.MethodGet, "https://example.com/", "192.0.2.1:1", true, "", http.StatusOK, ""},
+		{"trusted proxy https passes", http.MethodPost, "http://example.com/", "10.0.0.1:1", false, "https", http.StatusOK, ""},
+		{"trusted proxy http redirects", http.MethodGet, "http://example.com/", "10.0.0.1:1", false, "http", http.StatusMovedPermanently, "https://example.com/"},
+		{"untrusted proxy header ignored", http.MethodGet, "http://example.com/", "192.0.2.1:1", false, "https", http.StatusMovedPermanently, "https://example.com/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			r.RemoteAddr = tt.remoteAddr
+			if !tt.tls {
+				r.TLS = nil
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Location"); got != tt.wantLocation {
+				t.Fatalf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestForceHTTPSFallsBackToConfiguredProxies(t *testing.T) {
+	config.Set("app.trusted_proxies", []string{"10.0.0.0/8"})
+	defer config.Set("app.trusted_proxies", nil)
+
+	serve := func(handler http.Handler) int {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		r.RemoteAddr = "10.0.0.1:1"
+		r.Header.Set("X-Forwarded-Proto", "https")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if got := serve(ForceHTTPS()(okHandler())); got != http.StatusOK {
+		t.Errorf("status = %d through a configured proxy, want 200", got)
+	}
+
+	// An explicit empty list trusts no proxy, even with the config set
+	if got := serve(ForceHTTPS([]string{}...)(okHandler())); got != http.StatusMovedPermanently {
+		t.Errorf("status = %d with no trusted proxies, want 301", got)
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/req"
+)
+
+type IPFilterOptions struct {
+	// Allow lists the IPs or CIDR ranges that may pass. Every client not
+	// denied may pass if empty.
+	Allow []string
+
+	// Deny lists the IPs or CIDR ranges that are rejected. Deny takes
+	// precedence over Allow.
+	Deny []string
+
+	// TrustedProxies lists the proxies whose forwarding headers are used to
+	// find the client IP, app.trusted_proxies if nil
+	TrustedProxies []string
+}
+
+// IPFilter rejects requests with a 403 when the client IP is denied, or
+// when an allow list is given and the client IP is not on it. It panics if
+// an entry is neither an IP nor a CIDR range.
+func IPFilter(opts IPFilterOptions) app.HTTPMiddleware {
+	for _, entry := range append(append([]string{}, opts.Allow...), opts.Deny...) {
+		if !validIPEntry(entry) {
+			panic(fmt.Sprintf("middleware: invalid IP filter entry %q", entry))
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			trusted := opts.TrustedProxies
+			if trusted == nil {
+				trusted = app.TrustedProxies()
+			}
+
+			ip := req.ClientIP(r, trusted)
+			if req.IPMatches(ip, opts.Deny) || (len(opts.Allow) > 0 && !req.IPMatches(ip, opts.Allow)) {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func validIPEntry(entry string) bool {
+	if strings.Contains(entry, "/") {
+		_, _, err := net.ParseCIDR(entry)
+		return err == nil
+	}
+	return net.ParseIP(entry) != nil
+}
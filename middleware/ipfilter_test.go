@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       IPFilterOptions
+		remoteAddr string
+		forwarded  string
+		wantStatus int
+	}{
+		{"no lists passes", IPFilterOptions{}, "192.0.2.1:1", "", http.StatusOK},
+		{"allowed ip", IPFilterOptions{Allow: []string{"192.0.2.1"}}, "192.0.2.1:1", "", http.StatusOK},
+		{"not on allow list", IPFilterOptions{Allow: []string{"192.0.2.1"}}, "192.0.2.2:1", "", http.StatusForbidden},
+		{"allowed cidr", IPFilterOptions{Allow: []string{"10.0.0.0/8"}}, "10.20.30.40:1", "", http.StatusOK},
+		{"outside allowed cidr", IPFilterOptions{Allow: []string{"10.0.0.0/8"}}, "11.0.0.1:1", "", http.StatusForbidden},
+		{"ipv6 cidr", IPFilterOptions{Allow: []string{"2001:db8::/32"}}, "[2001:db8::1]:1", "", http.StatusOK},
+		{"denied ip", IPFilterOptions{Deny: []string{"192.0.2.1"}}, "192.0.2.1:1", "", http.StatusForbidden},
+		{"denied cidr", IPFilterOptions{Deny: []string{"192.0.2.0/24"}}, "192.0.2.99:1", "", http.StatusForbidden},
+		{"deny beats allow", IPFilterOptions{Allow: []string{"10.0.0.0/8"}, Deny: []string{"10.0.0.5"}}, "10.0.0.5:1", "", http.StatusForbidden},
+		{"deny cidr beats allowed ip", IPFilterOptions{Allow: []string{"10.0.0.5"}, Deny: []string{"10.0.0.0/24"}}, "10.0.0.5:1", "", http.StatusForbidden},
+		{"trusted proxy forwards client", IPFilterOptions{Allow: []string{"203.0.113.7"}, TrustedProxies: []string{"10.0.0.1"}}, "10.0.0.1:1", "203.0.113.7", http.StatusOK},
+		{"trusted proxy cidr", IPFilterOptions{Deny: []string{"203.0.113.7"}, TrustedProxies: []string{"10.0.0.0/8"}}, "10.1.2.3:1", "203.0.113.7, 10.0.0.2", http.StatusForbidden},
+		{"untrusted peer header ignored", IPFilterOptions{Allow: []string{"203.0.113.7"}, TrustedProxies: []string{"10.0.0.1"}}, "192.0.2.1:1", "203.0.113.7", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w := httptest.NewRecorder()
+			IPFilter(tt.opts)(okHandler()).ServeHTTP(w, r)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestIPFilterFallsBackToConfiguredProxies(t *testing.T) {
+	config.Set("app.trusted_proxies", []string{"10.0.0.0/8"})
+	defer config.Set("app.trusted_proxies", nil)
+
+	handler := IPFilter(IPFilterOptions{Allow: []string{"203.0.113.7"}})(okHandler())
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d through a configured proxy, want 200", w.Code)
+	}
+
+	// An explicit empty list trusts no proxy, even with the config set
+	handler = IPFilter(IPFilterOptions{Allow: []string{"203.0.113.7"}, TrustedProxies: []string{}})(okHandler())
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("status = %d with no trusted proxies, want 403", w.Code)
+	}
+}
+
+func TestIPFilterPanicsOnInvalidEntry(t *testing.T) {
+	for _, opts := range []IPFilterOptions{
+		{Allow: []string{"not-an-ip"}},
+		{Deny: []string{"10.0.0.0/99"}},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("IPFilter(%+v) did not panic", opts)
+				}
+			}()
+			IPFilter(opts)
+		}()
+	}
+}
@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/session"
+)
+
+// LocaleSource is a place the Locale middleware looks for the locale
+type LocaleSource string
+
+const (
+	LocaleFromQuery   LocaleSource = "query"
+	LocaleFromCookie  LocaleSource = "cookie"
+	LocaleFromSession LocaleSource = "session"
+	LocaleFromHeader  LocaleSource = "header"
+)
+
+type LocaleOptions struct {
+	// Supported lists the accepted locales. A resolved locale that isn't
+	// supported is ignored; its base language (e.g. "en" for "en-US") is
+	// tried first. Any locale is accepted if empty.
+	Supported []string
+
+	// Default is used when no source yields a supported locale, "en" if empty
+	Default string
+
+	// Sources is the resolution order, query, cookie, session, then the
+	// Accept-Language header if empty
+	Sources []LocaleSource
+
+	// QueryParam is the query parameter name, "lang" if empty
+	QueryParam string
+
+	// CookieName is the cookie name, "locale" if empty
+	CookieName string
+
+	// SessionKey is the session key, "locale" if empty
+	SessionKey string
+}
+
+// Locale resolves the request locale from the configured sources and stores
+// it in the request context, where handlers read it with c.Locale()
+func Locale(opts LocaleOptions) app.HTTPMiddleware {
+	if opts.Default == "" {
+		opts.Default = "en"
+	}
+	if len(opts.Sources) == 0 {
+		opts.Sources = []LocaleSource{LocaleFromQuery, LocaleFromCookie, LocaleFromSession, LocaleFromHeader}
+	}
+	if opts.QueryParam == "" {
+		opts.QueryParam = "lang"
+	}
+	if opts.CookieName == "" {
+		opts.CookieName = "locale"
+	}
+	if opts.SessionKey == "" {
+		opts.SessionKey = "locale"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			locale := resolveLocale(r, opts)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), app.LocaleKey, locale)))
+		})
+	}
+}
+
+func resolveLocale(r *http.Request, opts LocaleOptions) string {
+	for _, source := range opts.Sources {
+		var candidates []string
+
+		switch source {
+		case LocaleFromQuery:
+			candidates = []string{r.URL.Query().Get(opts.QueryParam)}
+		case LocaleFromCookie:
+			if cookie, err := r.Cookie(opts.CookieName); err == nil {
+				candidates = []string{cookie.Value}
+			}
+		case LocaleFromSession:
+			candidates = []string{sessionLocale(r, opts.SessionKey)}
+		case LocaleFromHeader:
+			candidates = parseAcceptLanguage(r.Header.Get("Accept-Language"))
+		}
+
+		for _, candidate := range candidates {
+			if locale, ok := matchLocale(candidate, opts.Supported); ok {
+				return locale
+			}
+		}
+	}
+
+	return opts.Default
+}
+
+// sessionLocale reads the locale from the session, if one is loaded for the request
+func sessionLocale(r *http.Request, key string) string {
+	sess := session.Get()
+	if sess == nil || !sess.Loaded(r.Context()) {
+		return ""
+	}
+	return sess.GetString(r.Context(), key)
+}
+
+// matchLocale returns the supported locale matching candidate exactly or by
+// its base language, compared case-insensitively with "_" treated as "-"
+func matchLocale(candidate string, supported []string) (string, bool) {
+	candidate = strings.ReplaceAll(strings.TrimSpace(candidate), "_", "-")
+	if candidate == "" || candidate == "*" {
+		return "", false
+	}
+	if len(supported) == 0 {
+		return candidate, true
+	}
+
+	base, _, _ := strings.Cut(candidate, "-")
+	for _, want := range []string{candidate, base} {
+		i := slices.IndexFunc(supported, func(s string) bool {
+			return strings.EqualFold(strings.ReplaceAll(s, "_", "-"), want)
+		})
+		if i >= 0 {
+			return supported[i], true
+		}
+	}
+
+	return "", false
+}
+
+// parseAcceptLanguage returns the language tags of an Accept-Language
+// header ordered by descending quality, dropping those with q=0
+func parseAcceptLanguage(header string) []string {
+	type tag struct {
+		name string
+		q    float64
+	}
+
+	var tags []tag
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q > 0 {
+			tags = append(tags, tag{name, q})
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	names := make([]string, len(tags))
+	for i, t := range tags {
+		names[i] = t.name
+	}
+	return names
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/session"
+)
+
+// resolvedLocale runs r through the Locale middleware and returns the
+// locale it stored
+func resolvedLocale(opts LocaleOptions, r *http.Request) string {
+	var locale string
+	Locale(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale, _ = r.Context().Value(app.LocaleKey).(string)
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	return locale
+}
+
+// withSessionLocale loads a session into r holding the given locale
+func withSessionLocale(t *testing.T, r *http.Request, locale string) *http.Request {
+	t.Helper()
+	session.Set(memstore.New(), scs.SessionCookie{Name: "session"})
+	sess := session.Get()
+
+	ctx, err := sess.Load(r.Context(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.Put(ctx, "locale", locale)
+	return r.WithContext(ctx)
+}
+
+func TestLocaleSources(t *testing.T) {
+	opts := LocaleOptions{Supported: []string{"en", "fr", "de", "pt-BR"}}
+
+	query := httptest.NewRequest(http.MethodGet, "/?lang=fr", nil)
+	if got := resolvedLocale(opts, query); got != "fr" {
+		t.Errorf("query: locale = %q, want fr", got)
+	}
+
+	cookie := httptest.NewRequest(http.MethodGet, "/", nil)
+	cookie.AddCookie(&http.Cookie{Name: "locale", Value: "de"})
+	if got := resolvedLocale(opts, cookie); got != "de" {
+		t.Errorf("cookie: locale = %q, want de", got)
+	}
+
+	sess := withSessionLocale(t, httptest.NewRequest(http.MethodGet, "/", nil), "pt_BR")
+	if got := resolvedLocale(opts, sess); got != "pt-BR" {
+		t.Errorf("session: locale = %q, want pt-BR", got)
+	}
+
+	header := httptest.NewRequest(http.MethodGet, "/", nil)
+	header.Header.Set("Accept-Language", "es;q=0.9, de-AT;q=0.8, fr;q=0.1")
+	if got := resolvedLocale(opts, header); got != "de" {
+		t.Errorf("header: locale = %q, want de", got)
+	}
+}
+
+func TestLocaleDefault(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?lang=xx", nil)
+	r.Header.Set("Accept-Language", "ja, *;q=0.5")
+
+	if got := resolvedLocale(LocaleOptions{Supported: []string{"en", "fr"}}, r); got != "en" {
+		t.Errorf("locale = %q, want the built-in default en", got)
+	}
+	if got := resolvedLocale(LocaleOptions{Supported: []string{"en", "fr"}, Default: "fr"}, r); got != "fr" {
+		t.Errorf("locale = %q, want the configured default fr", got)
+	}
+}
+
+func TestLocaleWithoutSession(t *testing.T) {
+	// A session provider exists but the request never passed through its
+	// middleware, so the session source is skipped rather than panicking
+	session.Set(memstore.New(), scs.SessionCookie{Name: "session"})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept-Language", "fr")
+	if got := resolvedLocale(LocaleOptions{Sources: []LocaleSource{LocaleFromSession, LocaleFromHeader}}, r); got != "fr" {
+		t.Errorf("locale = %q, want fr", got)
+	}
+}
+
+func TestLocalePriority(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?lang=fr", nil)
+	r.AddCookie(&http.Cookie{Name: "locale", Value: "de"})
+	r.Header.Set("Accept-Language", "en")
+
+	tests := []struct {
+		sources []LocaleSource
+		want    string
+	}{
+		{nil, "fr"},
+		{[]LocaleSource{LocaleFromCookie, LocaleFromQuery}, "de"},
+		{[]LocaleSource{LocaleFromHeader, LocaleFromQuery}, "en"},
+	}
+	for _, tt := range tests {
+		if got := resolvedLocale(LocaleOptions{Sources: tt.sources}, r); got != tt.want {
+			t.Errorf("sources %v: locale = %q, want %q", tt.sources, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleCustomNames(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/?hl=fr&lang=de", nil)
+	if got := resolvedLocale(LocaleOptions{QueryParam: "hl"}, r); got != "fr" {
+		t.Errorf("locale = %q, want fr", got)
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := parseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5, es;q=0")
+	want := []string{"fr-CH", "fr", "en", "de", "*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("parseAcceptLanguage() = %v, want %v", got, want)
+	}
+}
@@ -1,23 +1,64 @@
 package middleware
 
 import (
-	"encoding/json"
-	"fmt"
-	"github.com/lemmego/api/app"
-	"log"
+	"log/slog"
+	"math/rand"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/lemmego/api/app"
 )
 
+const redacted = "[REDACTED]"
+
 type LogOptions struct {
 	Headers   bool
 	UserAgent bool
+
+	// Logger receives the entries, slog.Default() if nil
+	Logger *slog.Logger
+
+	// SampleRate is the fraction of requests to log, between 0 and 1.
+	// Zero logs every request. Server errors are always logged.
+	SampleRate float64
+
+	// RedactHeaders lists header names whose values are replaced when
+	// Headers is enabled. Authorization and Cookie are always redacted.
+	RedactHeaders []string
+
+	// LatencyBuckets are the ascending upper bounds used for the
+	// latency_bucket field, e.g. "<=100ms", or ">5s" past the last one.
+	// DefaultLatencyBuckets is used if empty.
+	LatencyBuckets []time.Duration
+}
+
+// DefaultLatencyBuckets groups request latencies for the latency_bucket field
+var DefaultLatencyBuckets = []time.Duration{
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	5 * time.Second,
+}
+
+// latencyBucket returns the label of the first bucket bound latency fits in
+func latencyBucket(latency time.Duration, buckets []time.Duration) string {
+	for _, bound := range buckets {
+		if latency <= bound {
+			return "<=" + bound.String()
+		}
+	}
+	return ">" + buckets[len(buckets)-1].String()
 }
 
 type responseRecorder struct {
 	http.ResponseWriter
 	status int
+	bytes  int
 }
 
 // WriteHeader wraps the ResponseWriter's WriteHeader method to capture the status code.
@@ -26,47 +67,102 @@ func (r *responseRecorder) WriteHeader(statusCode int) {
 	r.ResponseWriter.WriteHeader(statusCode)
 }
 
+// Write wraps the ResponseWriter's Write method to count the bytes written.
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
 // Status method returns the status code.
 func (r *responseRecorder) Status() int {
 	return r.status
 }
 
+// Unwrap returns the original ResponseWriter for http.ResponseController.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 func RequestLogger(opts ...*LogOptions) app.HTTPMiddleware {
+	o := &LogOptions{}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+
+	redact := []string{"Authorization", "Cookie"}
+	for _, h := range o.RedactHeaders {
+		redact = append(redact, http.CanonicalHeaderKey(h))
+	}
+
+	buckets := o.LatencyBuckets
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Capture the start time to measure latency
 			start := time.Now()
 
-			var logStr strings.Builder
-			logStr.WriteString(fmt.Sprintf("[%s] <- %s %s", r.Method, r.URL, r.RemoteAddr))
+			// Create a responseRecorder to capture the status and size
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r)
+
+			latency := time.Since(start)
 
-			if len(opts) > 0 && opts[0].Headers {
-				headers, err := json.Marshal(r.Header)
-				if err != nil {
-					panic(err)
-				}
-				logStr.WriteString(fmt.Sprintf("\n\nHeaders: %s\n\n", headers))
+			if recorder.Status() < http.StatusInternalServerError && o.SampleRate > 0 && o.SampleRate < 1 && rand.Float64() >= o.SampleRate {
+				return
 			}
 
-			// Log request details
-			log.Printf(logStr.String())
+			attrs := []any{
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.Int("status", recorder.Status()),
+				slog.Int("bytes", recorder.bytes),
+				slog.Duration("latency", latency),
+				slog.String("latency_bucket", latencyBucket(latency, buckets)),
+			}
 
-			// Create a responseRecorder to capture the status
-			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
-			next.ServeHTTP(recorder, r)
+			if reqID := requestID(r); reqID != "" {
+				attrs = append(attrs, slog.String("request_id", reqID))
+			}
 
-			// Calculate the time taken for this request
-			latency := time.Since(start)
+			if o.UserAgent {
+				attrs = append(attrs, slog.String("user_agent", r.UserAgent()))
+			}
+
+			if o.Headers {
+				headers := make([]any, 0, len(r.Header))
+				for key, values := range r.Header {
+					value := strings.Join(values, ", ")
+					if slices.Contains(redact, key) {
+						value = redacted
+					}
+					headers = append(headers, slog.String(key, value))
+				}
+				attrs = append(attrs, slog.Group("headers", headers...))
+			}
+
+			logger := o.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
 
-			// Log after the request is processed including the status code
-			log.Printf(
-				"[%s] -> %s %s - %d | Completed in %v",
-				r.Method,
-				r.URL.Path,
-				r.RemoteAddr,
-				recorder.Status(),
-				latency,
-			)
+			level := slog.LevelInfo
+			if recorder.Status() >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+
+			logger.Log(r.Context(), level, "request completed", attrs...)
 		})
 	}
 }
+
+func requestID(r *http.Request) string {
+	if id := middleware.GetReqID(r.Context()); id != "" {
+		return id
+	}
+	return r.Header.Get(middleware.RequestIDHeader)
+}
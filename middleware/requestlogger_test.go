@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func captureLogs(opts *LogOptions) (*bytes.Buffer, *LogOptions) {
+	var buf bytes.Buffer
+	opts.Logger = slog.New(slog.NewJSONHandler(&buf, nil))
+	return &buf, opts
+}
+
+func decodeLogEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		entry := map[string]any{}
+		if err := dec.Decode(&entry); err != nil {
+			t.Fatalf("log output is not JSON: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRequestLoggerFields(t *testing.T) {
+	buf, opts := captureLogs(&LogOptions{Headers: true, UserAgent: true, RedactHeaders: []string{"x-api-key"}})
+
+	handler := RequestLogger(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/search/100%25%20off?q=%d", nil)
+	r.Header.Set("X-Request-Id", "req-1")
+	r.Header.Set("User-Agent", "test-agent")
+	r.Header.Set("Authorization", "Bearer secret")
+	r.Header.Set("X-Api-Key", "secret")
+	r.Header.Set("Accept", "text/plain")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	entries := decodeLogEntries(t, buf)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	entry := entries[0]
+
+	want := map[string]any{
+		"level":      "INFO",
+		"msg":        "request completed",
+		"method":     "POST",
+		"path":       "/search/100% off",
+		"status":     float64(http.StatusCreated),
+		"bytes":      float64(5),
+		"request_id": "req-1",
+		"user_agent": "test-agent",
+	}
+	for key, value := range want {
+		if entry[key] != value {
+			t.Errorf("%s = %v, want %v", key, entry[key], value)
+		}
+	}
+	if entry["latency_bucket"] != "<=10ms" {
+		t.Errorf("latency_bucket = %v", entry["latency_bucket"])
+	}
+
+	headers, _ := entry["headers"].(map[string]any)
+	if headers["Authorization"] != redacted || headers["X-Api-Key"] != redacted {
+		t.Errorf("headers not redacted: %v", headers)
+	}
+	if headers["Accept"] != "text/plain" {
+		t.Errorf("Accept header = %v", headers["Accept"])
+	}
+}
+
+func TestRequestLoggerSampling(t *testing.T) {
+	buf, opts := captureLogs(&LogOptions{SampleRate: 0.0000001})
+
+	status := http.StatusOK
+	handler := RequestLogger(opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+
+	for i := 0; i < 10; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if entries := decodeLogEntries(t, buf); len(entries) != 0 {
+		t.Fatalf("sampled out requests logged %d entries", len(entries))
+	}
+
+	status = http.StatusInternalServerError
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	entries := decodeLogEntries(t, buf)
+	if len(entries) != 1 || entries[0]["level"] != "ERROR" {
+		t.Fatalf("server error entries = %v, want one ERROR entry", entries)
+	}
+}
+
+func TestLatencyBucket(t *testing.T) {
+	buckets := []time.Duration{100 * time.Millisecond, time.Second}
+	tests := map[time.Duration]string{
+		time.Millisecond:        "<=100ms",
+		100 * time.Millisecond:  "<=100ms",
+		101 * time.Millisecond:  "<=1s",
+		1500 * time.Millisecond: ">1s",
+	}
+	for latency, want := range tests {
+		if got := latencyBucket(latency, buckets); got != want {
+			t.Errorf("latencyBucket(%v) = %q, want %q", latency, got, want)
+		}
+	}
+}
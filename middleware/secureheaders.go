@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/req"
+)
+
+type SecureHeadersOptions struct {
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header in
+	// seconds. Zero disables the header.
+	HSTSMaxAge            int
+	HSTSIncludeSubDomains bool
+	HSTSPreload           bool
+
+	// FrameOptions is the X-Frame-Options value, "DENY" if empty
+	FrameOptions string
+
+	// ReferrerPolicy is the Referrer-Policy value, "strict-origin-when-cross-origin" if empty
+	ReferrerPolicy string
+
+	// ContentSecurityPolicy is sent as-is when not empty
+	ContentSecurityPolicy string
+
+	// TrustedProxies whose X-Forwarded-Proto header is honored when
+	// deciding if the request is secure. Nil uses app.trusted_proxies, an
+	// empty slice trusts none.
+	TrustedProxies []string
+}
+
+// SecureHeaders sets common security related response headers. HSTS is only
+// sent for requests that arrived over HTTPS, directly or via a trusted proxy.
+func SecureHeaders(opts ...*SecureHeadersOptions) app.HTTPMiddleware {
+	o := &SecureHeadersOptions{HSTSMaxAge: 31536000}
+	if len(opts) > 0 && opts[0] != nil {
+		o = opts[0]
+	}
+
+	frameOptions := o.FrameOptions
+	if frameOptions == "" {
+		frameOptions = "DENY"
+	}
+
+	referrerPolicy := o.ReferrerPolicy
+	if referrerPolicy == "" {
+		referrerPolicy = "strict-origin-when-cross-origin"
+	}
+
+	hsts := ""
+	if o.HSTSMaxAge > 0 {
+		hsts = fmt.Sprintf("max-age=%d", o.HSTSMaxAge)
+		if o.HSTSIncludeSubDomains {
+			hsts += "; includeSubDomains"
+		}
+		if o.HSTSPreload {
+			hsts += "; preload"
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", frameOptions)
+			h.Set("Referrer-Policy", referrerPolicy)
+
+			if o.ContentSecurityPolicy != "" {
+				h.Set("Content-Security-Policy", o.ContentSecurityPolicy)
+			}
+
+			if hsts != "" {
+				trusted := o.TrustedProxies
+				if trusted == nil {
+					trusted = app.TrustedProxies()
+				}
+				if req.IsSecure(r, trusted) {
+					h.Set("Strict-Transport-Security", hsts)
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+func serveSecureHeaders(opts *SecureHeadersOptions, r *http.Request) http.Header {
+	w := httptest.NewRecorder()
+	SecureHeaders(opts)(okHandler()).ServeHTTP(w, r)
+	return w.Header()
+}
+
+func TestSecureHeadersDefaults(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{}
+
+	h := serveSecureHeaders(nil, r)
+	want := map[string]string{
+		"X-Content-Type-Options":    "nosniff",
+		"X-Frame-Options":           "DENY",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Strict-Transport-Security": "max-age=31536000",
+		"Content-Security-Policy":   "",
+	}
+	for key, value := range want {
+		if got := h.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestSecureHeadersOptions(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{}
+
+	h := serveSecureHeaders(&SecureHeadersOptions{
+		HSTSMaxAge:            600,
+		HSTSIncludeSubDomains: true,
+		HSTSPreload:           true,
+		FrameOptions:          "SAMEORIGIN",
+		ReferrerPolicy:        "no-referrer",
+		ContentSecurityPolicy: "default-src 'self'",
+	}, r)
+
+	want := map[string]string{
+		"X-Frame-Options":           "SAMEORIGIN",
+		"Referrer-Policy":           "no-referrer",
+		"Content-Security-Policy":   "default-src 'self'",
+		"Strict-Transport-Security": "max-age=600; includeSubDomains; preload",
+	}
+	for key, value := range want {
+		if got := h.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestSecureHeadersHSTSGating(t *testing.T) {
+	opts := &SecureHeadersOptions{HSTSMaxAge: 60, TrustedProxies: []string{"10.0.0.0/8"}}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		proto      string
+		wantHSTS   bool
+	}{
+		{"plain http", "192.0.2.1:1234", false, "", false},
+		{"direct tls", "192.0.2.1:1234", true, "", true},
+		{"trusted proxy https", "10.1.2.3:1234", false, "https", true},
+		{"trusted proxy http", "10.1.2.3:1234", false, "http", false},
+		{"untrusted proxy https", "192.0.2.1:1234", false, "https", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			if tt.proto != "" {
+				r.Header.Set("X-Forwarded-Proto", tt.proto)
+			}
+
+			got := serveSecureHeaders(opts, r).Get("Strict-Transport-Security") != ""
+			if got != tt.wantHSTS {
+				t.Errorf("HSTS sent = %v, want %v", got, tt.wantHSTS)
+			}
+		})
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.TLS = &tls.ConnectionState{}
+	if got := serveSecureHeaders(&SecureHeadersOptions{}, r).Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("HSTS with zero max-age = %q, want none", got)
+	}
+}
+
+func TestSecureHeadersFallsBackToConfiguredProxies(t *testing.T) {
+	config.Set("app.trusted_proxies", []string{"10.0.0.0/8"})
+	defer config.Set("app.trusted_proxies", nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "10.0.0.1:1"
+	r.Header.Set("X-Forwarded-Proto", "https")
+
+	if got := serveSecureHeaders(nil, r).Get("Strict-Transport-Security"); got == "" {
+		t.Error("HSTS not sent through a configured proxy")
+	}
+
+	// An explicit empty list trusts no proxy, even with the config set
+	opts := &SecureHeadersOptions{HSTSMaxAge: 60, TrustedProxies: []string{}}
+	if got := serveSecureHeaders(opts, r).Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("HSTS = %q with no trusted proxies, want none", got)
+	}
+}
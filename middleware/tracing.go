@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lemmego/api/app"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/lemmego/api/middleware"
+
+// Tracing starts an OpenTelemetry server span for every request. An incoming
+// W3C traceparent/tracestate and baggage are continued, and the span is put
+// in the request context so handlers (c.SpanContext()) and their outgoing
+// calls continue the trace. The span is named after the matched route
+// pattern, e.g. "GET /users/{id}", and records the response status.
+func Tracing(tp trace.TracerProvider) app.HTTPMiddleware {
+	tracer := tp.Tracer(tracerName)
+	propagator := propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(r.Method),
+					semconv.URLPath(r.URL.Path),
+					semconv.URLScheme(scheme),
+					semconv.ServerAddress(r.Host),
+					semconv.UserAgentOriginal(r.UserAgent()),
+				),
+			)
+			defer span.End()
+
+			var pattern string
+			ctx = context.WithValue(ctx, app.RoutePatternKey, &pattern)
+
+			recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(recorder, r.WithContext(ctx))
+
+			if pattern != "" {
+				span.SetName(pattern)
+				if _, route, ok := strings.Cut(pattern, " "); ok {
+					span.SetAttributes(semconv.HTTPRoute(route))
+				}
+			}
+
+			span.SetAttributes(
+				semconv.HTTPResponseStatusCode(recorder.status),
+				attribute.Int("http.response.body.size", recorder.bytes),
+			)
+			if recorder.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", recorder.status))
+			}
+		})
+	}
+}
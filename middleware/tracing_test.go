@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lemmego/api/app"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traced serves r through the tracing middleware with an in-memory
+// exporter. The handler fills in pattern the way the router does.
+func traced(t *testing.T, pattern string, status int, r *http.Request) (tracetest.SpanStub, trace.SpanContext) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer tp.Shutdown(r.Context())
+
+	var handlerSpan trace.SpanContext
+	handler := Tracing(tp)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if p, ok := r.Context().Value(app.RoutePatternKey).(*string); ok {
+			*p = pattern
+		}
+		handlerSpan = trace.SpanContextFromContext(r.Context())
+		w.WriteHeader(status)
+		w.Write([]byte("hello"))
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("exported %d spans, want 1", len(spans))
+	}
+	return spans[0], handlerSpan
+}
+
+func spanAttr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracingRecordsServerSpan(t *testing.T) {
+	span, handlerSpan := traced(t, "GET /users/{id}", http.StatusOK, httptest.NewRequest(http.MethodGet, "/users/7", nil))
+
+	if span.Name != "GET /users/{id}" {
+		t.Errorf("span name = %q, want the route pattern", span.Name)
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Errorf("span kind = %v, want server", span.SpanKind)
+	}
+	if span.SpanContext.SpanID() != handlerSpan.SpanID() {
+		t.Error("the handler's context does not carry the request span")
+	}
+
+	tests := []struct {
+		key  attribute.Key
+		want attribute.Value
+	}{
+		{"http.request.method", attribute.StringValue("GET")},
+		{"url.path", attribute.StringValue("/users/7")},
+		{"url.scheme", attribute.StringValue("http")},
+		{"http.route", attribute.StringValue("/users/{id}")},
+		{"http.response.status_code", attribute.IntValue(200)},
+		{"http.response.body.size", attribute.IntValue(5)},
+	}
+	for _, tt := range tests {
+		if got := spanAttr(span, tt.key); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.key, got.Emit(), tt.want.Emit())
+		}
+	}
+	if span.Status.Code != codes.Unset {
+		t.Errorf("status = %v, want unset for a 200", span.Status.Code)
+	}
+}
+
+func TestTracingUnmatchedRouteKeepsMethodName(t *testing.T) {
+	span, _ := traced(t, "", http.StatusNotFound, httptest.NewRequest(http.MethodPost, "/missing", nil))
+
+	if span.Name != http.MethodPost {
+		t.Errorf("span name = %q, want the method", span.Name)
+	}
+	if got := spanAttr(span, "http.route"); got.Type() != attribute.INVALID {
+		t.Errorf("http.route = %v, want it unset", got.Emit())
+	}
+}
+
+func TestTracingMarksServerErrors(t *testing.T) {
+	span, _ := traced(t, "GET /boom", http.StatusBadGateway, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if span.Status.Code != codes.Error || span.Status.Description != "HTTP 502" {
+		t.Errorf("status = %+v, want an error with HTTP 502", span.Status)
+	}
+}
+
+func TestTracingContinuesIncomingTrace(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/users/7", nil)
+	r.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	span, _ := traced(t, "GET /users/{id}", http.StatusOK, r)
+
+	if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the incoming one", got)
+	}
+	if got := span.Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span id = %s, want the incoming one", got)
+	}
+	if !span.Parent.IsRemote() {
+		t.Error("parent span is not marked remote")
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/lemmego/api/app"
+)
+
+// TrustedHosts rejects requests whose Host header is not in the allowed list.
+// Entries may be exact hosts ("example.com") or wildcards ("*.example.com"),
+// a wildcard matching any subdomain but not the bare domain itself.
+// An empty list disables the check.
+func TrustedHosts(allowed []string) app.HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		if len(allowed) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isTrustedHost(r.Host, allowed) {
+				http.Error(w, "Invalid Host header", http.StatusBadRequest)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func isTrustedHost(host string, allowed []string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if host == "" {
+		return false
+	}
+
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if strings.HasPrefix(pattern, "*.") {
+			if strings.HasSuffix(host, pattern[1:]) {
+				return true
+			}
+			continue
+		}
+		if host == pattern {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func okHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestTrustedHosts(t *testing.T) {
+	handler := TrustedHosts([]string{"example.com", "*.example.org"})(okHandler())
+
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"example.com", http.StatusOK},
+		{"EXAMPLE.com:8080", http.StatusOK},
+		{"example.com.", http.StatusOK},
+		{"api.example.org", http.StatusOK},
+		{"a.b.example.org", http.StatusOK},
+		{"example.org", http.StatusBadRequest},
+		{"evil.com", http.StatusBadRequest},
+		{"example.com.evil.com", http.StatusBadRequest},
+		{"notexample.org", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Host = tt.host
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("host %q: got %d, want %d", tt.host, w.Code, tt.want)
+		}
+	}
+}
+
+func TestTrustedHostsEmptyListAllowsAll(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Host = "anything.test"
+	w := httptest.NewRecorder()
+	TrustedHosts(nil)(okHandler()).ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/lemmego/api/app"
+)
+
+// WithValue stores value under key in every request's context, making it
+// available to handlers through c.Value(key), or c.Get(key) for a string key
+func WithValue(key any, value any) app.HTTPMiddleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), key, value)))
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/lemmego/api/app"
+)
+
+type tenantKey struct{}
+
+func TestWithValueReadableFromContext(t *testing.T) {
+	srv, shutDown := app.TestServer(app.WithRoutes(func(r app.Router) {
+		r.Use(WithValue("tenant", "acme"), WithValue(tenantKey{}, "typed"))
+		r.Get("/", func(c *app.Context) error {
+			return c.Text([]byte(c.Get("tenant").(string) + " " + c.Value(tenantKey{}).(string)))
+		})
+	}))
+	defer shutDown()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "acme typed" {
+		t.Fatalf("body = %q, want %q", body, "acme typed")
+	}
+}
@@ -0,0 +1,76 @@
+package providers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/lemmego/api/app"
+	"github.com/lemmego/api/cache"
+	"github.com/lemmego/api/config"
+)
+
+func init() {
+	app.RegisterService(func(a app.App) error {
+		cacheDriver, _ := config.Get("cache.driver", cache.DRIVER_MEMORY).(string)
+
+		switch cacheDriver {
+		case cache.DRIVER_MEMORY:
+			cache.Set(cache.NewMemoryStore())
+		case cache.DRIVER_REDIS:
+			conn, _ := config.Get("cache.connection", "default").(string)
+			addr, err := redisAddr(conn)
+			if err != nil {
+				return err
+			}
+			pool := &redis.Pool{
+				MaxIdle: 10,
+				Dial: func() (redis.Conn, error) {
+					conn, err := redis.Dial("tcp", addr)
+					if err != nil {
+						return nil, fmt.Errorf("failed to connect to redis: %v", err)
+					}
+					return conn, err
+				},
+			}
+			prefix, _ := config.Get("cache.prefix", "").(string)
+			cache.Set(cache.NewRedisStore(pool, prefix))
+		default:
+			return fmt.Errorf("cache: unsupported driver %v", config.Get("cache.driver"))
+		}
+
+		a.AddService(cache.Get())
+		return nil
+	})
+}
+
+// redisAddr returns the address of the redis.connections.<conn> connection,
+// defaulting to 127.0.0.1:6379. The port may be an int or a string, as
+// loaded from the environment.
+func redisAddr(conn string) (string, error) {
+	if conn == "" {
+		conn = "default"
+	}
+	host, _ := config.Get("redis.connections."+conn+".host", "127.0.0.1").(string)
+	if host == "" {
+		host = "127.0.0.1"
+	}
+
+	port := 6379
+	switch v := config.Get("redis.connections." + conn + ".port").(type) {
+	case nil:
+	case int:
+		port = v
+	case string:
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			return "", fmt.Errorf("cache: invalid redis port %q", v)
+		}
+		port = p
+	default:
+		return "", fmt.Errorf("cache: invalid redis port %v", v)
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(port)), nil
+}
@@ -0,0 +1,40 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/lemmego/api/config"
+)
+
+func TestRedisAddr(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    config.M
+		conn    string
+		want    string
+		wantErr bool
+	}{
+		{"defaults", config.M{}, "default", "127.0.0.1:6379", false},
+		{"int port", config.M{"default.host": "redis", "default.port": 6380}, "default", "redis:6380", false},
+		{"port from env", config.M{"default.host": "redis", "default.port": "6381"}, "default", "redis:6381", false},
+		{"named connection", config.M{"cache.host": "cache.internal", "cache.port": 7000}, "cache", "cache.internal:7000", false},
+		{"empty connection is default", config.M{"default.port": 6390}, "", "127.0.0.1:6390", false},
+		{"ipv6 host", config.M{"default.host": "::1"}, "default", "[::1]:6379", false},
+		{"bad port", config.M{"default.port": "redis"}, "default", "", true},
+		{"wrong port type", config.M{"default.port": 6379.5}, "default", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.conf {
+				config.Set("redis.connections."+key, value)
+				defer config.Set("redis.connections."+key, nil)
+			}
+
+			got, err := redisAddr(tt.conn)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("redisAddr(%q) = %q, %v, want %q, error %v", tt.conn, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package providers
 
 import (
+	"errors"
 	"fmt"
 	"github.com/alexedwards/scs/redisstore"
 	"github.com/alexedwards/scs/v2"
@@ -8,8 +9,11 @@ import (
 	"github.com/gomodule/redigo/redis"
 	"github.com/lemmego/api/app"
 	"github.com/lemmego/api/config"
+	"github.com/lemmego/api/db"
 	"github.com/lemmego/api/session"
 	"net/http"
+	"os"
+	"time"
 )
 
 func init() {
@@ -31,7 +35,28 @@ func init() {
 		}
 
 		if sessionDriver == session.DRIVER_FILE {
-			session.Set(session.NewFileSession(sessionConfig.(config.M)["files"].(string)), cookie)
+			var opts []session.FileStoreOption
+			if encrypt, _ := sessionConfig.(config.M)["encrypt"].(bool); encrypt {
+				key := os.Getenv("APP_KEY")
+				if key == "" {
+					return errors.New("session: APP_KEY must be set to encrypt sessions")
+				}
+				opts = append(opts, session.WithEncryption([]byte(key)))
+			}
+			if interval, ok := sessionConfig.(config.M)["gc_interval"].(time.Duration); ok {
+				opts = append(opts, session.WithGCInterval(interval))
+			}
+			session.Set(session.NewFileSession(sessionConfig.(config.M)["files"].(string), opts...), cookie)
+		}
+
+		if sessionDriver == session.DRIVER_DATABASE {
+			table, _ := sessionConfig.(config.M)["table"].(string)
+			autoMigrate, _ := sessionConfig.(config.M)["auto_migrate"].(bool)
+			store, err := session.NewDatabaseSession(db.DB(), table, autoMigrate)
+			if err != nil {
+				return err
+			}
+			session.Set(store, cookie)
 		}
 
 		if sessionDriver == session.DRIVER_REDIS {
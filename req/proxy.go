@@ -0,0 +1,105 @@
+package req
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// IsTrustedProxy reports whether the immediate peer of the request is one of
+// the trusted proxies. Entries may be single IPs or CIDR ranges.
+func IsTrustedProxy(r *http.Request, trustedProxies []string) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return ipMatches(net.ParseIP(host), trustedProxies)
+}
+
+// Scheme returns "https" or "http" for the request. X-Forwarded-Proto is
+// only honored when the request comes from a trusted proxy.
+func Scheme(r *http.Request, trustedProxies []string) string {
+	if r.TLS != nil {
+		return "https"
+	}
+
+	if IsTrustedProxy(r, trustedProxies) {
+		proto, _, _ := strings.Cut(r.Header.Get("X-Forwarded-Proto"), ",")
+		if proto = strings.ToLower(strings.TrimSpace(proto)); proto == "https" || proto == "http" {
+			return proto
+		}
+	}
+
+	return "http"
+}
+
+// IsSecure reports whether the request was made over TLS, either directly
+// or as indicated by a trusted proxy
+func IsSecure(r *http.Request, trustedProxies []string) bool {
+	return Scheme(r, trustedProxies) == "https"
+}
+
+// ClientIP returns the IP address of the client. When the immediate peer is
+// a trusted proxy, X-Forwarded-For is walked from the right and the first
+// address that is not a trusted proxy is returned, falling back to
+// X-Real-IP; otherwise the peer address is used.
+func ClientIP(r *http.Request, trustedProxies []string) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	if !ipMatches(net.ParseIP(peer), trustedProxies) {
+		return peer
+	}
+
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(header, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(hops[i])
+		if ip == nil {
+			break
+		}
+		if i == 0 || !ipMatches(ip, trustedProxies) {
+			return ip.String()
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+
+	return peer
+}
+
+// IPMatches reports whether ip equals one of the entries, which may be
+// single IPs or CIDR ranges
+func IPMatches(ip string, entries []string) bool {
+	return ipMatches(net.ParseIP(ip), entries)
+}
+
+func ipMatches(ip net.IP, entries []string) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			if _, network, err := net.ParseCIDR(entry); err == nil && network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if other := net.ParseIP(entry); other != nil && other.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net/http"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/romsar/gonertia"
@@ -19,6 +20,53 @@ import (
 
 const InKey = "input"
 
+var errTrailingData = errors.New("req: trailing data after JSON value")
+
+// JSONUnmarshalHook returns the decoder that replaces encoding/json for
+// request bodies in DecodeJSONBody, or nil to use encoding/json. The app
+// package points it at app.JSONUnmarshal once that is replaced, which is
+// the hook to set. The
+// replacement is responsible for its own unknown-field handling; the default
+// decoder rejects unknown fields unless DecodeOptions.AllowUnknownFields is
+// set.
+var JSONUnmarshalHook = func() func(data []byte, v any) error {
+	return nil
+}
+
+// MaxBodySize returns the largest request body, in bytes, that
+// DecodeJSONBody reads before failing with a 413. The app package points it
+// at the app.max_body_size config value.
+var MaxBodySize = func() int64 {
+	return 1 << 20
+}
+
+// BodyTooLarge returns the 413 error for a request body over limit bytes
+func BodyTooLarge(limit int64) *MalformedRequest {
+	size := fmt.Sprintf("%d bytes", limit)
+	switch {
+	case limit >= 1<<20 && limit%(1<<20) == 0:
+		size = fmt.Sprintf("%dMB", limit>>20)
+	case limit >= 1<<10 && limit%(1<<10) == 0:
+		size = fmt.Sprintf("%dKB", limit>>10)
+	}
+	msg := "Request body must not be larger than " + size
+	return &MalformedRequest{Status: http.StatusRequestEntityTooLarge, Message: msg}
+}
+
+// DecodeOptions tunes how JSON request bodies are decoded
+type DecodeOptions struct {
+	// AllowUnknownFields accepts body fields that have no matching struct
+	// field instead of rejecting the request
+	AllowUnknownFields bool
+
+	// MaxDepth rejects bodies whose objects and arrays nest deeper than
+	// this. Zero means no limit.
+	MaxDepth int
+}
+
+var errTooDeep = errors.New("req: JSON nesting too deep")
+var errRootMismatch = errors.New("req: JSON root does not match the target")
+
 type Validator interface {
 	Validate() error
 }
@@ -47,34 +95,183 @@ func (mr *MalformedRequest) Error() string {
 	return mr.Message
 }
 
+// mediaRange is a single entry of an Accept header
+type mediaRange struct {
+	typ     string
+	subtype string
+	q       float64
+}
+
+// parseAccept splits an Accept header into its media ranges, dropping
+// malformed entries and parameters other than the quality value
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+		typ, subtype, ok := strings.Cut(mediaType, "/")
+		if !ok || typ == "" || subtype == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.ToLower(strings.TrimSpace(key)) != "q" {
+				continue
+			}
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+
+		ranges = append(ranges, mediaRange{typ: typ, subtype: subtype, q: q})
+	}
+
+	return ranges
+}
+
+// isJSONMediaType reports whether the type/subtype pair denotes JSON,
+// including structured syntax suffixes like application/vnd.api+json
+func isJSONMediaType(typ, subtype string) bool {
+	return typ == "application" && (subtype == "json" || strings.HasSuffix(subtype, "+json"))
+}
+
+// WantsJSON reports whether the client explicitly accepts a JSON response
 func WantsJSON(r *http.Request) bool {
-	accept := r.Header.Get("Accept")
-	return strings.HasSuffix(accept, "json")
+	for _, mr := range parseAccept(r.Header.Get("Accept")) {
+		if mr.q > 0 && isJSONMediaType(mr.typ, mr.subtype) {
+			return true
+		}
+	}
+	return false
 }
 
+// WantsHTML reports whether the client explicitly accepts an HTML response
 func WantsHTML(r *http.Request) bool {
+	for _, mr := range parseAccept(r.Header.Get("Accept")) {
+		if mr.q <= 0 {
+			continue
+		}
+		if (mr.typ == "text" && mr.subtype == "html") || (mr.typ == "application" && mr.subtype == "xhtml+xml") {
+			return true
+		}
+	}
+	return false
+}
+
+// Accepts returns the offered media type the client prefers according to
+// the Accept header, or "" if none is acceptable. More specific ranges
+// override wildcards, and ties go to the earlier offer. Without an Accept
+// header the first offer is returned.
+func Accepts(r *http.Request, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+
 	accept := r.Header.Get("Accept")
-	return strings.Contains(accept, "text/html")
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		typ, subtype, ok := strings.Cut(strings.ToLower(offer), "/")
+		if !ok {
+			continue
+		}
+
+		// The most specific matching range decides the offer's quality
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			s := -1
+			switch {
+			case mr.typ == typ && mr.subtype == subtype:
+				s = 2
+			case mr.typ == typ && mr.subtype == "*":
+				s = 1
+			case mr.typ == "*" && mr.subtype == "*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+
+	return best
+}
+
+// WantsType reports whether the client explicitly accepts the given media
+// type, ignoring wildcard ranges
+func WantsType(r *http.Request, mediaType string) bool {
+	typ, subtype, ok := strings.Cut(strings.ToLower(mediaType), "/")
+	if !ok {
+		return false
+	}
+	for _, mr := range parseAccept(r.Header.Get("Accept")) {
+		if mr.q > 0 && mr.typ == typ && mr.subtype == subtype {
+			return true
+		}
+	}
+	return false
 }
 
-func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, opts ...DecodeOptions) error {
+	var o DecodeOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return decodeJSONBody(w, r, dst, o, false)
+}
+
+// DecodeJSONInto is DecodeJSONBody for endpoints whose body may be a
+// top-level array, e.g. bulk creation. The body's root must match dst: an
+// array for a slice or array target, an object otherwise. Unknown-field,
+// size and trailing-data checks still apply.
+func DecodeJSONInto(w http.ResponseWriter, r *http.Request, dst interface{}, opts ...DecodeOptions) error {
+	var o DecodeOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	return decodeJSONBody(w, r, dst, o, true)
+}
+
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}, o DecodeOptions, anyRoot bool) error {
 	if r.Header.Get("Content-Type") != "" {
 		value, _ := header.ParseValueAndParams(r.Header, "Content-Type")
-		if value != "application/json" {
+		typ, subtype, _ := strings.Cut(strings.ToLower(value), "/")
+		if !isJSONMediaType(typ, subtype) {
 			msg := "Content-Type header is not application/json"
 			return &MalformedRequest{Status: http.StatusUnsupportedMediaType, Message: msg}
 		}
 	}
-	r.Body = http.MaxBytesReader(w, r.Body, 1048576)
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodySize())
 	bodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return BodyTooLarge(tooLarge.Limit)
+		}
 		return &MalformedRequest{Status: http.StatusBadRequest, Message: err.Error()}
 	}
 
-	dec := json.NewDecoder(bytes.NewReader(bodyBytes))
-	dec.DisallowUnknownFields()
-
-	err = dec.Decode(&dst)
+	switch {
+	case anyRoot && !rootMatches(bodyBytes, dst):
+		err = errRootMismatch
+	case o.MaxDepth > 0 && jsonDepth(bodyBytes) > o.MaxDepth:
+		err = errTooDeep
+	case JSONUnmarshalHook() != nil:
+		err = JSONUnmarshalHook()(bodyBytes, dst)
+	default:
+		err = decodeStrict(bodyBytes, dst, !o.AllowUnknownFields)
+	}
 
 	// Repopulate the body for potential future-streaming from the buffer.
 	r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
@@ -110,42 +307,136 @@ func DecodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) err
 			msg := "Request body must not be empty"
 			return &MalformedRequest{Status: http.StatusBadRequest, Message: msg}
 
-		case err.Error() == "http: request body too large":
-			msg := "Request body must not be larger than 1MB"
-			return &MalformedRequest{Status: http.StatusRequestEntityTooLarge, Message: msg}
+		case errors.Is(err, errTrailingData):
+			msg := "Request body must only contain a single JSON object"
+			if anyRoot {
+				msg = "Request body must only contain a single JSON value"
+			}
+			return &MalformedRequest{Status: http.StatusBadRequest, Message: msg}
+
+		case errors.Is(err, errRootMismatch):
+			msg := "Request body must be a JSON object"
+			if isListTarget(dst) {
+				msg = "Request body must be a JSON array"
+			}
+			return &MalformedRequest{Status: http.StatusBadRequest, Message: msg}
+
+		case errors.Is(err, errTooDeep):
+			msg := fmt.Sprintf("Request body must not nest deeper than %d levels", o.MaxDepth)
+			return &MalformedRequest{Status: http.StatusBadRequest, Message: msg}
 
 		default:
 			return err
 		}
 	}
 
-	err = dec.Decode(&struct{}{})
-	if err != io.EOF {
-		msg := "Request body must only contain a single JSON object"
-		return &MalformedRequest{Status: http.StatusBadRequest, Message: msg}
+	return nil
+}
+
+// rootMatches reports whether the first token of data is an array for a
+// slice or array target, or an object for any other target. Empty bodies
+// pass so the decoder reports them.
+func rootMatches(data []byte, dst interface{}) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	if len(trimmed) == 0 || isInterfaceTarget(dst) {
+		return true
+	}
+	if isListTarget(dst) {
+		return trimmed[0] == '['
+	}
+	return trimmed[0] == '{'
+}
+
+func isInterfaceTarget(dst interface{}) bool {
+	t := reflect.TypeOf(dst)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && t.Kind() == reflect.Interface
+}
+
+func isListTarget(dst interface{}) bool {
+	t := reflect.TypeOf(dst)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array)
+}
+
+// decodeStrict decodes data into dst with encoding/json, rejecting anything
+// following the first JSON value and, if disallowUnknown, unknown fields
+func decodeStrict(data []byte, dst interface{}, disallowUnknown bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if disallowUnknown {
+		dec.DisallowUnknownFields()
+	}
+
+	if err := dec.Decode(dst); err != nil {
+		return err
+	}
+
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return errTrailingData
 	}
 
 	return nil
 }
 
+// jsonDepth returns the deepest object/array nesting in data, ignoring
+// brackets inside strings
+func jsonDepth(data []byte) int {
+	depth, maxDepth := 0, 0
+	inString, escaped := false, false
+
+	for _, b := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && b == '\\':
+			escaped = true
+		case b == '"':
+			inString = !inString
+		case inString:
+		case b == '{' || b == '[':
+			depth++
+			maxDepth = max(maxDepth, depth)
+		case b == '}' || b == ']':
+			depth--
+		}
+	}
+
+	return maxDepth
+}
+
 func HasFormData(r *http.Request) bool {
 	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
 }
 
 func ParseInput(rr RequestResponder, inputStruct any, opts ...core.Option) error {
+	return ParseInputWith(rr, inputStruct, DecodeOptions{}, opts...)
+}
+
+// ParseInputWith is ParseInput with control over JSON body decoding
+func ParseInputWith(rr RequestResponder, inputStruct any, decodeOpts DecodeOptions, opts ...core.Option) error {
 	if !HasFormData(rr.Request()) && (WantsJSON(rr.Request()) || gonertia.IsInertiaRequest(rr.Request())) {
-		if err := DecodeJSONBody(rr.ResponseWriter(), rr.Request(), inputStruct); err != nil {
+		if err := DecodeJSONBody(rr.ResponseWriter(), rr.Request(), inputStruct, decodeOpts); err != nil {
 			return err
 		}
 		return nil
 	}
+	return DecodeTagged(rr.Request(), inputStruct, opts...)
+}
+
+// DecodeTagged decodes the request into inputStruct according to its httpin
+// `in` tags, e.g. `in:"query=page"`, regardless of the request's content type
+func DecodeTagged(r *http.Request, inputStruct any, opts ...core.Option) error {
 	co, err := httpin.New(inputStruct, opts...)
 
 	if err != nil {
 		return err
 	}
 
-	input, err := co.Decode(rr.Request())
+	input, err := co.Decode(r)
 	if err != nil {
 		return err
 	}
@@ -155,9 +446,32 @@ func ParseInput(rr RequestResponder, inputStruct any, opts ...core.Option) error
 	return nil
 }
 
+// HasInTags reports whether the struct v points to has fields with httpin
+// `in` tags
+func HasInTags(v any) bool {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if _, ok := t.Field(i).Tag.Lookup("in"); ok {
+			return true
+		}
+	}
+	return false
+}
+
 func In(c Context, inputStruct any, opts ...core.Option) error {
+	return InWith(c, inputStruct, DecodeOptions{}, opts...)
+}
+
+// InWith is In with control over JSON body decoding
+func InWith(c Context, inputStruct any, decodeOpts DecodeOptions, opts ...core.Option) error {
 	if WantsJSON(c.Request()) || gonertia.IsInertiaRequest(c.Request()) {
-		if err := DecodeJSONBody(c.ResponseWriter(), c.Request(), inputStruct); err != nil {
+		if err := DecodeJSONBody(c.ResponseWriter(), c.Request(), inputStruct, decodeOpts); err != nil {
 			return err
 		}
 		c.Set(InKey, inputStruct)
@@ -0,0 +1,319 @@
+package req
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func requestWithAccept(accept string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if accept != "" {
+		r.Header.Set("Accept", accept)
+	}
+	return r
+}
+
+func TestWantsJSONAndHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		accept   string
+		wantJSON bool
+		wantHTML bool
+	}{
+		{"none", "", false, false},
+		{"any", "*/*", false, false},
+		{"json", "application/json", true, false},
+		{"json with charset", "application/json; charset=utf-8", true, false},
+		{"vendor json", "application/vnd.api+json", true, false},
+		{"axios", "application/json, text/plain, */*", true, false},
+		{"chrome", "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8", false, true},
+		{"firefox", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", false, true},
+		{"xhtml only", "application/xhtml+xml", false, true},
+		{"uppercase", "Application/JSON", true, false},
+		{"json refused", "application/json;q=0, text/html", false, true},
+		{"both", "text/html;q=0.9, application/json", true, true},
+		{"malformed entries", "json, /html, text/, application/json", true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := requestWithAccept(tt.accept)
+			if got := WantsJSON(r); got != tt.wantJSON {
+				t.Errorf("WantsJSON(%q) = %v, want %v", tt.accept, got, tt.wantJSON)
+			}
+			if got := WantsHTML(r); got != tt.wantHTML {
+				t.Errorf("WantsHTML(%q) = %v, want %v", tt.accept, got, tt.wantHTML)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		offers []string
+		want   string
+	}{
+		{"no header takes first offer", "", []string{"text/html", "application/json"}, "text/html"},
+		{"no offers", "application/json", nil, ""},
+		{"exact", "application/json", []string{"text/html", "application/json"}, "application/json"},
+		{"comma separated picks higher q", "text/html;q=0.5, application/json", []string{"text/html", "application/json"}, "application/json"},
+		{"tie goes to earlier offer", "application/json, text/html", []string{"text/html", "application/json"}, "text/html"},
+		{"type wildcard", "text/*", []string{"application/json", "text/csv"}, "text/csv"},
+		{"any wildcard", "*/*", []string{"application/xml"}, "application/xml"},
+		{"specific overrides wildcard", "*/*, text/html;q=0", []string{"text/html", "text/plain"}, "text/plain"},
+		{"nothing acceptable", "image/png", []string{"text/html", "application/json"}, ""},
+		{"vendor type", "application/vnd.api+json", []string{"application/json", "application/vnd.api+json"}, "application/vnd.api+json"},
+		{"vendor type not plain json", "application/vnd.api+json", []string{"application/json"}, ""},
+		{"vendor with params", "application/vnd.github.v3+json; charset=utf-8; q=0.8, text/plain;q=0.2", []string{"text/plain", "application/vnd.github.v3+json"}, "application/vnd.github.v3+json"},
+		{"case insensitive", "Application/JSON", []string{"application/json"}, "application/json"},
+		{"malformed offer skipped", "*/*", []string{"json", "text/plain"}, "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Accepts(requestWithAccept(tt.accept), tt.offers...); got != tt.want {
+				t.Errorf("Accepts(%q, %v) = %q, want %q", tt.accept, tt.offers, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWantsType(t *testing.T) {
+	tests := []struct {
+		accept    string
+		mediaType string
+		want      bool
+	}{
+		{"application/vnd.api+json", "application/vnd.api+json", true},
+		{"text/html, application/vnd.api+json;q=0.9", "application/vnd.api+json", true},
+		{"application/vnd.api+json;q=0", "application/vnd.api+json", false},
+		{"application/json", "application/vnd.api+json", false},
+		{"*/*", "application/vnd.api+json", false},
+		{"application/*", "application/json", false},
+		{"TEXT/CSV", "text/csv", true},
+		{"text/csv", "csv", false},
+	}
+
+	for _, tt := range tests {
+		if got := WantsType(requestWithAccept(tt.accept), tt.mediaType); got != tt.want {
+			t.Errorf("WantsType(%q, %q) = %v, want %v", tt.accept, tt.mediaType, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeJSONBodyTooLarge(t *testing.T) {
+	defer func(orig func() int64) { MaxBodySize = orig }(MaxBodySize)
+	MaxBodySize = func() int64 { return 2048 }
+
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"`+strings.Repeat("x", 4096)+`"}`))
+	r.Header.Set("Content-Type", "application/json")
+
+	var dst struct{ Name string }
+	err := DecodeJSONBody(httptest.NewRecorder(), r, &dst)
+
+	var mr *MalformedRequest
+	if !errors.As(err, &mr) || mr.Status != http.StatusRequestEntityTooLarge {
+		t.Fatalf("DecodeJSONBody() error = %v, want a 413 MalformedRequest", err)
+	}
+	if mr.Message != "Request body must not be larger than 2KB" {
+		t.Errorf("message = %q", mr.Message)
+	}
+}
+
+func TestBodyTooLargeMessage(t *testing.T) {
+	tests := map[int64]string{
+		1 << 20: "1MB",
+		8 << 20: "8MB",
+		512:     "512 bytes",
+		3 << 10: "3KB",
+		1536:    "1536 bytes",
+	}
+	for limit, size := range tests {
+		if got := BodyTooLarge(limit).Message; got != "Request body must not be larger than "+size {
+			t.Errorf("BodyTooLarge(%d) = %q", limit, got)
+		}
+	}
+}
+
+func jsonBodyRequest(body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
+// malformedMessage returns the message of a *MalformedRequest, or fails
+func malformedMessage(t *testing.T, err error) string {
+	t.Helper()
+	var mr *MalformedRequest
+	if !errors.As(err, &mr) {
+		t.Fatalf("error = %v, want a *MalformedRequest", err)
+	}
+	if mr.Status != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", mr.Status)
+	}
+	return mr.Message
+}
+
+type decodeTarget struct {
+	Name string `json:"name"`
+	Tags []struct {
+		Label string `json:"label"`
+	} `json:"tags"`
+}
+
+func TestDecodeOptionsUnknownFields(t *testing.T) {
+	const body = `{"name":"john","role":"admin"}`
+
+	var strict decodeTarget
+	err := DecodeJSONBody(httptest.NewRecorder(), jsonBodyRequest(body), &strict)
+	if msg := malformedMessage(t, err); msg != `Request body contains unknown field "role"` {
+		t.Errorf("strict message = %q", msg)
+	}
+
+	var lenient decodeTarget
+	err = DecodeJSONBody(httptest.NewRecorder(), jsonBodyRequest(body), &lenient, DecodeOptions{AllowUnknownFields: true})
+	if err != nil || lenient.Name != "john" {
+		t.Errorf("lenient decode = %+v, %v", lenient, err)
+	}
+}
+
+func TestDecodeOptionsMaxDepth(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		maxDepth int
+		ok       bool
+	}{
+		{"no limit", `{"tags":[{"label":"a"}]}`, 0, true},
+		{"within limit", `{"tags":[{"label":"a"}]}`, 3, true},
+		{"over limit", `{"tags":[{"label":"a"}]}`, 2, false},
+		{"brackets in strings ignored", `{"name":"[[{{\"]]"}`, 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var dst decodeTarget
+			err := DecodeJSONBody(httptest.NewRecorder(), jsonBodyRequest(tt.body), &dst, DecodeOptions{MaxDepth: tt.maxDepth})
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("DecodeJSONBody() = %v", err)
+				}
+				return
+			}
+			if msg := malformedMessage(t, err); msg != "Request body must not nest deeper than 2 levels" {
+				t.Errorf("message = %q", msg)
+			}
+		})
+	}
+}
+
+func TestDecodeOptionsStillRejectTrailingData(t *testing.T) {
+	var dst decodeTarget
+	err := DecodeJSONBody(httptest.NewRecorder(), jsonBodyRequest(`{"name":"a"}{"name":"b"}`), &dst, DecodeOptions{AllowUnknownFields: true})
+	if msg := malformedMessage(t, err); msg != "Request body must only contain a single JSON object" {
+		t.Errorf("message = %q", msg)
+	}
+}
+
+func TestDecodeJSONBodyContentTypes(t *testing.T) {
+	tests := []struct {
+		contentType string
+		ok          bool
+	}{
+		{"", true},
+		{"application/json", true},
+		{"application/json; charset=utf-8", true},
+		{"Application/JSON;charset=UTF-8", true},
+		{"application/vnd.api+json", true},
+		{"application/vnd.github.v3+json; charset=utf-8", true},
+		{"application/problem+json", true},
+		{"text/plain", false},
+		{"application/xml", false},
+		{"application/jsonp", false},
+		{"text/json+xml", false},
+		{"application/x-www-form-urlencoded", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"john"}`))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			var dst struct{ Name string }
+			err := DecodeJSONBody(httptest.NewRecorder(), r, &dst)
+			if tt.ok {
+				if err != nil || dst.Name != "john" {
+					t.Errorf("DecodeJSONBody() = %+v, %v", dst, err)
+				}
+				return
+			}
+
+			var mr *MalformedRequest
+			if !errors.As(err, &mr) || mr.Status != http.StatusUnsupportedMediaType {
+				t.Errorf("DecodeJSONBody() error = %v, want a 415 MalformedRequest", err)
+			}
+		})
+	}
+}
+
+func TestDecodeJSONIntoArrayRoot(t *testing.T) {
+	var list []decodeTarget
+	err := DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(`[{"name":"a"},{"name":"b"}]`), &list)
+	if err != nil || len(list) != 2 || list[0].Name != "a" || list[1].Name != "b" {
+		t.Fatalf("DecodeJSONInto() = %+v, %v", list, err)
+	}
+
+	var fixed [1]decodeTarget
+	if err := DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(` [{"name":"a"}]`), &fixed); err != nil || fixed[0].Name != "a" {
+		t.Errorf("DecodeJSONInto() into an array = %+v, %v", fixed, err)
+	}
+
+	var one decodeTarget
+	if err := DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(`{"name":"a"}`), &one); err != nil || one.Name != "a" {
+		t.Errorf("DecodeJSONInto() into a struct = %+v, %v", one, err)
+	}
+}
+
+func TestDecodeJSONIntoRootMismatch(t *testing.T) {
+	var list []decodeTarget
+	err := DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(`{"name":"a"}`), &list)
+	if msg := malformedMessage(t, err); msg != "Request body must be a JSON array" {
+		t.Errorf("object into slice message = %q", msg)
+	}
+
+	var one decodeTarget
+	err = DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(`[{"name":"a"}]`), &one)
+	if msg := malformedMessage(t, err); msg != "Request body must be a JSON object" {
+		t.Errorf("array into struct message = %q", msg)
+	}
+}
+
+func TestDecodeJSONIntoTrailingData(t *testing.T) {
+	for _, body := range []string{`[{"name":"a"}][]`, `[{"name":"a"}] garbage`, `[{"name":"a"}],`} {
+		var list []decodeTarget
+		err := DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(body), &list)
+		if msg := malformedMessage(t, err); msg != "Request body must only contain a single JSON value" {
+			t.Errorf("%s: message = %q", body, msg)
+		}
+	}
+}
+
+func TestDecodeJSONIntoStrictFields(t *testing.T) {
+	var list []decodeTarget
+	err := DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(`[{"name":"a","role":"admin"}]`), &list)
+	if msg := malformedMessage(t, err); msg != `Request body contains unknown field "role"` {
+		t.Errorf("message = %q", msg)
+	}
+
+	err = DecodeJSONInto(httptest.NewRecorder(), jsonBodyRequest(`[{"name":"a","role":"admin"}]`), &list, DecodeOptions{AllowUnknownFields: true})
+	if err != nil || len(list) != 1 {
+		t.Errorf("lenient DecodeJSONInto() = %+v, %v", list, err)
+	}
+}
@@ -0,0 +1,61 @@
+package req
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errInvalidRetryAfter = errors.New("req: invalid Retry-After value")
+
+// FormatRetryAfter formats d as a Retry-After delta-seconds value, rounding
+// up to whole seconds and clamping negative durations to zero
+func FormatRetryAfter(d time.Duration) string {
+	if d <= 0 {
+		return "0"
+	}
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// ParseRetryAfter parses a Retry-After value in either delta-seconds
+// ("120") or HTTP-date ("Wed, 21 Oct 2015 07:28:00 GMT") form and returns
+// how long to wait from now. Dates in the past yield zero.
+func ParseRetryAfter(value string) (time.Duration, error) {
+	return parseRetryAfter(value, time.Now())
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, errInvalidRetryAfter
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, errInvalidRetryAfter
+		}
+		if seconds > math.MaxInt64/int64(time.Second) {
+			return time.Duration(math.MaxInt64), nil
+		}
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0, errInvalidRetryAfter
+	}
+	if d := at.Sub(now); d > 0 {
+		return d, nil
+	}
+	return 0, nil
+}
+
+// RetryAfter returns the wait time from a response's Retry-After header,
+// with ok false if the header is missing or malformed
+func RetryAfter(res *http.Response) (d time.Duration, ok bool) {
+	d, err := ParseRetryAfter(res.Header.Get("Retry-After"))
+	return d, err == nil
+}
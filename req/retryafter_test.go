@@ -0,0 +1,94 @@
+package req
+
+import (
+	"math"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFormatRetryAfter(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0"},
+		{-time.Minute, "0"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+		{2 * time.Minute, "120"},
+	}
+	for _, tt := range tests {
+		if got := FormatRetryAfter(tt.d); got != tt.want {
+			t.Errorf("FormatRetryAfter(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfterDeltaSeconds(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"0", 0},
+		{"120", 2 * time.Minute},
+		{" 5 ", 5 * time.Second},
+		{"99999999999999999", time.Duration(math.MaxInt64)},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetryAfter(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRetryAfter(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfterHTTPDate(t *testing.T) {
+	now := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"Wed, 21 Oct 2015 07:30:00 GMT", 2 * time.Minute},
+		{"Wednesday, 21-Oct-15 07:28:30 GMT", 30 * time.Second},
+		{"Wed Oct 21 08:28:00 2015", time.Hour},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+		{"Tue, 20 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		got, err := parseRetryAfter(tt.value, now)
+		if err != nil || got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseRetryAfterInvalid(t *testing.T) {
+	for _, value := range []string{"", "  ", "-1", "1.5", "soon", "21 Oct 2015"} {
+		if _, err := ParseRetryAfter(value); err == nil {
+			t.Errorf("ParseRetryAfter(%q) err = nil, want an error", value)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	if _, ok := RetryAfter(res); ok {
+		t.Error("RetryAfter() ok = true without the header")
+	}
+
+	res.Header.Set("Retry-After", "30")
+	if d, ok := RetryAfter(res); !ok || d != 30*time.Second {
+		t.Errorf("RetryAfter() = %v, %v, want 30s, true", d, ok)
+	}
+
+	res.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if d, ok := RetryAfter(res); !ok || d <= 59*time.Minute || d > time.Hour {
+		t.Errorf("RetryAfter() = %v, %v, want about an hour", d, ok)
+	}
+
+	res.Header.Set("Retry-After", "later")
+	if _, ok := RetryAfter(res); ok {
+		t.Error("RetryAfter() ok = true for a malformed header")
+	}
+}
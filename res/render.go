@@ -28,6 +28,16 @@ type TemplateData struct {
 	Data             map[string]any
 	ValidationErrors shared.ValidationErrors
 	Messages         []*AlertMessage
+	OldInput         map[string][]string
+}
+
+// Old returns the first flashed value of a form field, for use in templates
+// as {{ .Old "email" }}
+func (td *TemplateData) Old(key string) string {
+	if values := td.OldInput[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
 }
 
 func init() {
@@ -52,6 +62,12 @@ func RenderTemplate(w http.ResponseWriter, tmpl string, data *TemplateData) erro
 func createTemplateCache() (map[string]*template.Template, error) {
 	myCache := map[string]*template.Template{}
 
+	// Apps that don't render templates, and package tests, have no
+	// templates directory
+	if _, err := os.Stat("./templates"); os.IsNotExist(err) {
+		return myCache, nil
+	}
+
 	err := filepath.Walk("./templates", func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
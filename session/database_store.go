@@ -0,0 +1,92 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultTable = "sessions"
+
+// sessionRecord is a row of the sessions table
+type sessionRecord struct {
+	Token  string    `gorm:"primaryKey;size:64"`
+	Data   []byte    `gorm:"not null"`
+	Expiry time.Time `gorm:"not null;index"`
+}
+
+// DatabaseStore is an scs store persisting sessions in a database table
+type DatabaseStore struct {
+	db    *gorm.DB
+	table string
+}
+
+// NewDatabaseSession returns a store backed by the given table ("sessions"
+// if empty), creating the table first when autoMigrate is true
+func NewDatabaseSession(db *gorm.DB, table string, autoMigrate bool) (*DatabaseStore, error) {
+	if table == "" {
+		table = defaultTable
+	}
+
+	ds := &DatabaseStore{db: db, table: table}
+
+	if autoMigrate {
+		if err := db.Table(table).AutoMigrate(&sessionRecord{}); err != nil {
+			return nil, err
+		}
+	}
+
+	return ds, nil
+}
+
+func (ds *DatabaseStore) Find(token string) ([]byte, bool, error) {
+	return ds.FindCtx(context.Background(), token)
+}
+
+func (ds *DatabaseStore) Commit(token string, b []byte, expiry time.Time) error {
+	return ds.CommitCtx(context.Background(), token, b, expiry)
+}
+
+func (ds *DatabaseStore) Delete(token string) error {
+	return ds.DeleteCtx(context.Background(), token)
+}
+
+func (ds *DatabaseStore) FindCtx(ctx context.Context, token string) ([]byte, bool, error) {
+	var record sessionRecord
+
+	err := ds.db.WithContext(ctx).Table(ds.table).
+		Where("token = ? AND expiry > ?", token, time.Now()).
+		Take(&record).Error
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	return record.Data, true, nil
+}
+
+func (ds *DatabaseStore) CommitCtx(ctx context.Context, token string, b []byte, expiry time.Time) error {
+	record := &sessionRecord{Token: token, Data: b, Expiry: expiry}
+
+	return ds.db.WithContext(ctx).Table(ds.table).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "token"}},
+			DoUpdates: clause.AssignmentColumns([]string{"data", "expiry"}),
+		}).
+		Create(record).Error
+}
+
+func (ds *DatabaseStore) DeleteCtx(ctx context.Context, token string) error {
+	return ds.db.WithContext(ctx).Table(ds.table).Where("token = ?", token).Delete(&sessionRecord{}).Error
+}
+
+// Cleanup removes all expired sessions from the table
+func (ds *DatabaseStore) Cleanup(ctx context.Context) error {
+	return ds.db.WithContext(ctx).Table(ds.table).Where("expiry <= ?", time.Now()).Delete(&sessionRecord{}).Error
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Both interfaces scs uses to talk to the store
+var (
+	_ scs.Store    = (*DatabaseStore)(nil)
+	_ scs.CtxStore = (*DatabaseStore)(nil)
+)
+
+func newTestDatabaseStore(t *testing.T) *DatabaseStore {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to :memory: opens a separate database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	ds, err := NewDatabaseSession(db, "test_sessions", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func TestDatabaseStoreCommitAndFind(t *testing.T) {
+	ds := newTestDatabaseStore(t)
+
+	if err := ds.Commit("token", []byte("first"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, found, err := ds.Find("token")
+	if err != nil || !found || !bytes.Equal(data, []byte("first")) {
+		t.Fatalf("Find() = %q, %v, %v; want first", data, found, err)
+	}
+
+	// Committing an existing token replaces its data
+	if err := ds.Commit("token", []byte("second"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	data, found, _ = ds.Find("token")
+	if !found || !bytes.Equal(data, []byte("second")) {
+		t.Fatalf("Find() after recommit = %q, %v; want second", data, found)
+	}
+}
+
+func TestDatabaseStoreFindMissing(t *testing.T) {
+	ds := newTestDatabaseStore(t)
+
+	data, found, err := ds.Find("missing")
+	if err != nil || found || data != nil {
+		t.Fatalf("Find() = %q, %v, %v; want not found", data, found, err)
+	}
+}
+
+func TestDatabaseStoreExpired(t *testing.T) {
+	ds := newTestDatabaseStore(t)
+	ds.Commit("expired", []byte("a"), time.Now().Add(-time.Minute))
+	ds.Commit("live", []byte("b"), time.Now().Add(time.Hour))
+
+	if _, found, err := ds.Find("expired"); found || err != nil {
+		t.Fatalf("Find(expired) = %v, %v; want not found", found, err)
+	}
+
+	if err := ds.Cleanup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int64
+	ds.db.Table(ds.table).Count(&count)
+	if count != 1 {
+		t.Fatalf("rows after Cleanup = %d, want 1", count)
+	}
+	if _, found, _ := ds.Find("live"); !found {
+		t.Fatal("Cleanup removed a live session")
+	}
+}
+
+func TestDatabaseStoreDelete(t *testing.T) {
+	ds := newTestDatabaseStore(t)
+	ds.Commit("token", []byte("a"), time.Now().Add(time.Hour))
+
+	if err := ds.Delete("token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, found, _ := ds.Find("token"); found {
+		t.Fatal("session found after Delete")
+	}
+	if err := ds.Delete("token"); err != nil {
+		t.Fatalf("deleting a missing token = %v, want nil", err)
+	}
+}
+
+func TestDatabaseStoreWithSessionManager(t *testing.T) {
+	ds := newTestDatabaseStore(t)
+	manager := scs.New()
+	manager.Store = ds
+
+	ctx, err := manager.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager.Put(ctx, "user_id", 42)
+	token, _, err := manager.Commit(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, err = manager.Load(context.Background(), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := manager.GetInt(ctx, "user_id"); got != 42 {
+		t.Fatalf("user_id = %d, want 42", got)
+	}
+}
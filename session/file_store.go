@@ -1,25 +1,62 @@
 package session
 
 import (
+	"bufio"
 	"encoding/base64"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/lemmego/api/encryption"
 )
 
-const defaultDir = "storage/session"
+const (
+	defaultDir        = "storage/session"
+	defaultGCInterval = 30 * time.Minute
+)
 
 type FileStore struct {
-	dir string
+	dir        string
+	encrypter  *encryption.Encrypter
+	gcInterval time.Duration
+	stopGC     chan struct{}
+	closeOnce  sync.Once
+}
+
+type FileStoreOption func(fs *FileStore) error
+
+// WithEncryption encrypts session data at rest with the given key
+func WithEncryption(key []byte) FileStoreOption {
+	return func(fs *FileStore) error {
+		e, err := encryption.New(key)
+		if err != nil {
+			return err
+		}
+		fs.encrypter = e
+		return nil
+	}
+}
+
+// WithGCInterval sets how often expired session files are removed.
+// A non-positive interval disables the background collection.
+func WithGCInterval(interval time.Duration) FileStoreOption {
+	return func(fs *FileStore) error {
+		fs.gcInterval = interval
+		return nil
+	}
 }
 
 func (fs *FileStore) Delete(token string) error {
 	return os.Remove(filepath.Join(fs.dir, token))
 }
 
+// Find returns the session data for the token. Expired, corrupt or
+// undecryptable session files are treated as missing.
 func (fs *FileStore) Find(token string) ([]byte, bool, error) {
 	filename := filepath.Join(fs.dir, token)
 	f, err := os.Open(filename)
@@ -38,12 +75,12 @@ func (fs *FileStore) Find(token string) ([]byte, bool, error) {
 
 	parts := strings.SplitN(string(data), "|", 2)
 	if len(parts) != 2 {
-		return nil, false, fmt.Errorf("invalid file format")
+		return nil, false, nil
 	}
 
 	expiry, err := time.Parse(time.RFC3339, parts[0])
 	if err != nil {
-		return nil, false, err
+		return nil, false, nil
 	}
 
 	if time.Now().After(expiry) {
@@ -53,18 +90,31 @@ func (fs *FileStore) Find(token string) ([]byte, bool, error) {
 
 	sessionData, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
-		return nil, false, err
+		return nil, false, nil
+	}
+
+	if fs.encrypter != nil {
+		if sessionData, err = fs.encrypter.Decrypt(sessionData); err != nil {
+			return nil, false, nil
+		}
 	}
 
 	return sessionData, true, nil
 }
 
 func (fs *FileStore) Commit(token string, b []byte, expiry time.Time) error {
+	if fs.encrypter != nil {
+		var err error
+		if b, err = fs.encrypter.Encrypt(b); err != nil {
+			return err
+		}
+	}
+
 	data := fmt.Sprintf("%s|%s", expiry.Format(time.RFC3339), base64.StdEncoding.EncodeToString(b))
 	return os.WriteFile(filepath.Join(fs.dir, token), []byte(data), 0644)
 }
 
-func NewFileSession(directoryPath string) *FileStore {
+func NewFileSession(directoryPath string, opts ...FileStoreOption) *FileStore {
 	if directoryPath == "" {
 		directoryPath = defaultDir
 	}
@@ -72,5 +122,85 @@ func NewFileSession(directoryPath string) *FileStore {
 	if err != nil {
 		panic(err)
 	}
-	return &FileStore{dir: directoryPath}
+
+	fs := &FileStore{dir: directoryPath, gcInterval: defaultGCInterval, stopGC: make(chan struct{})}
+	for _, opt := range opts {
+		if err := opt(fs); err != nil {
+			panic(err)
+		}
+	}
+
+	if fs.gcInterval > 0 {
+		go fs.startGC()
+	}
+	return fs
+}
+
+// Close stops the background garbage collection
+func (fs *FileStore) Close() error {
+	fs.closeOnce.Do(func() {
+		close(fs.stopGC)
+	})
+	return nil
+}
+
+func (fs *FileStore) startGC() {
+	ticker := time.NewTicker(fs.gcInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := fs.gc(); err != nil {
+				slog.Error("session: garbage collection failed", "error", err)
+			}
+		case <-fs.stopGC:
+			return
+		}
+	}
+}
+
+// gc removes every session file whose expiry has passed
+func (fs *FileStore) gc() error {
+	entries, err := os.ReadDir(fs.dir)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		filename := filepath.Join(fs.dir, entry.Name())
+		expiry, err := readExpiry(filename)
+		if err != nil {
+			continue
+		}
+
+		if now.After(expiry) {
+			if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// readExpiry parses the expiry prefix of a session file without reading the data
+func readExpiry(filename string) (time.Time, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	prefix, err := bufio.NewReader(f).ReadString('|')
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.Parse(time.RFC3339, strings.TrimSuffix(prefix, "|"))
 }
@@ -0,0 +1,171 @@
+package session
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestFileStore(t *testing.T, opts ...FileStoreOption) *FileStore {
+	t.Helper()
+	fs := NewFileSession(t.TempDir(), append([]FileStoreOption{WithGCInterval(0)}, opts...)...)
+	t.Cleanup(func() { fs.Close() })
+	return fs
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	for name, opts := range map[string][]FileStoreOption{
+		"plain":     nil,
+		"encrypted": {WithEncryption([]byte("app-key"))},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fs := newTestFileStore(t, opts...)
+			data := []byte("user_id=42")
+
+			if err := fs.Commit("token", data, time.Now().Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+
+			got, found, err := fs.Find("token")
+			if err != nil || !found {
+				t.Fatalf("Find() = %v, %v; want found", found, err)
+			}
+			if !bytes.Equal(got, data) {
+				t.Fatalf("Find() = %q, want %q", got, data)
+			}
+		})
+	}
+}
+
+func TestFileStoreEncryptsAtRest(t *testing.T) {
+	fs := newTestFileStore(t, WithEncryption([]byte("app-key")))
+	plain := newTestFileStore(t)
+
+	data := []byte("user_id=42")
+	expiry := time.Now().Add(time.Hour)
+	fs.Commit("token", data, expiry)
+	plain.Commit("token", data, expiry)
+
+	encrypted, _ := os.ReadFile(filepath.Join(fs.dir, "token"))
+	unencrypted, _ := os.ReadFile(filepath.Join(plain.dir, "token"))
+	if bytes.Equal(encrypted, unencrypted) {
+		t.Fatal("encrypted session file matches the plaintext one")
+	}
+}
+
+func TestFileStoreTamperedFileIsMissing(t *testing.T) {
+	fs := newTestFileStore(t, WithEncryption([]byte("app-key")))
+	if err := fs.Commit("token", []byte("user_id=42"), time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	filename := filepath.Join(fs.dir, "token")
+	contents, _ := os.ReadFile(filename)
+	// Flip a character of the base64 payload, keeping it decodable
+	i := len(contents) - 4
+	if contents[i] == 'A' {
+		contents[i] = 'B'
+	} else {
+		contents[i] = 'A'
+	}
+	if err := os.WriteFile(filename, contents, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	data, found, err := fs.Find("token")
+	if err != nil || found || data != nil {
+		t.Fatalf("Find() on tampered file = %q, %v, %v; want missing", data, found, err)
+	}
+}
+
+func TestFileStoreWrongKeyIsMissing(t *testing.T) {
+	dir := t.TempDir()
+	writer := NewFileSession(dir, WithGCInterval(0), WithEncryption([]byte("old-key")))
+	defer writer.Close()
+	writer.Commit("token", []byte("user_id=42"), time.Now().Add(time.Hour))
+
+	reader := NewFileSession(dir, WithGCInterval(0), WithEncryption([]byte("new-key")))
+	defer reader.Close()
+	if _, found, err := reader.Find("token"); found || err != nil {
+		t.Fatalf("Find() with another key = %v, %v; want missing", found, err)
+	}
+}
+
+func TestFileStoreCorruptFileIsMissing(t *testing.T) {
+	fs := newTestFileStore(t)
+	for name, contents := range map[string]string{
+		"no-separator": "garbage",
+		"bad-expiry":   "tomorrow|dXNlcg==",
+		"bad-base64":   time.Now().Add(time.Hour).Format(time.RFC3339) + "|!!!",
+	} {
+		os.WriteFile(filepath.Join(fs.dir, name), []byte(contents), 0644)
+		if _, found, err := fs.Find(name); found || err != nil {
+			t.Errorf("Find(%s) = %v, %v; want missing", name, found, err)
+		}
+	}
+}
+
+func TestFileStoreGCRemovesOnlyExpired(t *testing.T) {
+	fs := newTestFileStore(t)
+	now := time.Now()
+
+	fs.Commit("expired", []byte("a"), now.Add(-time.Minute))
+	fs.Commit("live", []byte("b"), now.Add(time.Hour))
+	os.WriteFile(filepath.Join(fs.dir, "unparseable"), []byte("garbage"), 0644)
+	os.Mkdir(filepath.Join(fs.dir, "subdir"), 0755)
+
+	if err := fs.gc(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]bool{"expired": false, "live": true, "unparseable": true, "subdir": true} {
+		_, err := os.Stat(filepath.Join(fs.dir, name))
+		if exists := err == nil; exists != want {
+			t.Errorf("%s exists = %v, want %v", name, exists, want)
+		}
+	}
+}
+
+func TestFileStoreBackgroundGC(t *testing.T) {
+	fs := NewFileSession(t.TempDir(), WithGCInterval(10*time.Millisecond))
+	defer fs.Close()
+
+	fs.Commit("expired", []byte("a"), time.Now().Add(-time.Minute))
+	fs.Commit("live", []byte("b"), time.Now().Add(time.Hour))
+
+	expired := filepath.Join(fs.dir, "expired")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(expired); os.IsNotExist(err) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expired session was not collected")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := os.Stat(filepath.Join(fs.dir, "live")); err != nil {
+		t.Fatalf("live session was removed: %v", err)
+	}
+}
+
+func TestFileStoreCloseStopsGC(t *testing.T) {
+	fs := NewFileSession(t.TempDir(), WithGCInterval(10*time.Millisecond))
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("second Close() = %v", err)
+	}
+	// Let the collector observe the stop before seeding an expired file
+	time.Sleep(30 * time.Millisecond)
+
+	fs.Commit("expired", []byte("a"), time.Now().Add(-time.Minute))
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(filepath.Join(fs.dir, "expired")); err != nil {
+		t.Fatalf("GC ran after Close: %v", err)
+	}
+}
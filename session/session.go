@@ -1,14 +1,18 @@
 package session
 
 import (
-	"github.com/alexedwards/scs/v2"
+	"context"
+	"net/http"
 	"sync"
+
+	"github.com/alexedwards/scs/v2"
 )
 
 const (
-	DRIVER_MEMORY = "memory" // Not recommended for production
-	DRIVER_FILE   = "file"
-	DRIVER_REDIS  = "redis"
+	DRIVER_MEMORY   = "memory" // Not recommended for production
+	DRIVER_FILE     = "file"
+	DRIVER_REDIS    = "redis"
+	DRIVER_DATABASE = "database"
 )
 
 var session *Session
@@ -36,3 +40,32 @@ func newSession(store scs.Store, cookie scs.SessionCookie) *Session {
 	s.Cookie = cookie
 	return &Session{s}
 }
+
+// loadedKey marks a context whose session data was loaded by a Session
+type loadedKey struct {
+	s *Session
+}
+
+// LoadAndSave wraps scs's LoadAndSave, marking the request context so
+// Loaded can tell the session data is there
+func (s *Session) LoadAndSave(next http.Handler) http.Handler {
+	return s.SessionManager.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), loadedKey{s}, true)))
+	}))
+}
+
+// Load wraps scs's Load, marking the returned context for Loaded
+func (s *Session) Load(ctx context.Context, token string) (context.Context, error) {
+	ctx, err := s.SessionManager.Load(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, loadedKey{s}, true), nil
+}
+
+// Loaded reports whether the session data was loaded into ctx by Load or
+// LoadAndSave. The scs methods panic on a context without it.
+func (s *Session) Loaded(ctx context.Context) bool {
+	loaded, _ := ctx.Value(loadedKey{s}).(bool)
+	return loaded
+}
@@ -0,0 +1,39 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/alexedwards/scs/v2/memstore"
+)
+
+func TestLoaded(t *testing.T) {
+	sess := newSession(memstore.New(), scs.SessionCookie{Name: "session"})
+	other := newSession(memstore.New(), scs.SessionCookie{Name: "other"})
+
+	if sess.Loaded(context.Background()) {
+		t.Fatal("empty context reported loaded")
+	}
+
+	ctx, err := sess.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sess.Loaded(ctx) {
+		t.Fatal("context from Load not reported loaded")
+	}
+	if other.Loaded(ctx) {
+		t.Fatal("another session reported loaded")
+	}
+
+	var loaded bool
+	sess.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		loaded = sess.Loaded(r.Context())
+	})).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !loaded {
+		t.Fatal("request inside LoadAndSave not reported loaded")
+	}
+}
@@ -2,9 +2,59 @@ package shared
 
 import "encoding/json"
 
+// ValidationMessage is the top-level message of a validation failure
+const ValidationMessage = "The given data was invalid."
+
+// ValidationErrors maps each invalid field to its error messages
 type ValidationErrors map[string][]string
 
+// ValidationEnvelope is the one shape validation failures are exposed in:
+// the JSON body of a 422 response, the value flashed to the session under
+// "errors", and the "message" and "errors" Inertia props.
+//
+//	{"message": "The given data was invalid.", "errors": {"email": ["..."]}}
+type ValidationEnvelope struct {
+	Message string           `json:"message"`
+	Errors  ValidationErrors `json:"errors"`
+}
+
 func (e ValidationErrors) Error() string {
 	val, _ := json.Marshal(e)
 	return string(val)
 }
+
+// Envelope wraps the errors in the standard response envelope
+func (e ValidationErrors) Envelope() ValidationEnvelope {
+	if e == nil {
+		e = ValidationErrors{}
+	}
+	return ValidationEnvelope{Message: ValidationMessage, Errors: e}
+}
+
+// Has reports whether the field has any errors
+func (e ValidationErrors) Has(field string) bool {
+	return len(e[field]) > 0
+}
+
+// First returns the field's first error message, or "" if it has none
+func (e ValidationErrors) First(field string) string {
+	if msgs := e[field]; len(msgs) > 0 {
+		return msgs[0]
+	}
+	return ""
+}
+
+// All returns the errors as a plain map
+func (e ValidationErrors) All() map[string][]string {
+	return e
+}
+
+// Any reports whether any field has errors
+func (e ValidationErrors) Any() bool {
+	for _, msgs := range e {
+		if len(msgs) > 0 {
+			return true
+		}
+	}
+	return false
+}
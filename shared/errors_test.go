@@ -0,0 +1,106 @@
+package shared
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidationErrorsHas(t *testing.T) {
+	e := ValidationErrors{"email": {"required"}, "name": {}}
+
+	if !e.Has("email") {
+		t.Error("Has(email) = false")
+	}
+	if e.Has("name") {
+		t.Error("Has(name) = true for an empty message list")
+	}
+	if e.Has("missing") {
+		t.Error("Has(missing) = true")
+	}
+	if ValidationErrors(nil).Has("email") {
+		t.Error("Has on nil errors = true")
+	}
+}
+
+func TestValidationErrorsFirst(t *testing.T) {
+	e := ValidationErrors{"email": {"required", "invalid"}, "name": {}}
+
+	if got := e.First("email"); got != "required" {
+		t.Errorf("First(email) = %q, want required", got)
+	}
+	if got := e.First("name"); got != "" {
+		t.Errorf("First(name) = %q, want empty", got)
+	}
+	if got := ValidationErrors(nil).First("email"); got != "" {
+		t.Errorf("First on nil errors = %q, want empty", got)
+	}
+}
+
+func TestValidationErrorsAll(t *testing.T) {
+	e := ValidationErrors{"email": {"required"}}
+
+	want := map[string][]string{"email": {"required"}}
+	if got := e.All(); !reflect.DeepEqual(got, want) {
+		t.Errorf("All() = %v, want %v", got, want)
+	}
+	if got := ValidationErrors(nil).All(); len(got) != 0 {
+		t.Errorf("All() on nil errors = %v, want empty", got)
+	}
+}
+
+func TestValidationErrorsAny(t *testing.T) {
+	tests := []struct {
+		name string
+		e    ValidationErrors
+		want bool
+	}{
+		{"nil", nil, false},
+		{"empty", ValidationErrors{}, false},
+		{"only empty lists", ValidationErrors{"email": {}, "name": nil}, false},
+		{"one error", ValidationErrors{"email": {}, "name": {"required"}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.e.Any(); got != tt.want {
+			t.Errorf("%s: Any() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidationErrorsStillAnError(t *testing.T) {
+	var err error = ValidationErrors{"email": {"required"}}
+
+	if got := err.Error(); got != `{"email":["required"]}` {
+		t.Errorf("Error() = %s", got)
+	}
+
+	var verrs ValidationErrors
+	if !errors.As(err, &verrs) || !verrs.Has("email") {
+		t.Error("errors.As did not recover ValidationErrors")
+	}
+}
+
+func TestValidationErrorsGob(t *testing.T) {
+	gob.Register(ValidationErrors{})
+	in := map[string]any{"errors": ValidationErrors{"email": {"required"}}}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(in); err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	var out map[string]any
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+
+	verrs, ok := out["errors"].(ValidationErrors)
+	if !ok {
+		t.Fatalf("decoded %T, want ValidationErrors", out["errors"])
+	}
+	if verrs.First("email") != "required" {
+		t.Errorf("decoded %v", verrs)
+	}
+}
@@ -0,0 +1,269 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// MapToStruct decodes m into the struct pointed to by out, matching keys to
+// json tag names (or field names, case-insensitively). Scalar values are
+// coerced where it is lossless, e.g. "42" into an int or 3.0 into a uint.
+// Types implementing json.Unmarshaler (e.g. time.Time) are decoded through
+// encoding/json. Errors name the offending field path.
+func MapToStruct(m map[string]any, out any) error {
+	v := reflect.ValueOf(out)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("utils: MapToStruct requires a non-nil pointer to a struct")
+	}
+	return decodeStruct(m, v.Elem(), "")
+}
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+func decodeStruct(m map[string]any, v reflect.Value, path string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+
+		if field.Anonymous && name == "" {
+			if fv.Kind() == reflect.Pointer && fv.Type().Elem().Kind() == reflect.Struct {
+				if !fv.CanSet() {
+					continue
+				}
+				if fv.IsNil() {
+					fv.Set(reflect.New(fv.Type().Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				if err := decodeStruct(m, fv, path); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		src, ok := lookupKey(m, name)
+		if !ok {
+			continue
+		}
+
+		if err := decodeValue(src, fv, joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupKey finds key in m, falling back to a case-insensitive match
+func lookupKey(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func decodeValue(src any, dst reflect.Value, path string) error {
+	if src == nil {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	if reflect.PointerTo(dst.Type()).Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(src)
+		if err != nil {
+			return fmt.Errorf("utils: %s: %w", path, err)
+		}
+		if err := json.Unmarshal(data, dst.Addr().Interface()); err != nil {
+			return fmt.Errorf("utils: %s: %w", path, err)
+		}
+		return nil
+	}
+
+	sv := reflect.ValueOf(src)
+
+	switch dst.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(dst.Type().Elem())
+		if err := decodeValue(src, elem.Elem(), path); err != nil {
+			return err
+		}
+		dst.Set(elem)
+		return nil
+	case reflect.Interface:
+		if !sv.Type().AssignableTo(dst.Type()) {
+			return mismatch(src, dst, path)
+		}
+		dst.Set(sv)
+		return nil
+	case reflect.Struct:
+		m, ok := src.(map[string]any)
+		if !ok {
+			return mismatch(src, dst, path)
+		}
+		return decodeStruct(m, dst, path)
+	case reflect.Map:
+		if sv.Kind() != reflect.Map {
+			return mismatch(src, dst, path)
+		}
+		out := reflect.MakeMapWithSize(dst.Type(), sv.Len())
+		iter := sv.MapRange()
+		for iter.Next() {
+			key := reflect.New(dst.Type().Key()).Elem()
+			keyPath := joinPath(path, fmt.Sprint(iter.Key().Interface()))
+			if err := decodeValue(iter.Key().Interface(), key, keyPath); err != nil {
+				return err
+			}
+			val := reflect.New(dst.Type().Elem()).Elem()
+			if err := decodeValue(iter.Value().Interface(), val, keyPath); err != nil {
+				return err
+			}
+			out.SetMapIndex(key, val)
+		}
+		dst.Set(out)
+		return nil
+	case reflect.Slice:
+		if sv.Kind() != reflect.Slice && sv.Kind() != reflect.Array {
+			return mismatch(src, dst, path)
+		}
+		out := reflect.MakeSlice(dst.Type(), sv.Len(), sv.Len())
+		for i := 0; i < sv.Len(); i++ {
+			if err := decodeValue(sv.Index(i).Interface(), out.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		dst.Set(out)
+		return nil
+	}
+
+	return decodeScalar(src, sv, dst, path)
+}
+
+func decodeScalar(src any, sv reflect.Value, dst reflect.Value, path string) error {
+	if sv.Type().ConvertibleTo(dst.Type()) && sv.Kind() == dst.Kind() {
+		dst.Set(sv.Convert(dst.Type()))
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		switch sv.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			dst.SetString(fmt.Sprint(src))
+			return nil
+		}
+	case reflect.Bool:
+		if s, ok := src.(string); ok {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return mismatch(src, dst, path)
+			}
+			dst.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		switch {
+		case sv.Kind() == reflect.String:
+			var err error
+			if n, err = strconv.ParseInt(sv.String(), 10, 64); err != nil {
+				return mismatch(src, dst, path)
+			}
+		case sv.CanInt():
+			n = sv.Int()
+		case sv.CanUint() && sv.Uint() <= 1<<63-1:
+			n = int64(sv.Uint())
+		case sv.CanFloat() && sv.Float() == float64(int64(sv.Float())):
+			n = int64(sv.Float())
+		default:
+			return mismatch(src, dst, path)
+		}
+		if dst.OverflowInt(n) {
+			return fmt.Errorf("utils: %s: %v overflows %s", path, src, dst.Type())
+		}
+		dst.SetInt(n)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		switch {
+		case sv.Kind() == reflect.String:
+			var err error
+			if n, err = strconv.ParseUint(sv.String(), 10, 64); err != nil {
+				return mismatch(src, dst, path)
+			}
+		case sv.CanUint():
+			n = sv.Uint()
+		case sv.CanInt() && sv.Int() >= 0:
+			n = uint64(sv.Int())
+		case sv.CanFloat() && sv.Float() >= 0 && sv.Float() == float64(uint64(sv.Float())):
+			n = uint64(sv.Float())
+		default:
+			return mismatch(src, dst, path)
+		}
+		if dst.OverflowUint(n) {
+			return fmt.Errorf("utils: %s: %v overflows %s", path, src, dst.Type())
+		}
+		dst.SetUint(n)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		switch {
+		case sv.Kind() == reflect.String:
+			var err error
+			if f, err = strconv.ParseFloat(sv.String(), 64); err != nil {
+				return mismatch(src, dst, path)
+			}
+		case sv.CanFloat():
+			f = sv.Float()
+		case sv.CanInt():
+			f = float64(sv.Int())
+		case sv.CanUint():
+			f = float64(sv.Uint())
+		default:
+			return mismatch(src, dst, path)
+		}
+		if dst.OverflowFloat(f) {
+			return fmt.Errorf("utils: %s: %v overflows %s", path, src, dst.Type())
+		}
+		dst.SetFloat(f)
+		return nil
+	}
+
+	return mismatch(src, dst, path)
+}
+
+func mismatch(src any, dst reflect.Value, path string) error {
+	return fmt.Errorf("utils: %s: cannot convert %T (%v) to %s", path, src, src, dst.Type())
+}
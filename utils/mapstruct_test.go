@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decodeAddress struct {
+	City string `json:"city"`
+	Zip  int    `json:"zip"`
+}
+
+type decodeUser struct {
+	mapBase
+	Name     string          `json:"name"`
+	Age      int             `json:"age"`
+	Score    float32         `json:"score"`
+	Active   bool            `json:"active"`
+	Ignored  string          `json:"-"`
+	Address  decodeAddress   `json:"address"`
+	Previous *decodeAddress  `json:"previous"`
+	Tags     []string        `json:"tags"`
+	Scores   []uint8         `json:"scores"`
+	Labels   map[string]int  `json:"labels"`
+	Homes    []decodeAddress `json:"homes"`
+	Created  time.Time       `json:"created"`
+	Extra    any             `json:"extra"`
+	Nickname string
+	Meta     map[string]string `json:"meta"`
+}
+
+func TestMapToStruct(t *testing.T) {
+	m := map[string]any{
+		"id":       float64(7),
+		"name":     "jane",
+		"age":      "30",
+		"score":    9.5,
+		"active":   "true",
+		"Ignored":  "nope",
+		"address":  map[string]any{"city": "Oslo", "zip": "0150"},
+		"previous": map[string]any{"city": "Bergen"},
+		"tags":     []any{"a", "b"},
+		"scores":   []any{float64(1), "2"},
+		"labels":   map[string]any{"x": float64(1)},
+		"homes":    []any{map[string]any{"city": "Rome", "zip": 100}},
+		"created":  "2024-01-02T03:04:05Z",
+		"extra":    []any{1, "two"},
+		"NICKNAME": "jd",
+		"meta":     nil,
+	}
+
+	var got decodeUser
+	if err := MapToStruct(m, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	want := decodeUser{
+		mapBase:  mapBase{ID: 7},
+		Name:     "jane",
+		Age:      30,
+		Score:    9.5,
+		Active:   true,
+		Address:  decodeAddress{City: "Oslo", Zip: 150},
+		Previous: &decodeAddress{City: "Bergen"},
+		Tags:     []string{"a", "b"},
+		Scores:   []uint8{1, 2},
+		Labels:   map[string]int{"x": 1},
+		Homes:    []decodeAddress{{City: "Rome", Zip: 100}},
+		Created:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Extra:    []any{1, "two"},
+		Nickname: "jd",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("MapToStruct() =\n%#v\nwant\n%#v", got, want)
+	}
+}
+
+func TestMapToStructRoundTrip(t *testing.T) {
+	in := mapUser{mapBase: mapBase{ID: 1}, Name: "jane", Age: 30, Address: mapAddress{City: "Oslo", Zip: 5}, Tags: []string{"x"}}
+
+	var out mapUser
+	if err := MapToStruct(StructToMapReflect(in), &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, in) {
+		t.Fatalf("round trip = %#v, want %#v", out, in)
+	}
+}
+
+func TestMapToStructCoercionFailures(t *testing.T) {
+	tests := []struct {
+		m    map[string]any
+		path string
+	}{
+		{map[string]any{"age": "thirty"}, "age"},
+		{map[string]any{"age": 1.5}, "age"},
+		{map[string]any{"active": "maybe"}, "active"},
+		{map[string]any{"address": map[string]any{"zip": "abc"}}, "address.zip"},
+		{map[string]any{"address": "Oslo"}, "address"},
+		{map[string]any{"tags": "a,b"}, "tags"},
+		{map[string]any{"scores": []any{1, 300}}, "scores[1]"},
+		{map[string]any{"scores": []any{-1}}, "scores[0]"},
+		{map[string]any{"homes": []any{map[string]any{"zip": true}}}, "homes[0].zip"},
+		{map[string]any{"labels": map[string]any{"x": "y"}}, "labels.x"},
+		{map[string]any{"name": []any{"x"}}, "name"},
+		{map[string]any{"created": "yesterday"}, "created"},
+	}
+
+	for _, tt := range tests {
+		var u decodeUser
+		err := MapToStruct(tt.m, &u)
+		if err == nil {
+			t.Errorf("MapToStruct(%v) succeeded, want an error", tt.m)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.path+":") {
+			t.Errorf("MapToStruct(%v) error %q does not name %s", tt.m, err, tt.path)
+		}
+	}
+}
+
+func TestMapToStructRequiresStructPointer(t *testing.T) {
+	var u decodeUser
+	var nilUser *decodeUser
+	for _, out := range []any{u, nilUser, new(int), nil} {
+		if err := MapToStruct(map[string]any{}, out); err == nil {
+			t.Errorf("MapToStruct into %T succeeded", out)
+		}
+	}
+}
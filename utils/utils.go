@@ -1,12 +1,19 @@
 package utils
 
 import (
+	crand "crypto/rand"
 	"encoding/json"
+	"fmt"
 	"math/rand"
+	"reflect"
+	"strings"
 	"time"
+	"unicode"
 
+	"github.com/google/uuid"
 	_ "github.com/joho/godotenv/autoload"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/text/unicode/norm"
 )
 
 // GenerateRandomString generates a random string of a given length using the characters provided.
@@ -40,7 +47,9 @@ func Bcrypt(password string) (string, error) {
 	return string(bytes), err
 }
 
-// StructToMap converts any struct to map[string]interface{}
+// StructToMap converts any struct to map[string]interface{}. Because it
+// round-trips through JSON, all numbers become float64; use
+// StructToMapReflect to keep the original field types.
 func StructToMap(obj interface{}) (map[string]interface{}, error) {
 	data, err := json.Marshal(obj) // Convert to JSON
 	if err != nil {
@@ -53,3 +62,189 @@ func StructToMap(obj interface{}) (map[string]interface{}, error) {
 	}
 	return ret, nil
 }
+
+// transliterations covers letters that don't decompose into an ASCII base letter
+var transliterations = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'œ': "oe", 'ø': "o", 'ł': "l", 'đ': "d", 'ð': "d", 'þ': "th", 'ı': "i",
+}
+
+// Slugify converts a string into a lowercase, hyphen-separated, URL-safe identifier
+func Slugify(s string) string {
+	var b strings.Builder
+	hyphen := false
+
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+
+		if t, ok := transliterations[r]; ok {
+			b.WriteString(t)
+			hyphen = false
+			continue
+		}
+
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+
+		if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// NewUUID generates a random (version 4) UUID
+func NewUUID() string {
+	return uuid.NewString()
+}
+
+// crockford is the Crockford base32 alphabet used by ULIDs
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// NewULID generates a ULID from the current time and 80 random bits
+func NewULID() string {
+	var id [16]byte
+
+	ms := uint64(time.Now().UnixMilli())
+	for i := 5; i >= 0; i-- {
+		id[i] = byte(ms)
+		ms >>= 8
+	}
+
+	if _, err := crand.Read(id[6:]); err != nil {
+		panic(err)
+	}
+
+	// Encode the 128 bits as 26 characters of 5 bits each, the first
+	// character holding only the top 3 bits
+	out := make([]byte, 26)
+	var acc uint64
+	bits := 2
+	pos := 0
+	for _, b := range id {
+		acc = acc<<8 | uint64(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out[pos] = crockford[(acc>>bits)&31]
+			pos++
+		}
+	}
+
+	return string(out)
+}
+
+// StructToMapReflect converts a struct to map[string]any using reflection,
+// keeping field types intact. Like encoding/json, it uses json tag names,
+// skips unexported fields and fields tagged "-", honors omitempty and
+// flattens embedded structs. Nested structs become nested maps, except
+// types implementing json.Marshaler (e.g. time.Time) which are kept as is.
+func StructToMapReflect(obj any) map[string]any {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	out := make(map[string]any)
+	structToMap(v, out)
+	return out
+}
+
+func structToMap(v reflect.Value, out map[string]any) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+		fv := v.Field(i)
+
+		if field.Anonymous && name == "" {
+			ev := fv
+			if ev.Kind() == reflect.Pointer {
+				if ev.IsNil() {
+					continue
+				}
+				ev = ev.Elem()
+			}
+			if ev.Kind() == reflect.Struct {
+				structToMap(ev, out)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+		if strings.Contains(","+opts+",", ",omitempty,") && fv.IsZero() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		out[name] = reflectValue(fv)
+	}
+}
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func reflectValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Type().Implements(jsonMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return reflectValue(v.Elem())
+	case reflect.Struct:
+		m := make(map[string]any)
+		structToMap(v, m)
+		return m
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = reflectValue(v.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		m := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = reflectValue(iter.Value())
+		}
+		return m
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"hello-world":                 "hello-world",
+		"already-clean-123":           "already-clean-123",
+		"Hello World":                 "hello-world",
+		"Crème Brûlée":                "creme-brulee",
+		"Ångström über Straße":        "angstrom-uber-strasse",
+		"Łódź, Smørrebrød & Œuvre":    "lodz-smorrebrod-oeuvre",
+		"Hello, World! How's it?":     "hello-world-how-s-it",
+		"  --Leading and trailing-- ": "leading-and-trailing",
+		"a___b...c":                   "a-b-c",
+		"日本語":                         "",
+		"":                            "",
+	}
+	for input, want := range tests {
+		if got := Slugify(input); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestSlugifyIsIdempotent(t *testing.T) {
+	for _, input := range []string{"Crème Brûlée", "Hello, World!", "a--b"} {
+		once := Slugify(input)
+		if twice := Slugify(once); twice != once {
+			t.Errorf("Slugify(Slugify(%q)) = %q, want %q", input, twice, once)
+		}
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	id, err := uuid.Parse(NewUUID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Version() != 4 || id.Variant() != uuid.RFC4122 {
+		t.Fatalf("NewUUID() = version %d variant %s, want a v4 RFC 4122 UUID", id.Version(), id.Variant())
+	}
+	if NewUUID() == NewUUID() {
+		t.Fatal("NewUUID() repeated a value")
+	}
+}
+
+func TestNewULID(t *testing.T) {
+	id := NewULID()
+	if len(id) != 26 || strings.Trim(id, crockford) != "" {
+		t.Fatalf("NewULID() = %q, want 26 Crockford base32 characters", id)
+	}
+	// The first character holds only the top 3 bits of the timestamp
+	if id[0] > '7' {
+		t.Fatalf("NewULID() = %q overflows 128 bits", id)
+	}
+}
+
+func TestNewULIDSortsByTime(t *testing.T) {
+	first := NewULID()
+	time.Sleep(2 * time.Millisecond)
+	second := NewULID()
+	if first[:10] >= second[:10] {
+		t.Fatalf("later ULID %q does not sort after %q", second, first)
+	}
+}
+
+type mapAddress struct {
+	City string `json:"city"`
+	Zip  int    `json:"zip,omitempty"`
+}
+
+type mapBase struct {
+	ID int64 `json:"id"`
+}
+
+type mapUser struct {
+	mapBase
+	Name     string     `json:"name"`
+	Age      int        `json:"age"`
+	Score    float64    `json:"score"`
+	Password string     `json:"-"`
+	Nickname string     `json:"nickname,omitempty"`
+	Address  mapAddress `json:"address"`
+	Tags     []string   `json:"tags"`
+	Untagged uint8
+	secret   string
+}
+
+func TestStructToMapReflect(t *testing.T) {
+	user := mapUser{
+		mapBase:  mapBase{ID: 7},
+		Name:     "jane",
+		Age:      30,
+		Score:    9.5,
+		Password: "hunter2",
+		Address:  mapAddress{City: "Oslo"},
+		Tags:     []string{"a"},
+		Untagged: 3,
+		secret:   "x",
+	}
+
+	want := map[string]any{
+		"id":       int64(7),
+		"name":     "jane",
+		"age":      30,
+		"score":    9.5,
+		"address":  map[string]any{"city": "Oslo"},
+		"tags":     []any{"a"},
+		"Untagged": uint8(3),
+	}
+	if got := StructToMapReflect(&user); !reflect.DeepEqual(got, want) {
+		t.Fatalf("StructToMapReflect() = %#v, want %#v", got, want)
+	}
+}
+
+func TestStructToMapComparedToReflect(t *testing.T) {
+	user := mapUser{mapBase: mapBase{ID: 7}, Name: "jane", Age: 30, Password: "hunter2", Address: mapAddress{City: "Oslo", Zip: 123}}
+
+	viaJSON, err := StructToMap(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viaReflect := StructToMapReflect(user)
+
+	// Both honor the same tags, so they produce the same keys
+	if len(viaJSON) != len(viaReflect) {
+		t.Fatalf("key counts differ: %v vs %v", viaJSON, viaReflect)
+	}
+	for key := range viaJSON {
+		if _, ok := viaReflect[key]; !ok {
+			t.Errorf("StructToMapReflect() is missing %q", key)
+		}
+	}
+	if _, ok := viaReflect["Password"]; ok {
+		t.Error(`field tagged "-" was included`)
+	}
+
+	// Only the JSON round trip turns integers into float64
+	if _, ok := viaJSON["age"].(float64); !ok {
+		t.Errorf("StructToMap() age = %T, want float64", viaJSON["age"])
+	}
+	if _, ok := viaReflect["age"].(int); !ok {
+		t.Errorf("StructToMapReflect() age = %T, want int", viaReflect["age"])
+	}
+	if zip := viaReflect["address"].(map[string]any)["zip"]; zip != 123 {
+		t.Errorf("nested zip = %#v, want int 123", zip)
+	}
+}
+
+func TestStructToMapReflectKeepsMarshalers(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	got := StructToMapReflect(struct {
+		Created time.Time `json:"created"`
+		Nil     *mapAddress
+	}{Created: created})
+
+	if got["created"] != created {
+		t.Errorf("created = %#v, want the time.Time", got["created"])
+	}
+	if v, ok := got["Nil"]; !ok || v != nil {
+		t.Errorf("nil pointer = %#v, %v; want a nil entry", v, ok)
+	}
+}
+
+func TestStructToMapReflectNonStruct(t *testing.T) {
+	var nilUser *mapUser
+	for _, v := range []any{nil, 42, "x", nilUser} {
+		if got := StructToMapReflect(v); got != nil {
+			t.Errorf("StructToMapReflect(%#v) = %v, want nil", v, got)
+		}
+	}
+}